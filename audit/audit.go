@@ -14,6 +14,7 @@ import (
 	"fmt"
 	"github.com/evorts/kevlars/common"
 	"github.com/evorts/kevlars/db"
+	"github.com/evorts/kevlars/requests"
 	"github.com/evorts/kevlars/utils"
 	"github.com/huandu/go-sqlbuilder"
 	"time"
@@ -21,6 +22,7 @@ import (
 
 type Record struct {
 	Id              int64                  `db:"id"`
+	TenantId        string                 `db:"tenant_id"` // populated from context on write
 	Action          string                 `db:"action"`
 	CreatedById     string                 `db:"created_by_id"`
 	CreatedByName   string                 `db:"created_by_name"`
@@ -48,7 +50,7 @@ const (
 
 //goland:noinspection SqlResolve
 var (
-	columns = []string{"action", "created_by_id", "created_by_name", "role", "before_changed", "after_changed",
+	columns = []string{"tenant_id", "action", "created_by_id", "created_by_name", "role", "before_changed", "after_changed",
 		"additional_props", "notes", "created_at"}
 	tableExistenceCheckQuery = map[db.SupportedDriver][]string{
 		db.DriverPostgreSQL: {
@@ -60,9 +62,11 @@ var (
 	tableColumnDefinitions = map[db.SupportedDriver][][]string{
 		db.DriverPostgreSQL: {
 			{"id", "serial", "primary key"},
+			{"tenant_id", "varchar(50)", "not null", "default ''"},
 			{"action", "varchar(150)", "not null"},
 			{"created_by_id", "varchar(25)", "not null"},
 			{"created_by_name", "varchar(50)", "not null"},
+			{"role", "varchar(50)"},
 			{"before_changed", "jsonb"},
 			{"after_changed", "jsonb"},
 			{"additional_props", "jsonb"},
@@ -76,6 +80,15 @@ var (
 			fmt.Sprintf("create  index if not exists %s_action_idx on public.%s(action)", table, table),
 			fmt.Sprintf("create index if not exists %s_created_by_id_idx on public.%s(created_by_id)", table, table),
 			fmt.Sprintf("create index if not exists %s_created_at_idx on public.%s(created_at)", table, table),
+			fmt.Sprintf("create index if not exists %s_tenant_id_idx on public.%s(tenant_id)", table, table),
+		},
+	}
+	// tableTenancyDefinitions bring table created before tenant awareness into the current shape
+	tableTenancyDefinitions = map[db.SupportedDriver][]string{
+		db.DriverPostgreSQL: {
+			fmt.Sprintf("alter table %s add column if not exists tenant_id varchar(50) not null default ''", table),
+			fmt.Sprintf("alter table %s add column if not exists role varchar(50)", table),
+			fmt.Sprintf("create index if not exists %s_tenant_id_idx on public.%s(tenant_id)", table, table),
 		},
 	}
 )
@@ -84,8 +97,9 @@ func (m *manager) Add(ctx context.Context, records ...Record) error {
 	builder := sqlbuilder.NewInsertBuilder().
 		InsertInto(table).
 		Cols(columns...)
+	tenantId := requests.TenantId(ctx)
 	for _, record := range records {
-		builder.Values(record.Action, record.CreatedById, record.CreatedByName, record.Role,
+		builder.Values(tenantId, record.Action, record.CreatedById, record.CreatedByName, record.Role,
 			db.ToJsonObjectFromMap(record.BeforeChanged), db.ToJsonObjectFromMap(record.AfterChanged),
 			db.ToJsonObjectFromMap(record.AdditionalProps), record.Notes, sqlbuilder.Raw("now()"))
 	}
@@ -132,7 +146,7 @@ func (m *manager) Init() error {
 		return err
 	}
 	if total >= 1 {
-		return nil
+		return db.ExecDefinitions(ctx, m.dbw, tableTenancyDefinitions)
 	}
 	columnDefinitions := utils.GetValueOnMap(tableColumnDefinitions, driver, [][]string{})
 	if len(columnDefinitions) < 1 {
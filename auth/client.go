@@ -19,7 +19,9 @@ import (
 	"github.com/evorts/kevlars/ctime"
 	"github.com/evorts/kevlars/db"
 	"github.com/evorts/kevlars/inmemory"
+	"github.com/evorts/kevlars/jwe"
 	"github.com/evorts/kevlars/logger"
+	"github.com/evorts/kevlars/requests"
 	"github.com/evorts/kevlars/rules"
 	"github.com/evorts/kevlars/rules/eval"
 	"github.com/evorts/kevlars/utils"
@@ -27,6 +29,8 @@ import (
 	"github.com/lib/pq"
 	"net/url"
 	"strings"
+	"sync"
+	"time"
 )
 
 type ClientManager interface {
@@ -46,7 +50,18 @@ type ClientManager interface {
 	ModifyClient(ctx context.Context, item Client) error
 	ModifyClientScope(ctx context.Context, item ClientScope) error
 
-	IsAllowed(secret, resource string, scope Scope) (clientName string, allowed bool)
+	// IsAllowed check access of client secret, client should belong to the tenant of context
+	IsAllowed(ctx context.Context, secret, resource string, scope Scope) (clientName string, allowed bool)
+
+	// IssueToken through client credentials grant, requested scope are space delimited of "resource:scope",
+	// when requested scope empty all scopes granted to client will be included
+	IssueToken(ctx context.Context, clientId, secret, scope string) (ClientToken, error)
+	// IntrospectToken following RFC 7662, inactive token reported along with the reason
+	IntrospectToken(ctx context.Context, token string) (TokenIntrospection, error)
+	// IsTokenAllowed check access of token issued by IssueToken against both its scope and current client scopes
+	IsTokenAllowed(ctx context.Context, token, resource string, scope Scope) (clientName string, allowed bool)
+	// Authenticate client by its id (name) and secret within tenant of context
+	Authenticate(ctx context.Context, clientId, secret string) bool
 
 	AddOptions(opts ...common.Option[clientManager]) ClientManager
 	Reload() error
@@ -67,8 +82,12 @@ type clientManager struct {
 	mem              inmemory.Manager
 	migrationDir     []string
 	migrationEnabled bool
+	mu               sync.RWMutex // guard maps below, replaced as a whole on reload
 	mapAuthorization mapClientAuthorization
+	mapSecret        map[string]string // map[tenant/name]secret
 	startContext     context.Context
+	jwe              jwe.Manager
+	tokenExpiration  time.Duration
 }
 
 const (
@@ -92,6 +111,7 @@ var (
 	clientsColumnsDefinition = map[db.SupportedDriver][][]string{
 		db.DriverPostgreSQL: {
 			{"id", "serial", "primary key"},
+			{"tenant_id", "varchar(50)", "not null", "default ''"},
 			{"name", "varchar(45)", "not null"},
 			{"secret", "varchar(128)", "not null"},
 			{"expired_at", "timestamp with time zone"},
@@ -104,7 +124,15 @@ var (
 	clientsTableIndexDefinition = map[db.SupportedDriver][]string{
 		db.DriverPostgreSQL: {
 			fmt.Sprintf("create unique index if not exists %s_secret_uidx on public.%s(secret)", tableClients, tableClients),
-			fmt.Sprintf("create unique index if not exists %s_name_uidx on public.%s(name)", tableClients, tableClients),
+			fmt.Sprintf("create unique index if not exists %s_tenant_id_name_uidx on public.%s(tenant_id, name)", tableClients, tableClients),
+		},
+	}
+	// clientsTenancyDefinition bring tables created before tenant awareness into the current shape
+	clientsTenancyDefinition = map[db.SupportedDriver][]string{
+		db.DriverPostgreSQL: {
+			fmt.Sprintf("alter table %s add column if not exists tenant_id varchar(50) not null default ''", tableClients),
+			fmt.Sprintf("drop index if exists %s_name_uidx", tableClients),
+			fmt.Sprintf("create unique index if not exists %s_tenant_id_name_uidx on public.%s(tenant_id, name)", tableClients, tableClients),
 		},
 	}
 	clientScopesColumnsDefinition = map[db.SupportedDriver][][]string{
@@ -133,9 +161,10 @@ func (m *clientManager) AddClient(ctx context.Context, items Clients) (Clients,
 		return rs, db.ErrorEmptyArguments
 	}
 	placeholders := addClientQuery[m.driver].placeholder(len(items))
+	tenantId := requests.TenantId(ctx)
 	args := make([]interface{}, 0)
 	for _, item := range items {
-		args = append(args, item.Name, item.Secret, item.Disabled, item.ExpiredAt, item.Disabled)
+		args = append(args, tenantId, item.Name, item.Secret, item.Disabled, item.ExpiredAt, item.Disabled)
 	}
 	q := addClientQuery[m.driver].query(strings.Join(placeholders, ","))
 	rows, err := m.dbw.Query(ctx, m.dbw.Rebind(q), args...)
@@ -162,6 +191,23 @@ func (m *clientManager) AddClientScope(ctx context.Context, items ClientScopes)
 	if eval.IsEmpty(items) {
 		return rs, db.ErrorEmptyArguments
 	}
+	// scope could only be added into clients of the same tenant
+	clientIds := make([]int, 0)
+	for _, item := range items {
+		if !utils.InArray(clientIds, item.ClientID) {
+			clientIds = append(clientIds, item.ClientID)
+		}
+	}
+	var total int
+	err := m.dbw.QueryRow(
+		ctx, m.dbw.Rebind(countClientsOfTenantQuery[m.driver].query(len(clientIds))), idsWithTenant(ctx, clientIds)...,
+	).Scan(&total)
+	if err != nil {
+		return rs, err
+	}
+	if total != len(clientIds) {
+		return rs, db.ErrorInvalidArgument
+	}
 	// build select values
 	placeholders := addScopeQuery[m.driver].placeholder(len(items))
 	args := make([]interface{}, 0)
@@ -195,7 +241,9 @@ func (m *clientManager) AddClientWithScopes(ctx context.Context, item ClientWith
 	ph := addClientQuery[m.driver].placeholder(1)
 	q := addClientQuery[m.driver].query(strings.Join(ph, ","))
 	var client Client
-	err := tx.QueryRowx(tx.Rebind(q), item.Name, item.Secret, item.Disabled, item.ExpiredAt, item.Disabled).StructScan(&client)
+	err := tx.QueryRowx(
+		tx.Rebind(q), requests.TenantId(ctx), item.Name, item.Secret, item.Disabled, item.ExpiredAt, item.Disabled,
+	).StructScan(&client)
 	if err != nil {
 		_ = tx.Rollback()
 		return nil, err
@@ -233,7 +281,7 @@ func (m *clientManager) AddClientWithScopes(ctx context.Context, item ClientWith
 }
 
 func (m *clientManager) GetClientsBy(ctx context.Context, by db.IHelper) (Clients, error) {
-	qf, args := by.BuildSqlAndArgsWithWherePrefix()
+	qf, args := db.NewScopedHelper(by, "tenant_id = ?", requests.TenantId(ctx)).BuildSqlAndArgsWithWherePrefix()
 	//goland:noinspection SqlResolve
 	q := m.dbr.Rebind(getClientsByQuery[m.driver].query(qf))
 	rs := make(Clients, 0)
@@ -260,7 +308,9 @@ func (m *clientManager) GetClientsBy(ctx context.Context, by db.IHelper) (Client
 }
 
 func (m *clientManager) GetClientScopesBy(ctx context.Context, by db.IHelper) (ClientScopes, error) {
-	qf, args := by.BuildSqlAndArgsWithWherePrefix()
+	qf, args := db.NewScopedHelper(
+		by, "client_id IN (SELECT id FROM "+tableClients+" WHERE tenant_id = ?)", requests.TenantId(ctx),
+	).BuildSqlAndArgsWithWherePrefix()
 	//goland:noinspection SqlResolve
 	q := m.dbr.Rebind(getClientScopesByQuery[m.driver].query(qf))
 	rs := make(ClientScopes, 0)
@@ -287,6 +337,11 @@ func (m *clientManager) GetClientScopesBy(ctx context.Context, by db.IHelper) (C
 }
 
 func (m *clientManager) GetClientsWithScopesBy(ctx context.Context, by db.IHelper) (ClientsWithScopes, error) {
+	return m.getClientsWithScopesBy(ctx, db.NewScopedHelper(by, "c.tenant_id = ?", requests.TenantId(ctx)))
+}
+
+// getClientsWithScopesBy across tenants
+func (m *clientManager) getClientsWithScopesBy(ctx context.Context, by db.IHelper) (ClientsWithScopes, error) {
 	qf, args := by.BuildSqlAndArgsWithWherePrefix()
 	//goland:noinspection SqlResolve
 	q := m.dbr.Rebind(getClientWithScopesByQuery[m.driver].query(qf))
@@ -311,7 +366,7 @@ func (m *clientManager) GetClientsWithScopesBy(ctx context.Context, by db.IHelpe
 			itemScope ClientScope
 		)
 		if err = rows.Scan(
-			&item.ID, &item.Name, &item.Secret, &item.ExpiredAt, &item.Disabled,
+			&item.ID, &item.TenantID, &item.Name, &item.Secret, &item.ExpiredAt, &item.Disabled,
 			&item.CreatedAt, &item.UpdatedAt, &item.DisabledAt,
 			&itemScope.ID, &itemScope.Resource, &itemScope.Scopes, &itemScope.Disabled,
 			&itemScope.CreatedAt, &itemScope.UpdatedAt, &itemScope.DisabledAt,
@@ -341,7 +396,7 @@ func (m *clientManager) VoidClientsByIds(ctx context.Context, ids ...int) error
 		return db.ErrorEmptyArguments
 	}
 	q := voidClientByIdsQuery[m.driver].query(len(ids))
-	_, err := m.dbw.Exec(ctx, m.dbw.Rebind(q), ids)
+	_, err := m.dbw.Exec(ctx, m.dbw.Rebind(q), idsWithTenant(ctx, ids)...)
 	return err
 }
 
@@ -350,7 +405,7 @@ func (m *clientManager) RemoveClientsByIds(ctx context.Context, ids ...int) erro
 		return db.ErrorEmptyArguments
 	}
 	q := removeClientByIdsQuery[m.driver].query(len(ids))
-	_, err := m.dbw.Exec(ctx, m.dbw.Rebind(q), ids)
+	_, err := m.dbw.Exec(ctx, m.dbw.Rebind(q), idsWithTenant(ctx, ids)...)
 	return err
 }
 
@@ -359,7 +414,7 @@ func (m *clientManager) VoidScopeByIds(ctx context.Context, ids ...int) error {
 		return db.ErrorEmptyArguments
 	}
 	q := voidClientScopesByIdsQuery[m.driver].query(len(ids))
-	_, err := m.dbw.Exec(ctx, m.dbw.Rebind(q), ids)
+	_, err := m.dbw.Exec(ctx, m.dbw.Rebind(q), idsWithTenant(ctx, ids)...)
 	return err
 }
 
@@ -368,7 +423,7 @@ func (m *clientManager) RemoveScopeByIds(ctx context.Context, ids ...int) error
 		return db.ErrorEmptyArguments
 	}
 	q := removeClientScopesByIdsQuery[m.driver].query(len(ids))
-	_, err := m.dbw.Exec(ctx, m.dbw.Rebind(q), ids)
+	_, err := m.dbw.Exec(ctx, m.dbw.Rebind(q), idsWithTenant(ctx, ids)...)
 	return err
 }
 
@@ -377,6 +432,7 @@ func (m *clientManager) ModifyClient(ctx context.Context, item Client) error {
 		return db.ErrorInvalidArgument
 	}
 	q := modifyClientQuery[m.driver].query()
+	item.TenantID = requests.TenantId(ctx)
 	_, err := m.dbw.NamedExec(
 		ctx, q, item,
 	)
@@ -388,22 +444,28 @@ func (m *clientManager) ModifyClientScope(ctx context.Context, item ClientScope)
 		return db.ErrorInvalidArgument
 	}
 	q := modifyClientScopeQuery[m.driver].query()
-	_, err := m.dbw.NamedExec(ctx, q, item)
+	_, err := m.dbw.NamedExec(ctx, q, struct {
+		ClientScope
+		TenantID string `db:"tenant_id"`
+	}{ClientScope: item, TenantID: requests.TenantId(ctx)})
 	return err
 }
 
-func (m *clientManager) IsAllowed(secret, resource string, scope Scope) (clientName string, allowed bool) {
+func (m *clientManager) IsAllowed(ctx context.Context, secret, resource string, scope Scope) (clientName string, allowed bool) {
 	dt := m.getMapAuthorization(secret, resource)
 	if dt == nil {
 		return "unknown", false
 	}
+	if dt.TenantID != requests.TenantId(ctx) {
+		return dt.ClientName, false
+	}
 	if dt.Disabled {
 		return dt.ClientName, false
 	}
 	if len(dt.Scopes) < 1 {
 		return dt.ClientName, false
 	}
-	if dt.ExpiredAt != nil && ctime.Now().Before(*dt.ExpiredAt) {
+	if dt.ExpiredAt != nil && !dt.ExpiredAt.IsZero() && ctime.Now().After(*dt.ExpiredAt) {
 		return dt.ClientName, false
 	}
 	return dt.ClientName, dt.Scopes.AllowedTo(scope)
@@ -423,24 +485,39 @@ func (m *clientManager) getMapAuthorization(secret, resource string) *clientData
 		"context":  "client.get_map_authorization",
 		"resource": resource,
 	}, err.Error())
-	rm, ok := m.mapAuthorization[secret]
+	dt, ok := m.authorizationsOf(secret)[resource]
 	if !ok {
 		return nil
 	}
-	dt, okd := rm[resource]
-	if !okd {
-		return nil
-	}
 	return &dt
 }
 
+// secretOf client on the tenant
+func (m *clientManager) secretOf(tenantId, clientId string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	secret, ok := m.mapSecret[clientKey(tenantId, clientId)]
+	return secret, ok
+}
+
+// authorizationsOf client secret by resource
+func (m *clientManager) authorizationsOf(secret string) map[string]clientDataForAuthorization {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.mapAuthorization[secret]
+}
+
+// loadData build fresh maps of the clients then swap them in,
+// thus removed client or rotated secret no longer authorized after reload
 func (m *clientManager) loadData() error {
 	var (
-		page  = 1
-		limit = 20
+		page             = 1
+		limit            = 20
+		mapAuthorization = make(mapClientAuthorization)
+		mapSecret        = make(map[string]string)
 	)
 	for {
-		items, err := m.GetClientsWithScopesBy(
+		items, err := m.getClientsWithScopesBy(
 			m.startContext,
 			db.NewHelper(
 				db.SeparatorAND,
@@ -452,19 +529,21 @@ func (m *clientManager) loadData() error {
 		}
 		// map items into map client authorization
 		for _, item := range items {
-			m.mapAuthorization[item.Secret] = make(map[string]clientDataForAuthorization)
+			mapAuthorization[item.Secret] = make(map[string]clientDataForAuthorization)
+			mapSecret[clientKey(item.TenantID, item.Name)] = item.Secret
 			inMemoryFieldValues := make([]interface{}, 0)
 			for _, scope := range item.Scopes {
 				if scope == nil {
 					continue
 				}
-				m.mapAuthorization[item.Secret][scope.Resource] = clientDataForAuthorization{
+				mapAuthorization[item.Secret][scope.Resource] = clientDataForAuthorization{
+					TenantID:   item.TenantID,
 					ClientName: item.Name,
 					Scopes:     scope.Scopes,
 					Disabled:   rules.Iif(item.Disabled, item.Disabled, scope.Disabled),
 					ExpiredAt:  &item.ExpiredAt.Time,
 				}
-				inMemoryFieldValues = append(inMemoryFieldValues, scope.Resource, m.mapAuthorization[item.Secret][scope.Resource])
+				inMemoryFieldValues = append(inMemoryFieldValues, scope.Resource, mapAuthorization[item.Secret][scope.Resource])
 			}
 			if err = m.mem.HSet(m.startContext, item.Secret, inMemoryFieldValues...); err != nil {
 				return err
@@ -475,6 +554,18 @@ func (m *clientManager) loadData() error {
 		}
 		page++
 	}
+	m.mu.Lock()
+	stale := m.mapAuthorization
+	m.mapAuthorization, m.mapSecret = mapAuthorization, mapSecret
+	m.mu.Unlock()
+	for secret := range stale {
+		if _, ok := mapAuthorization[secret]; ok {
+			continue
+		}
+		if err := m.mem.Del(m.startContext, secret); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -554,7 +645,7 @@ func (m *clientManager) initSchema(ctx context.Context) error {
 		return err
 	}
 	if total == 2 {
-		return nil
+		return db.ExecDefinitions(ctx, m.dbw, clientsTenancyDefinition)
 	}
 	// custom type
 	if !utils.KeyExistsInMap(clientCustomDefinitions, driver) {
@@ -656,6 +747,8 @@ func NewClientManager(dbm db.Manager, opts ...common.Option[clientManager]) Clie
 		log:              logger.NewNoop(),
 		mem:              inmemory.NewNoop(),
 		mapAuthorization: make(mapClientAuthorization),
+		mapSecret:        make(map[string]string),
+		tokenExpiration:  DefaultClientTokenExpiration,
 		migrationEnabled: false,
 		migrationDir:     []string{},
 		startContext:     context.Background(),
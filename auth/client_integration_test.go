@@ -14,11 +14,14 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"database/sql"
 	"github.com/evorts/kevlars/common"
 	"github.com/evorts/kevlars/ctime"
 	"github.com/evorts/kevlars/db"
 	"github.com/evorts/kevlars/inmemory"
+	"github.com/evorts/kevlars/jwe"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"github.com/testcontainers/testcontainers-go"
@@ -547,6 +550,45 @@ func (ts *clientAuthTestSuite) TestModifyClientScope() {
 	}
 }
 
+func (ts *clientAuthTestSuite) TestIssueToken() {
+	_, err := ts.cm.AddClientWithScopes(ts.ctx, ClientWithScopes{
+		Client: &Client{
+			Name:      "Token Client",
+			Secret:    "Token Client Secret",
+			ExpiredAt: sql.NullTime{Time: ctime.NowAdd(10 * time.Hour), Valid: true},
+		},
+		Scopes: ClientScopes{
+			{Resource: "/orders", Scopes: Scopes{ScopeRead, ScopeWrite}},
+		},
+	})
+	ts.Require().NoError(err)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	ts.Require().NoError(err)
+	ts.cm.AddOptions(ClientWithJWE(jwe.NewJWE(key).MustInit()))
+	ts.Require().NoError(ts.cm.Reload())
+
+	_, err = ts.cm.IssueToken(ts.ctx, "Token Client", "wrong", "")
+	assert.Equal(ts.T(), ErrInvalidClient, err)
+	_, err = ts.cm.IssueToken(ts.ctx, "Token Client", "Token Client Secret", "/orders:delete")
+	assert.Equal(ts.T(), ErrInvalidScope, err)
+	token, err := ts.cm.IssueToken(ts.ctx, "Token Client", "Token Client Secret", "/orders:read")
+	ts.Require().NoError(err)
+	assert.Equal(ts.T(), "/orders:read", token.Scope)
+
+	rs, err := ts.cm.IntrospectToken(ts.ctx, token.AccessToken)
+	assert.NoError(ts.T(), err)
+	assert.True(ts.T(), rs.Active)
+	assert.Equal(ts.T(), "Token Client", rs.ClientID)
+	rs, _ = ts.cm.IntrospectToken(ts.ctx, "invalid-token")
+	assert.False(ts.T(), rs.Active)
+
+	_, allowed := ts.cm.IsTokenAllowed(ts.ctx, token.AccessToken, "/orders", ScopeRead)
+	assert.True(ts.T(), allowed)
+	// write granted to client but not requested within token
+	_, allowed = ts.cm.IsTokenAllowed(ts.ctx, token.AccessToken, "/orders", ScopeWrite)
+	assert.False(ts.T(), allowed)
+}
+
 func (ts *clientAuthTestSuite) TearDownTest() {
 	if err := ts.postgresContainer.Terminate(ts.ctx); err != nil {
 		log.Fatalf("failed to terminate container: %s", err)
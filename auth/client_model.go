@@ -14,6 +14,7 @@ import (
 
 type Client struct {
 	ID         int          `db:"id"`
+	TenantID   string       `db:"tenant_id"` // populated from context on write
 	Name       string       `db:"name"`
 	Secret     string       `db:"secret"`
 	ExpiredAt  sql.NullTime `db:"expired_at"`
@@ -46,6 +47,7 @@ type ClientWithScopes struct {
 type ClientsWithScopes []*ClientWithScopes
 
 type clientDataForAuthorization struct {
+	TenantID   string
 	ClientName string
 	Scopes     Scopes
 	Disabled   bool
@@ -54,3 +56,25 @@ type clientDataForAuthorization struct {
 
 // map[secret][resource]ClientForAuthorization
 type mapClientAuthorization map[string]map[string]clientDataForAuthorization
+
+// ClientToken response of client credentials grant as defined by RFC 6749 section 5.1
+type ClientToken struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// TokenIntrospection response as defined by RFC 7662 section 2.2
+type TokenIntrospection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiredAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenID   string `json:"jti,omitempty"`
+	TenantID  string `json:"tenant_id,omitempty"`
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/evorts/kevlars/common"
 	"github.com/evorts/kevlars/db"
 	"github.com/evorts/kevlars/inmemory"
+	"github.com/evorts/kevlars/jwe"
 	"github.com/evorts/kevlars/logger"
+	"time"
 )
 
 func ClientWithLogger(v logger.Manager) common.Option[clientManager] {
@@ -38,3 +40,16 @@ func ClientWithExecuteMigration(enabled bool, dir ...string) common.Option[clien
 		c.migrationEnabled = enabled
 	})
 }
+
+// ClientWithJWE required to issue and validate token through client credentials grant
+func ClientWithJWE(v jwe.Manager) common.Option[clientManager] {
+	return common.OptionFunc[clientManager](func(c *clientManager) {
+		c.jwe = v
+	})
+}
+
+func ClientWithTokenExpiration(v time.Duration) common.Option[clientManager] {
+	return common.OptionFunc[clientManager](func(c *clientManager) {
+		c.tokenExpiration = v
+	})
+}
@@ -26,14 +26,14 @@ var (
 				return db.PlaceholderRepeat(
 					fmt.Sprintf(
 						`(%s,CASE WHEN ? THEN current_timestamp END)`,
-						strings.Join(utils.RepeatInSlice("?", 4), ","),
+						strings.Join(utils.RepeatInSlice("?", 5), ","),
 					), repeat,
 				)
 			},
 			query: func(v string) string {
-				return `INSERT INTO ` + tableClients + `(name, secret, disabled, expired_at, disabled_at)
+				return `INSERT INTO ` + tableClients + `(tenant_id, name, secret, disabled, expired_at, disabled_at)
 					VALUES ` + v + `
-					RETURNING id, name, disabled, expired_at, created_at, disabled_at
+					RETURNING id, tenant_id, name, disabled, expired_at, created_at, disabled_at
 		`
 			},
 		},
@@ -69,7 +69,7 @@ var (
 	}{
 		db.DriverPostgreSQL: {
 			query: func(pl int) string {
-				return `DELETE FROM` + " " + tableClients + ` WHERE id IN(` + db.BuildPlaceholder(pl) + `)`
+				return `DELETE FROM` + " " + tableClients + ` WHERE id IN(` + db.BuildPlaceholder(pl) + `) AND tenant_id = ?`
 			},
 		},
 	}
@@ -78,7 +78,7 @@ var (
 	}{
 		db.DriverPostgreSQL: {
 			query: func(pl int) string {
-				return `UPDATE ` + tableClients + ` SET disabled=true, disabled_at=current_timestamp WHERE id IN(` + db.BuildPlaceholder(pl) + `)`
+				return `UPDATE ` + tableClients + ` SET disabled=true, disabled_at=current_timestamp WHERE id IN(` + db.BuildPlaceholder(pl) + `) AND tenant_id = ?`
 			},
 		},
 	}
@@ -87,7 +87,8 @@ var (
 	}{
 		db.DriverPostgreSQL: {
 			query: func(pl int) string {
-				return `DELETE FROM` + " " + tableClientScope + ` WHERE id IN(` + db.BuildPlaceholder(pl) + `)`
+				return `DELETE FROM` + " " + tableClientScope + ` WHERE id IN(` + db.BuildPlaceholder(pl) + `)
+					AND client_id IN (SELECT id FROM ` + tableClients + ` WHERE tenant_id = ?)`
 			},
 		},
 	}
@@ -96,7 +97,8 @@ var (
 	}{
 		db.DriverPostgreSQL: {
 			query: func(pl int) string {
-				return `UPDATE ` + tableClientScope + ` SET disabled=true, disabled_at=current_timestamp WHERE id IN(` + db.BuildPlaceholder(pl) + `)`
+				return `UPDATE ` + tableClientScope + ` SET disabled=true, disabled_at=current_timestamp WHERE id IN(` + db.BuildPlaceholder(pl) + `)
+					AND client_id IN (SELECT id FROM ` + tableClients + ` WHERE tenant_id = ?)`
 			},
 		},
 	}
@@ -114,7 +116,7 @@ var (
 					secret=(CASE WHEN :secret <> '' THEN :secret ELSE secret END),
 					expired_at=(CASE WHEN CAST(:expired_at AS timestamp) IS NOT NULL THEN :expired_at ELSE expired_at END),
 					updated_at=current_timestamp
-				WHERE id=:id`
+				WHERE id=:id AND tenant_id=:tenant_id`
 			},
 		},
 	}
@@ -129,6 +131,8 @@ var (
 					scopes=(CASE WHEN array_length(CAST(:scopes AS client_scope[]), 1) > 0 THEN :scopes ELSE scopes END),
 					updated_at=current_timestamp
 				WHERE id=:id
+					AND client_id IN (SELECT id FROM ` + tableClients + ` WHERE tenant_id=:tenant_id)
+					AND (:client_id < 1 OR :client_id IN (SELECT id FROM ` + tableClients + ` WHERE tenant_id=:tenant_id))
 `
 			},
 		},
@@ -143,7 +147,7 @@ var (
 		db.DriverPostgreSQL: {
 			query: func(qf string) string {
 				return `SELECT 
-		    				c.id, c.name, c.secret, c.expired_at, c.disabled, 
+		    				c.id, c.tenant_id, c.name, c.secret, c.expired_at, c.disabled, 
 		    				c.created_at, c.updated_at, c.disabled_at,
 		    				cs.id as scope_id, cs.resource, cs.scopes, cs.disabled as scope_disabled,
 		    				cs.created_at as scope_created_at, cs.updated_at as scope_updated_at,
@@ -160,12 +164,23 @@ var (
 		db.DriverPostgreSQL: {
 			query: func(qf string) string {
 				return `SELECT 
-					id, name, secret, expired_at, disabled, created_at, updated_at, disabled_at
+					id, tenant_id, name, secret, expired_at, disabled, created_at, updated_at, disabled_at
 				FROM` + " " + tableClients + " " + qf
 			},
 		},
 	}
 
+	// countClientsOfTenantQuery used to ensure clients referenced belong to the tenant
+	countClientsOfTenantQuery = map[db.SupportedDriver]struct {
+		query func(pl int) string
+	}{
+		db.DriverPostgreSQL: {
+			query: func(pl int) string {
+				return `SELECT count(DISTINCT id) FROM ` + tableClients + ` WHERE id IN(` + db.BuildPlaceholder(pl) + `) AND tenant_id = ?`
+			},
+		},
+	}
+
 	getClientScopesByQuery = map[db.SupportedDriver]struct {
 		query func(qf string) string
 	}{
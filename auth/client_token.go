@@ -0,0 +1,165 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: client_token
+ * @Date: 05/08/24 10.12
+ */
+
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"github.com/evorts/kevlars/ctime"
+	"github.com/evorts/kevlars/jwe"
+	"github.com/evorts/kevlars/requests"
+	"sort"
+	"strings"
+)
+
+const clientTokenScopeSeparator = ":"
+
+// ClientTokenScope build scope representation of resource and scope as used by client token, e.g. "/orders:read"
+func ClientTokenScope(resource string, scope Scope) string {
+	return resource + clientTokenScopeSeparator + scope.String()
+}
+
+func (m *clientManager) Authenticate(ctx context.Context, clientId, secret string) bool {
+	_, ok := m.authenticate(requests.TenantId(ctx), clientId, secret)
+	return ok
+}
+
+func (m *clientManager) IssueToken(ctx context.Context, clientId, secret, scope string) (ClientToken, error) {
+	if m.jwe == nil {
+		return ClientToken{}, ErrTokenManagerNotDefined
+	}
+	tenantId := requests.TenantId(ctx)
+	granted, ok := m.authenticate(tenantId, clientId, secret)
+	if !ok {
+		return ClientToken{}, ErrInvalidClient
+	}
+	scopes := granted
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		scopes = make([]string, 0, len(requested))
+		for _, s := range requested {
+			idx := sort.SearchStrings(granted, s)
+			if idx >= len(granted) || granted[idx] != s {
+				return ClientToken{}, ErrInvalidScope
+			}
+			scopes = append(scopes, s)
+		}
+	}
+	if len(scopes) < 1 {
+		return ClientToken{}, ErrInvalidScope
+	}
+	now := ctime.Now()
+	claim := jwe.Claim{
+		RegisteredClaims: jwe.RegisteredClaims{
+			Subject:   clientId,
+			TokenID:   newTokenId(),
+			IssuedAt:  now.Unix(),
+			ExpiredAt: now.Add(m.tokenExpiration).Unix(),
+		},
+		ClientID:  clientId,
+		TokenType: TokenTypeClient,
+		TenantID:  tenantId,
+		Scope:     strings.Join(scopes, " "),
+	}
+	token, err := m.jwe.Encode(claim)
+	if err != nil {
+		return ClientToken{}, err
+	}
+	return ClientToken{
+		AccessToken: token,
+		TokenType:   tokenTypeBearer,
+		ExpiresIn:   int64(m.tokenExpiration.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+func (m *clientManager) IntrospectToken(ctx context.Context, token string) (TokenIntrospection, error) {
+	claim, err := m.decodeToken(ctx, token)
+	if err != nil {
+		return TokenIntrospection{Active: false}, err
+	}
+	return TokenIntrospection{
+		Active:    true,
+		Scope:     claim.Scope,
+		ClientID:  claim.ClientID,
+		TokenType: tokenTypeBearer,
+		ExpiredAt: claim.ExpiredAt,
+		IssuedAt:  claim.IssuedAt,
+		Issuer:    claim.Issuer,
+		Subject:   claim.ClientID,
+		TokenID:   claim.TokenID,
+		TenantID:  claim.TenantID,
+	}, nil
+}
+
+func (m *clientManager) IsTokenAllowed(ctx context.Context, token, resource string, scope Scope) (clientName string, allowed bool) {
+	claim, err := m.decodeToken(ctx, token)
+	if err != nil {
+		return "unknown", false
+	}
+	if !scopeContains(claim.Scope, ClientTokenScope(resource, scope)) {
+		return claim.ClientID, false
+	}
+	// scope of token only narrow down the access, the current state of client remain the source of truth
+	secret, _ := m.secretOf(claim.TenantID, claim.ClientID)
+	return m.IsAllowed(ctx, secret, resource, scope)
+}
+
+// authenticate client and return sorted scopes granted to it
+func (m *clientManager) authenticate(tenantId, clientId, secret string) ([]string, bool) {
+	expected, ok := m.secretOf(tenantId, clientId)
+	if !ok || len(secret) < 1 || subtle.ConstantTimeCompare([]byte(expected), []byte(secret)) != 1 {
+		return nil, false
+	}
+	now := ctime.Now()
+	granted := make([]string, 0)
+	for resource, dt := range m.authorizationsOf(expected) {
+		if dt.Disabled {
+			continue
+		}
+		if dt.ExpiredAt != nil && !dt.ExpiredAt.IsZero() && now.After(*dt.ExpiredAt) {
+			return nil, false
+		}
+		for _, s := range dt.Scopes {
+			granted = append(granted, ClientTokenScope(resource, s))
+		}
+	}
+	sort.Strings(granted)
+	return granted, true
+}
+
+func (m *clientManager) decodeToken(ctx context.Context, token string) (jwe.Claim, error) {
+	if m.jwe == nil {
+		return jwe.Claim{}, ErrTokenManagerNotDefined
+	}
+	if len(token) < 1 {
+		return jwe.Claim{}, ErrInvalidToken
+	}
+	claim, err := m.jwe.Decode(token)
+	if err != nil {
+		return jwe.Claim{}, tokenError(err)
+	}
+	if claim.TokenType != TokenTypeClient {
+		return jwe.Claim{}, ErrInvalidToken
+	}
+	if claim.TenantID != requests.TenantId(ctx) {
+		return jwe.Claim{}, ErrTenantMismatch
+	}
+	if _, ok := m.secretOf(claim.TenantID, claim.ClientID); !ok {
+		return jwe.Claim{}, ErrInvalidClient
+	}
+	return claim, nil
+}
+
+func scopeContains(scopes, scope string) bool {
+	for _, s := range strings.Fields(scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
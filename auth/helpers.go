@@ -8,8 +8,16 @@
 package auth
 
 import (
+	"context"
+	"errors"
+	"github.com/evorts/kevlars/common"
+	"github.com/evorts/kevlars/crypt"
 	"github.com/evorts/kevlars/db"
+	"github.com/evorts/kevlars/jwe"
+	"github.com/evorts/kevlars/requests"
+	"github.com/gofrs/uuid/v5"
 	"github.com/huandu/go-sqlbuilder"
+	"strconv"
 )
 
 func getFlavorByDriver(driver db.SupportedDriver) sqlbuilder.Flavor {
@@ -18,3 +26,52 @@ func getFlavorByDriver(driver db.SupportedDriver) sqlbuilder.Flavor {
 	}
 	panic("unsupported flavor")
 }
+
+func hashCreds(h crypt.Hasher, creds string) (string, error) {
+	if len(creds) < 1 {
+		return "", ErrInvalidCredential
+	}
+	return h.HashPassword(common.Bytes(creds))
+}
+
+func verifyCreds(h crypt.Hasher, hashed, creds string) error {
+	ok, err := h.Verify(common.Bytes(creds), hashed)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidCredential
+	}
+	return nil
+}
+
+func newTokenId() string {
+	return uuid.Must(uuid.NewV4()).String()
+}
+
+// idsWithTenant build arguments of ids followed by tenant id of context
+func idsWithTenant(ctx context.Context, ids []int) []interface{} {
+	args := make([]interface{}, 0, len(ids)+1)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return append(args, requests.TenantId(ctx))
+}
+
+// clientKey of client name within tenant
+func clientKey(tenantId, name string) string {
+	return tenantId + "/" + name
+}
+
+// userKey of in memory entry prefixed by the key, user id scoped within tenant
+func userKey(prefix, tenantId string, userId int64) string {
+	return prefix + "_" + clientKey(tenantId, strconv.FormatInt(userId, 10))
+}
+
+// tokenError translate validation error of decoding token, reason other than expiration is not exposed
+func tokenError(err error) error {
+	if errors.Is(err, jwe.ErrTokenExpired) {
+		return ErrTokenExpired
+	}
+	return ErrInvalidToken
+}
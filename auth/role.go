@@ -0,0 +1,618 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: role
+ * @Date: 02/08/24 09.05
+ */
+
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/evorts/kevlars/common"
+	"github.com/evorts/kevlars/db"
+	"github.com/evorts/kevlars/inmemory"
+	"github.com/evorts/kevlars/logger"
+	"github.com/evorts/kevlars/requests"
+	"github.com/evorts/kevlars/rules"
+	"github.com/evorts/kevlars/rules/eval"
+	"github.com/evorts/kevlars/utils"
+	"github.com/huandu/go-sqlbuilder"
+	"github.com/lib/pq"
+	"strconv"
+	"strings"
+)
+
+type RoleManager interface {
+	AddRoles(ctx context.Context, items Roles) (Roles, error)
+	AddRolePermissions(ctx context.Context, items RolePermissions) (RolePermissions, error)
+
+	GetRolesBy(ctx context.Context, by db.IHelper) (Roles, error)
+	GetRolePermissionsBy(ctx context.Context, by db.IHelper) (RolePermissions, error)
+	GetRolesOfUser(ctx context.Context, userId int64) (Roles, error)
+
+	ModifyRole(ctx context.Context, item Role) error
+	ModifyRolePermission(ctx context.Context, item RolePermission) error
+
+	VoidRolesByIds(ctx context.Context, ids ...int) error
+	RemoveRolesByIds(ctx context.Context, ids ...int) error
+	RemoveRolePermissionsByIds(ctx context.Context, ids ...int) error
+
+	AssignRoles(ctx context.Context, userId int64, roleIds ...int) error
+	RevokeRoles(ctx context.Context, userId int64, roleIds ...int) error
+
+	// ResolvePermissions of user through assigned roles including the inherited one
+	ResolvePermissions(ctx context.Context, userId int64) (ResolvedPermissions, error)
+	IsAllowed(ctx context.Context, userId int64, resource string, scope Scope) (bool, error)
+	// Invalidate cached permissions of given users, when no user defined then invalidate all
+	Invalidate(ctx context.Context, userIds ...int64) error
+
+	AddOptions(opts ...common.Option[roleManager]) RoleManager
+
+	common.Init[RoleManager]
+}
+
+type roleManager struct {
+	dbw    db.Manager
+	dbr    db.Manager
+	driver db.SupportedDriver
+	log    logger.Manager
+	mem    inmemory.Manager
+}
+
+const (
+	tableRoles           = "roles"
+	tableRolePermissions = "role_permissions"
+	tableUserRoles       = "user_roles"
+
+	inMemoryUserPermissionsHashKey = "user_permissions" // tenant_id/user_id -> resolved permissions
+)
+
+//goland:noinspection SqlResolve
+var (
+	roleTableExistenceCheckQuery = map[db.SupportedDriver][]string{
+		db.DriverPostgreSQL: {
+			fmt.Sprintf(`select count(table_name) as tableCount from information_schema.tables ist
+				       where ist.table_name in ('%s','%s','%s')`, tableRoles, tableRolePermissions, tableUserRoles),
+		},
+	}
+	rolesColumnsDefinition = map[db.SupportedDriver][][]string{
+		db.DriverPostgreSQL: {
+			{"id", "serial", "primary key"},
+			{"tenant_id", "varchar(50)", "not null", "default ''"},
+			{"name", "varchar(45)", "not null"},
+			{"description", "varchar(255)", "default ''"},
+			{"parent_id", "int"},
+			{"constraint", "fk_" + tableRoles + "_parent_id", "foreign key (parent_id)", "references " + tableRoles + "(id)", "on delete set null"},
+			{"disabled", "boolean", "default false"},
+			{"created_at", "timestamp with time zone", "default current_timestamp"},
+			{"updated_at", "timestamp with time zone"},
+			{"disabled_at", "timestamp with time zone"},
+		},
+	}
+	rolesTableIndexDefinition = map[db.SupportedDriver][]string{
+		db.DriverPostgreSQL: {
+			fmt.Sprintf("create unique index if not exists %s_tenant_id_name_uidx on public.%s(tenant_id, name)", tableRoles, tableRoles),
+			fmt.Sprintf("create index if not exists %s_parent_id_idx on public.%s(parent_id)", tableRoles, tableRoles),
+		},
+	}
+	rolePermissionsColumnsDefinition = map[db.SupportedDriver][][]string{
+		db.DriverPostgreSQL: {
+			{"id", "serial", "primary key"},
+			{"role_id", "int", "not null"},
+			{"constraint", "fk_" + tableRolePermissions + "_role_id", "foreign key (role_id)", "references " + tableRoles + "(id)", "on delete cascade"},
+			{"resource", "varchar(255)", "not null"},
+			{"scopes", "access_scope[]", "default array[]::access_scope[]"},
+			{"disabled", "boolean", "default false"},
+			{"created_at", "timestamp with time zone", "default current_timestamp"},
+			{"updated_at", "timestamp with time zone"},
+			{"disabled_at", "timestamp with time zone"},
+		},
+	}
+	rolePermissionsTableIndexDefinition = map[db.SupportedDriver][]string{
+		db.DriverPostgreSQL: {
+			fmt.Sprintf("create unique index if not exists %s_role_id_resource_uidx on public.%s(role_id, resource)", tableRolePermissions, tableRolePermissions),
+		},
+	}
+	userRolesColumnsDefinition = map[db.SupportedDriver][][]string{
+		db.DriverPostgreSQL: {
+			{"id", "serial", "primary key"},
+			{"tenant_id", "varchar(50)", "not null", "default ''"},
+			{"user_id", "bigint", "not null"},
+			{"role_id", "int", "not null"},
+			{"constraint", "fk_" + tableUserRoles + "_role_id", "foreign key (role_id)", "references " + tableRoles + "(id)", "on delete cascade"},
+			{"created_at", "timestamp with time zone", "default current_timestamp"},
+		},
+	}
+	userRolesTableIndexDefinition = map[db.SupportedDriver][]string{
+		db.DriverPostgreSQL: {
+			fmt.Sprintf("create unique index if not exists %s_tenant_id_user_id_role_id_uidx on public.%s(tenant_id, user_id, role_id)", tableUserRoles, tableUserRoles),
+			fmt.Sprintf("create index if not exists %s_role_id_idx on public.%s(role_id)", tableUserRoles, tableUserRoles),
+		},
+	}
+	// roleTenancyDefinition bring tables created before tenant awareness into the current shape,
+	// permissions of role scoped through the role
+	roleTenancyDefinition = map[db.SupportedDriver][]string{
+		db.DriverPostgreSQL: {
+			fmt.Sprintf("alter table %s add column if not exists tenant_id varchar(50) not null default ''", tableRoles),
+			fmt.Sprintf("drop index if exists %s_name_uidx", tableRoles),
+			fmt.Sprintf("create unique index if not exists %s_tenant_id_name_uidx on public.%s(tenant_id, name)", tableRoles, tableRoles),
+			fmt.Sprintf("alter table %s add column if not exists tenant_id varchar(50) not null default ''", tableUserRoles),
+			fmt.Sprintf("drop index if exists %s_user_id_role_id_uidx", tableUserRoles),
+			fmt.Sprintf("create unique index if not exists %s_tenant_id_user_id_role_id_uidx on public.%s(tenant_id, user_id, role_id)", tableUserRoles, tableUserRoles),
+		},
+	}
+)
+
+func (m *roleManager) AddRoles(ctx context.Context, items Roles) (Roles, error) {
+	rs := make(Roles, 0)
+	if eval.IsEmpty(items) {
+		return rs, db.ErrorEmptyArguments
+	}
+	// parent could only be role of the same tenant
+	parentIds := make([]int, 0)
+	for _, item := range items {
+		if item.ParentID.Valid && item.ParentID.Int64 > 0 && !utils.InArray(parentIds, int(item.ParentID.Int64)) {
+			parentIds = append(parentIds, int(item.ParentID.Int64))
+		}
+	}
+	if err := m.ensureRolesOfTenant(ctx, parentIds...); err != nil {
+		return rs, err
+	}
+	placeholders := addRoleQuery[m.driver].placeholder(len(items))
+	tenantId := requests.TenantId(ctx)
+	args := make([]interface{}, 0)
+	for _, item := range items {
+		args = append(args, tenantId, item.Name, item.Description, item.ParentID, item.Disabled, item.Disabled)
+	}
+	q := addRoleQuery[m.driver].query(strings.Join(placeholders, ","))
+	rows, err := m.dbw.Query(ctx, m.dbw.Rebind(q), args...)
+	defer func() {
+		rules.WhenTrue(rows != nil, func() {
+			_ = rows.Close()
+		})
+	}()
+	if err != nil {
+		return rs, err
+	}
+	for rows.Next() {
+		var item Role
+		if err = rows.StructScan(&item); err != nil {
+			return rs, err
+		}
+		rs = append(rs, &item)
+	}
+	return rs, nil
+}
+
+func (m *roleManager) AddRolePermissions(ctx context.Context, items RolePermissions) (RolePermissions, error) {
+	rs := make(RolePermissions, 0)
+	if eval.IsEmpty(items) {
+		return rs, db.ErrorEmptyArguments
+	}
+	// permission could only be added into roles of the same tenant
+	roleIds := make([]int, 0)
+	for _, item := range items {
+		if !utils.InArray(roleIds, item.RoleID) {
+			roleIds = append(roleIds, item.RoleID)
+		}
+	}
+	if err := m.ensureRolesOfTenant(ctx, roleIds...); err != nil {
+		return rs, err
+	}
+	placeholders := addRolePermissionQuery[m.driver].placeholder(len(items))
+	args := make([]interface{}, 0)
+	for _, item := range items {
+		args = append(args, item.RoleID, item.Resource, pq.Array(item.Scopes), item.Disabled, item.Disabled)
+	}
+	q := addRolePermissionQuery[m.driver].query(strings.Join(placeholders, ","))
+	rows, err := m.dbw.Query(ctx, m.dbw.Rebind(q), args...)
+	defer func() {
+		rules.WhenTrue(rows != nil, func() {
+			_ = rows.Close()
+		})
+	}()
+	if err != nil {
+		return rs, err
+	}
+	for rows.Next() {
+		var item RolePermission
+		if err = rows.StructScan(&item); err != nil {
+			return rs, err
+		}
+		rs = append(rs, &item)
+	}
+	// permission of a role might be inherited by other roles, so all resolved permissions are stale
+	return rs, m.Invalidate(ctx)
+}
+
+func (m *roleManager) GetRolesBy(ctx context.Context, by db.IHelper) (Roles, error) {
+	qf, args := db.NewScopedHelper(by, "tenant_id = ?", requests.TenantId(ctx)).BuildSqlAndArgsWithWherePrefix()
+	//goland:noinspection SqlResolve
+	q := m.dbr.Rebind(getRolesByQuery[m.driver].query(qf))
+	return m.queryRoles(ctx, q, args...)
+}
+
+func (m *roleManager) GetRolePermissionsBy(ctx context.Context, by db.IHelper) (RolePermissions, error) {
+	qf, args := db.NewScopedHelper(
+		by, "role_id IN (SELECT id FROM "+tableRoles+" WHERE tenant_id = ?)", requests.TenantId(ctx),
+	).BuildSqlAndArgsWithWherePrefix()
+	//goland:noinspection SqlResolve
+	q := m.dbr.Rebind(getRolePermissionsByQuery[m.driver].query(qf))
+	rs := make(RolePermissions, 0)
+	rows, err := m.dbr.Query(ctx, q, args...)
+	defer func() {
+		rules.WhenTrue(rows != nil, func() {
+			_ = rows.Close()
+		})
+	}()
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return rs, db.ErrorRecordNotFound
+		}
+		return rs, err
+	}
+	for rows.Next() {
+		var item RolePermission
+		if err = rows.StructScan(&item); err != nil {
+			return rs, err
+		}
+		rs = append(rs, &item)
+	}
+	return rs, nil
+}
+
+func (m *roleManager) GetRolesOfUser(ctx context.Context, userId int64) (Roles, error) {
+	q := m.dbr.Rebind(getRolesOfUserQuery[m.driver].query())
+	return m.queryRoles(ctx, q, userId, requests.TenantId(ctx))
+}
+
+func (m *roleManager) queryRoles(ctx context.Context, q string, args ...interface{}) (Roles, error) {
+	rs := make(Roles, 0)
+	rows, err := m.dbr.Query(ctx, q, args...)
+	defer func() {
+		rules.WhenTrue(rows != nil, func() {
+			_ = rows.Close()
+		})
+	}()
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return rs, db.ErrorRecordNotFound
+		}
+		return rs, err
+	}
+	for rows.Next() {
+		var item Role
+		if err = rows.StructScan(&item); err != nil {
+			return rs, err
+		}
+		rs = append(rs, &item)
+	}
+	return rs, nil
+}
+
+func (m *roleManager) ModifyRole(ctx context.Context, item Role) error {
+	if item.ID < 1 {
+		return db.ErrorInvalidArgument
+	}
+	if item.ParentID.Valid && int(item.ParentID.Int64) == item.ID {
+		return ErrRoleSelfInheritance
+	}
+	if item.ParentID.Valid && item.ParentID.Int64 > 0 {
+		if err := m.ensureRolesOfTenant(ctx, int(item.ParentID.Int64)); err != nil {
+			return err
+		}
+		if err := m.ensureNotAncestor(ctx, item.ID, int(item.ParentID.Int64)); err != nil {
+			return err
+		}
+	}
+	q := modifyRoleQuery[m.driver].query()
+	item.TenantID = requests.TenantId(ctx)
+	if _, err := m.dbw.NamedExec(ctx, q, item); err != nil {
+		return err
+	}
+	return m.Invalidate(ctx)
+}
+
+// ensureNotAncestor walk up the ancestor chain of the new parent, the role found along the way means cycle
+func (m *roleManager) ensureNotAncestor(ctx context.Context, roleId, parentId int) error {
+	var found int
+	q := m.dbw.Rebind(getRoleAncestorCountQuery[m.driver].query())
+	if err := m.dbw.QueryRow(ctx, q, parentId, roleId).Scan(&found); err != nil {
+		return err
+	}
+	if found > 0 {
+		return ErrRoleCycle
+	}
+	return nil
+}
+
+// ensureRolesOfTenant ensure all roles referenced belong to the tenant of context
+func (m *roleManager) ensureRolesOfTenant(ctx context.Context, roleIds ...int) error {
+	if len(roleIds) < 1 {
+		return nil
+	}
+	var total int
+	err := m.dbw.QueryRow(
+		ctx, m.dbw.Rebind(countRolesOfTenantQuery[m.driver].query(len(roleIds))), idsWithTenant(ctx, roleIds)...,
+	).Scan(&total)
+	if err != nil {
+		return err
+	}
+	if total != len(roleIds) {
+		return db.ErrorInvalidArgument
+	}
+	return nil
+}
+
+func (m *roleManager) ModifyRolePermission(ctx context.Context, item RolePermission) error {
+	if item.ID < 1 {
+		return db.ErrorInvalidArgument
+	}
+	q := modifyRolePermissionQuery[m.driver].query()
+	_, err := m.dbw.NamedExec(ctx, q, struct {
+		RolePermission
+		TenantID string `db:"tenant_id"`
+	}{RolePermission: item, TenantID: requests.TenantId(ctx)})
+	if err != nil {
+		return err
+	}
+	return m.Invalidate(ctx)
+}
+
+func (m *roleManager) VoidRolesByIds(ctx context.Context, ids ...int) error {
+	return m.execByIds(ctx, voidRolesByIdsQuery[m.driver].query(len(ids)), ids...)
+}
+
+func (m *roleManager) RemoveRolesByIds(ctx context.Context, ids ...int) error {
+	return m.execByIds(ctx, removeRolesByIdsQuery[m.driver].query(len(ids)), ids...)
+}
+
+func (m *roleManager) RemoveRolePermissionsByIds(ctx context.Context, ids ...int) error {
+	return m.execByIds(ctx, removeRolePermissionsByIdsQuery[m.driver].query(len(ids)), ids...)
+}
+
+func (m *roleManager) execByIds(ctx context.Context, q string, ids ...int) error {
+	if eval.IsEmpty(ids) {
+		return db.ErrorEmptyArguments
+	}
+	if _, err := m.dbw.Exec(ctx, m.dbw.Rebind(q), idsWithTenant(ctx, ids)...); err != nil {
+		return err
+	}
+	return m.Invalidate(ctx)
+}
+
+func (m *roleManager) AssignRoles(ctx context.Context, userId int64, roleIds ...int) error {
+	if eval.IsEmpty(roleIds) {
+		return db.ErrorEmptyArguments
+	}
+	// only roles of the same tenant could be assigned
+	distinct := make([]int, 0)
+	for _, roleId := range roleIds {
+		if !utils.InArray(distinct, roleId) {
+			distinct = append(distinct, roleId)
+		}
+	}
+	if err := m.ensureRolesOfTenant(ctx, distinct...); err != nil {
+		return err
+	}
+	placeholders := assignUserRolesQuery[m.driver].placeholder(len(roleIds))
+	tenantId := requests.TenantId(ctx)
+	args := make([]interface{}, 0)
+	for _, roleId := range roleIds {
+		args = append(args, tenantId, userId, roleId)
+	}
+	q := assignUserRolesQuery[m.driver].query(strings.Join(placeholders, ","))
+	if _, err := m.dbw.Exec(ctx, m.dbw.Rebind(q), args...); err != nil {
+		return err
+	}
+	return m.Invalidate(ctx, userId)
+}
+
+func (m *roleManager) RevokeRoles(ctx context.Context, userId int64, roleIds ...int) error {
+	if eval.IsEmpty(roleIds) {
+		return db.ErrorEmptyArguments
+	}
+	args := []interface{}{requests.TenantId(ctx), userId}
+	for _, roleId := range roleIds {
+		args = append(args, roleId)
+	}
+	q := revokeUserRolesQuery[m.driver].query(len(roleIds))
+	if _, err := m.dbw.Exec(ctx, m.dbw.Rebind(q), args...); err != nil {
+		return err
+	}
+	return m.Invalidate(ctx, userId)
+}
+
+func (m *roleManager) ResolvePermissions(ctx context.Context, userId int64) (ResolvedPermissions, error) {
+	tenantId := requests.TenantId(ctx)
+	field := permissionsKey(tenantId, userId)
+	// cached value is json string, empty means not cached yet
+	var cached string
+	if err := m.mem.HGet(ctx, inMemoryUserPermissionsHashKey, field, &cached); err == nil && len(cached) > 0 {
+		var rs ResolvedPermissions
+		if err = json.Unmarshal([]byte(cached), &rs); err == nil {
+			return rs, nil
+		}
+	}
+	rs := make(ResolvedPermissions)
+	rows, err := m.dbr.Query(ctx, m.dbr.Rebind(getResolvedPermissionsOfUserQuery[m.driver].query()), userId, tenantId)
+	defer func() {
+		rules.WhenTrue(rows != nil, func() {
+			_ = rows.Close()
+		})
+	}()
+	if err != nil {
+		return rs, err
+	}
+	for rows.Next() {
+		var (
+			resource string
+			scopes   Scopes
+		)
+		if err = rows.Scan(&resource, &scopes); err != nil {
+			return rs, err
+		}
+		rs.Merge(resource, scopes)
+	}
+	if err = m.mem.HSet(ctx, inMemoryUserPermissionsHashKey, field, rs.String()); err != nil {
+		m.log.WarnWithProps(map[string]interface{}{
+			"context":   "role.resolve_permissions",
+			"user_id":   userId,
+			"tenant_id": tenantId,
+		}, err.Error())
+	}
+	return rs, nil
+}
+
+func (m *roleManager) IsAllowed(ctx context.Context, userId int64, resource string, scope Scope) (bool, error) {
+	permissions, err := m.ResolvePermissions(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	return permissions.IsAllowed(resource, scope), nil
+}
+
+func (m *roleManager) Invalidate(ctx context.Context, userIds ...int64) error {
+	if len(userIds) < 1 {
+		return m.mem.Del(ctx, inMemoryUserPermissionsHashKey)
+	}
+	tenantId := requests.TenantId(ctx)
+	fields := make([]string, len(userIds))
+	for i, userId := range userIds {
+		fields[i] = permissionsKey(tenantId, userId)
+	}
+	return m.mem.HDel(ctx, inMemoryUserPermissionsHashKey, fields...)
+}
+
+// permissionsKey of user within tenant, the same user might be granted differently on each tenant
+func permissionsKey(tenantId string, userId int64) string {
+	return tenantId + "/" + strconv.FormatInt(userId, 10)
+}
+
+func (m *roleManager) tableCheck(ctx context.Context, driver db.SupportedDriver) (int, error) {
+	total := 0
+	if !utils.KeyExistsInMap(roleTableExistenceCheckQuery, driver) {
+		return total, errors.New("driver not supported by table existence check")
+	}
+	tableChecks := utils.GetValueOnMap(roleTableExistenceCheckQuery, driver, []string{})
+	if len(tableChecks) < 1 {
+		return total, errors.New("no table existence check query exists")
+	}
+	for _, checkQuery := range tableChecks {
+		var count sql.NullInt16
+		err := m.dbw.QueryRow(ctx, checkQuery).Scan(&count)
+		if err != nil {
+			return total, err
+		}
+		total += int(count.Int16)
+	}
+	return total, nil
+}
+
+func (m *roleManager) initSchema(ctx context.Context) error {
+	driver := m.dbw.Driver()
+	flavor := getFlavorByDriver(driver)
+	// to avoid unnecessary execution of schema scaffolding,
+	// check the existence of tables -- should return total table of 3
+	total, err := m.tableCheck(ctx, driver)
+	if err != nil {
+		return err
+	}
+	if total == 3 {
+		return db.ExecDefinitions(ctx, m.dbw, roleTenancyDefinition)
+	}
+	if !utils.KeyExistsInMap(userCustomDefinitions, driver) {
+		return errors.New("driver not supported by custom definition")
+	}
+	tables := []struct {
+		name    string
+		columns [][]string
+		indexes []string
+	}{
+		{
+			name:    tableRoles,
+			columns: utils.GetValueOnMap(rolesColumnsDefinition, driver, [][]string{}),
+			indexes: utils.GetValueOnMap(rolesTableIndexDefinition, driver, []string{}),
+		},
+		{
+			name:    tableRolePermissions,
+			columns: utils.GetValueOnMap(rolePermissionsColumnsDefinition, driver, [][]string{}),
+			indexes: utils.GetValueOnMap(rolePermissionsTableIndexDefinition, driver, []string{}),
+		},
+		{
+			name:    tableUserRoles,
+			columns: utils.GetValueOnMap(userRolesColumnsDefinition, driver, [][]string{}),
+			indexes: utils.GetValueOnMap(userRolesTableIndexDefinition, driver, []string{}),
+		},
+	}
+	tx := m.dbw.MustBegin(ctx, &sql.TxOptions{})
+	// create custom type when not exist yet
+	for _, definition := range userCustomDefinitions[driver] {
+		_, err = tx.Exec(definition)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	for _, table := range tables {
+		if len(table.columns) < 1 {
+			_ = tx.Rollback()
+			return fmt.Errorf("%s column definitions is empty", table.name)
+		}
+		builder := sqlbuilder.NewCreateTableBuilder().CreateTable(table.name).IfNotExists()
+		for _, definition := range table.columns {
+			builder = builder.Define(definition...)
+		}
+		q, _ := builder.BuildWithFlavor(flavor)
+		if _, err = tx.Exec(q); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		for _, definition := range table.indexes {
+			if _, err = tx.Exec(definition); err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func (m *roleManager) Init() error {
+	return m.initSchema(context.Background())
+}
+
+func (m *roleManager) MustInit() RoleManager {
+	if err := m.Init(); err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m *roleManager) AddOptions(opts ...common.Option[roleManager]) RoleManager {
+	for _, opt := range opts {
+		opt.Apply(m)
+	}
+	return m
+}
+
+func NewRoleManager(dbm db.Manager, opts ...common.Option[roleManager]) RoleManager {
+	m := &roleManager{
+		dbw: dbm,
+		dbr: dbm,
+		driver: rules.WhenTrueRE1(dbm == nil, func() db.SupportedDriver {
+			return db.DriverPostgreSQL
+		}, func() db.SupportedDriver {
+			return dbm.Driver()
+		}),
+		log: logger.NewNoop(),
+		mem: inmemory.NewNoop(),
+	}
+	m.AddOptions(opts...)
+	return m
+}
@@ -0,0 +1,155 @@
+//go:build integration
+
+/**
+ * @Author: steven
+ * @Description:
+ * @File: role_integration_test
+ * @Date: 02/08/24 14.20
+ */
+
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/evorts/kevlars/db"
+	"github.com/evorts/kevlars/inmemory"
+	"github.com/evorts/kevlars/requests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"log"
+	"testing"
+	"time"
+)
+
+type roleTestSuite struct {
+	suite.Suite
+
+	ctx       context.Context
+	db        db.Manager
+	container *postgres.PostgresContainer
+	redis     *miniredis.Miniredis
+	rm        RoleManager
+}
+
+func (ts *roleTestSuite) SetupTest() {
+	var err error
+	ts.ctx = context.Background()
+	ts.container, err = postgres.RunContainer(
+		ts.ctx,
+		testcontainers.WithImage("docker.io/postgres:16-alpine"),
+		testcontainers.WithHostPortAccess(55433),
+		postgres.WithDatabase("test_db"),
+		postgres.WithUsername("user"),
+		postgres.WithPassword("secrets"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(5*time.Second),
+		),
+	)
+	ts.Require().NoError(err)
+	dsn, err := ts.container.ConnectionString(ts.ctx, "sslmode=disable")
+	ts.Require().NoError(err)
+	ts.db = db.New(db.DriverPostgreSQL, dsn).MustConnect(ts.ctx)
+	ts.redis, err = miniredis.Run()
+	ts.Require().NoError(err)
+	ts.rm = NewRoleManager(ts.db, RoleWithInMemory(inmemory.NewRedis(ts.redis.Addr()).MustConnect(ts.ctx))).MustInit()
+}
+
+func (ts *roleTestSuite) addRole(name string, parent *Role) *Role {
+	role := &Role{Name: name}
+	if parent != nil {
+		role.ParentID = sql.NullInt64{Int64: int64(parent.ID), Valid: true}
+	}
+	roles, err := ts.rm.AddRoles(ts.ctx, Roles{role})
+	ts.Require().NoError(err)
+	return roles[0]
+}
+
+func (ts *roleTestSuite) TestIsAllowedThroughRoles() {
+	viewer := ts.addRole("viewer", nil)
+	editor := ts.addRole("editor", viewer)
+	_, err := ts.rm.AddRolePermissions(ts.ctx, RolePermissions{
+		{RoleID: viewer.ID, Resource: "/res/a", Scopes: Scopes{ScopeRead}},
+		{RoleID: editor.ID, Resource: "/res/a", Scopes: Scopes{ScopeWrite}},
+	})
+	ts.Require().NoError(err)
+	ts.Require().NoError(ts.rm.AssignRoles(ts.ctx, 1, editor.ID))
+	// read inherited from viewer, write granted by editor
+	for _, scope := range []Scope{ScopeRead, ScopeWrite} {
+		allowed, errA := ts.rm.IsAllowed(ts.ctx, 1, "/res/a", scope)
+		assert.NoError(ts.T(), errA)
+		assert.True(ts.T(), allowed)
+	}
+	allowed, err := ts.rm.IsAllowed(ts.ctx, 1, "/res/a", ScopeDelete)
+	assert.NoError(ts.T(), err)
+	assert.False(ts.T(), allowed)
+	// detaching the parent drop the inherited permissions
+	ts.Require().NoError(ts.rm.ModifyRole(ts.ctx, Role{ID: editor.ID, ParentID: sql.NullInt64{Valid: true}}))
+	allowed, err = ts.rm.IsAllowed(ts.ctx, 1, "/res/a", ScopeRead)
+	assert.NoError(ts.T(), err)
+	assert.False(ts.T(), allowed)
+	// revoking role should invalidate the cached permissions
+	ts.Require().NoError(ts.rm.RevokeRoles(ts.ctx, 1, editor.ID))
+	allowed, err = ts.rm.IsAllowed(ts.ctx, 1, "/res/a", ScopeWrite)
+	assert.NoError(ts.T(), err)
+	assert.False(ts.T(), allowed)
+}
+
+func (ts *roleTestSuite) TestModifyRoleRejectCycle() {
+	root := ts.addRole("root", nil)
+	middle := ts.addRole("middle", root)
+	leaf := ts.addRole("leaf", middle)
+	assert.ErrorIs(ts.T(), ts.rm.ModifyRole(ts.ctx, Role{ID: root.ID, ParentID: sql.NullInt64{Int64: int64(root.ID), Valid: true}}), ErrRoleSelfInheritance)
+	assert.ErrorIs(ts.T(), ts.rm.ModifyRole(ts.ctx, Role{ID: root.ID, ParentID: sql.NullInt64{Int64: int64(leaf.ID), Valid: true}}), ErrRoleCycle)
+	assert.ErrorIs(ts.T(), ts.rm.ModifyRole(ts.ctx, Role{ID: middle.ID, ParentID: sql.NullInt64{Int64: int64(leaf.ID), Valid: true}}), ErrRoleCycle)
+	// moving under a sibling branch is fine
+	other := ts.addRole("other", root)
+	assert.NoError(ts.T(), ts.rm.ModifyRole(ts.ctx, Role{ID: leaf.ID, ParentID: sql.NullInt64{Int64: int64(other.ID), Valid: true}}))
+}
+
+func (ts *roleTestSuite) TestTenantIsolation() {
+	acme := requests.WithTenantId(ts.ctx, "acme")
+	roles, err := ts.rm.AddRoles(acme, Roles{{Name: "viewer"}})
+	ts.Require().NoError(err)
+	viewer := roles[0]
+	assert.Equal(ts.T(), "acme", viewer.TenantID)
+	_, err = ts.rm.AddRolePermissions(acme, RolePermissions{{RoleID: viewer.ID, Resource: "/res/a", Scopes: Scopes{ScopeRead}}})
+	ts.Require().NoError(err)
+	ts.Require().NoError(ts.rm.AssignRoles(acme, 1, viewer.ID))
+	allowed, err := ts.rm.IsAllowed(acme, 1, "/res/a", ScopeRead)
+	assert.NoError(ts.T(), err)
+	assert.True(ts.T(), allowed)
+	// cached permissions of the tenant not leaked into another tenant
+	allowed, err = ts.rm.IsAllowed(ts.ctx, 1, "/res/a", ScopeRead)
+	assert.NoError(ts.T(), err)
+	assert.False(ts.T(), allowed)
+	// role of other tenant could not be referenced
+	assert.ErrorIs(ts.T(), ts.rm.AssignRoles(ts.ctx, 1, viewer.ID), db.ErrorInvalidArgument)
+	_, err = ts.rm.AddRoles(ts.ctx, Roles{{Name: "editor", ParentID: sql.NullInt64{Int64: int64(viewer.ID), Valid: true}}})
+	assert.ErrorIs(ts.T(), err, db.ErrorInvalidArgument)
+	_, err = ts.rm.AddRolePermissions(ts.ctx, RolePermissions{{RoleID: viewer.ID, Resource: "/res/b", Scopes: Scopes{ScopeRead}}})
+	assert.ErrorIs(ts.T(), err, db.ErrorInvalidArgument)
+	others, err := ts.rm.GetRolesOfUser(ts.ctx, 1)
+	ts.Require().NoError(err)
+	assert.Empty(ts.T(), others)
+	// the same name is allowed on other tenant
+	_, err = ts.rm.AddRoles(ts.ctx, Roles{{Name: "viewer"}})
+	assert.NoError(ts.T(), err)
+}
+
+func (ts *roleTestSuite) TearDownTest() {
+	ts.redis.Close()
+	if err := ts.container.Terminate(ts.ctx); err != nil {
+		log.Fatalf("failed to terminate container: %s", err)
+	}
+}
+
+func TestRoleTestSuite(t *testing.T) {
+	suite.Run(t, new(roleTestSuite))
+}
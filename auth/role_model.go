@@ -0,0 +1,89 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: role_model
+ * @Date: 02/08/24 09.12
+ */
+
+package auth
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+type Role struct {
+	ID          int           `db:"id"`
+	TenantID    string        `db:"tenant_id"` // populated from context on write
+	Name        string        `db:"name"`
+	Description string        `db:"description"`
+	ParentID    sql.NullInt64 `db:"parent_id"` // role inherit all permissions of its parent, valid zero detach it on modify
+	Disabled    bool          `db:"disabled"`
+	CreatedAt   time.Time     `db:"created_at"`
+	UpdatedAt   sql.NullTime  `db:"updated_at"`
+	DisabledAt  sql.NullTime  `db:"disabled_at"`
+}
+
+type Roles []*Role
+
+type RolePermission struct {
+	ID         int          `db:"id"`
+	RoleID     int          `db:"role_id"`
+	Resource   string       `db:"resource"`
+	Scopes     Scopes       `db:"scopes"`
+	Disabled   bool         `db:"disabled"`
+	CreatedAt  time.Time    `db:"created_at"`
+	UpdatedAt  sql.NullTime `db:"updated_at"`
+	DisabledAt sql.NullTime `db:"disabled_at"`
+}
+
+type RolePermissions []*RolePermission
+
+type RoleWithPermissions struct {
+	*Role
+	Permissions RolePermissions
+}
+
+type RolesWithPermissions []*RoleWithPermissions
+
+type UserRole struct {
+	ID        int       `db:"id"`
+	TenantID  string    `db:"tenant_id"`
+	UserID    int64     `db:"user_id"`
+	RoleID    int       `db:"role_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type UserRoles []*UserRole
+
+// ResolvedPermissions map[resource]Scopes -- the effective permissions after role inheritance resolved
+type ResolvedPermissions map[string]Scopes
+
+func (p ResolvedPermissions) Merge(resource string, scopes Scopes) ResolvedPermissions {
+	existing, ok := p[resource]
+	if !ok {
+		p[resource] = scopes
+		return p
+	}
+	for _, scope := range scopes {
+		if !existing.AllowedTo(scope) {
+			existing = append(existing, scope)
+		}
+	}
+	p[resource] = existing
+	return p
+}
+
+func (p ResolvedPermissions) IsAllowed(resource string, scope Scope) bool {
+	scopes, ok := p[resource]
+	if !ok {
+		return false
+	}
+	return scopes.AllowedTo(scope)
+}
+
+func (p ResolvedPermissions) String() string {
+	b, _ := json.Marshal(p)
+	return string(b)
+}
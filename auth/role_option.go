@@ -0,0 +1,33 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: role_option
+ * @Date: 02/08/24 09.55
+ */
+
+package auth
+
+import (
+	"github.com/evorts/kevlars/common"
+	"github.com/evorts/kevlars/db"
+	"github.com/evorts/kevlars/inmemory"
+	"github.com/evorts/kevlars/logger"
+)
+
+func RoleWithLogger(v logger.Manager) common.Option[roleManager] {
+	return common.OptionFunc[roleManager](func(m *roleManager) {
+		m.log = v
+	})
+}
+
+func RoleWithInMemory(v inmemory.Manager) common.Option[roleManager] {
+	return common.OptionFunc[roleManager](func(m *roleManager) {
+		m.mem = v
+	})
+}
+
+func RoleWithDatabaseRead(db db.Manager) common.Option[roleManager] {
+	return common.OptionFunc[roleManager](func(m *roleManager) {
+		m.dbr = db
+	})
+}
@@ -0,0 +1,247 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: role_query
+ * @Date: 02/08/24 09.40
+ */
+
+package auth
+
+import (
+	"fmt"
+	"github.com/evorts/kevlars/db"
+	"github.com/evorts/kevlars/utils"
+	"strings"
+)
+
+/** Add Query **/
+var (
+	addRoleQuery = map[db.SupportedDriver]struct {
+		placeholder func(int) []string
+		query       func(v string) string
+	}{
+		db.DriverPostgreSQL: {
+			placeholder: func(repeat int) []string {
+				return db.PlaceholderRepeat(
+					fmt.Sprintf(
+						`(%s,CASE WHEN ? THEN current_timestamp END)`,
+						strings.Join(utils.RepeatInSlice("?", 5), ","),
+					), repeat,
+				)
+			},
+			query: func(v string) string {
+				return `INSERT INTO ` + tableRoles + `(tenant_id, name, description, parent_id, disabled, disabled_at)
+					VALUES ` + v + `
+					RETURNING id, tenant_id, name, description, parent_id, disabled, created_at, disabled_at
+		`
+			},
+		},
+	}
+	addRolePermissionQuery = map[db.SupportedDriver]struct {
+		placeholder func(int) []string
+		query       func(v string) string
+	}{
+		db.DriverPostgreSQL: {
+			placeholder: func(repeat int) []string {
+				return db.PlaceholderRepeat(
+					fmt.Sprintf(
+						`(%s,CASE WHEN ? THEN current_timestamp END)`,
+						strings.Join(utils.RepeatInSlice("?", 4), ","),
+					), repeat,
+				)
+			},
+			query: func(v string) string {
+				return `INSERT INTO ` + tableRolePermissions + `(role_id, resource, scopes, disabled, disabled_at)
+					VALUES ` + v + `
+					ON CONFLICT (role_id, resource) DO
+						UPDATE SET
+							scopes = excluded.scopes,
+							disabled = excluded.disabled,
+							disabled_at = excluded.disabled_at,
+							updated_at = current_timestamp
+					RETURNING id, role_id, resource, scopes, disabled, created_at, disabled_at
+		`
+			},
+		},
+	}
+	assignUserRolesQuery = map[db.SupportedDriver]struct {
+		placeholder func(int) []string
+		query       func(v string) string
+	}{
+		db.DriverPostgreSQL: {
+			placeholder: func(repeat int) []string {
+				return db.PlaceholderRepeat(`(?,?,?)`, repeat)
+			},
+			query: func(v string) string {
+				return `INSERT INTO ` + tableUserRoles + `(tenant_id, user_id, role_id)
+					VALUES ` + v + `
+					ON CONFLICT (tenant_id, user_id, role_id) DO NOTHING`
+			},
+		},
+	}
+)
+
+/** Remove/Void Query **/
+var (
+	removeRolesByIdsQuery = map[db.SupportedDriver]struct {
+		query func(pl int) string
+	}{
+		db.DriverPostgreSQL: {
+			query: func(pl int) string {
+				return `DELETE FROM` + " " + tableRoles + ` WHERE id IN(` + db.BuildPlaceholder(pl) + `) AND tenant_id = ?`
+			},
+		},
+	}
+	voidRolesByIdsQuery = map[db.SupportedDriver]struct {
+		query func(pl int) string
+	}{
+		db.DriverPostgreSQL: {
+			query: func(pl int) string {
+				return `UPDATE ` + tableRoles + ` SET disabled=true, disabled_at=current_timestamp
+					WHERE id IN(` + db.BuildPlaceholder(pl) + `) AND tenant_id = ?`
+			},
+		},
+	}
+	removeRolePermissionsByIdsQuery = map[db.SupportedDriver]struct {
+		query func(pl int) string
+	}{
+		db.DriverPostgreSQL: {
+			query: func(pl int) string {
+				return `DELETE FROM` + " " + tableRolePermissions + ` WHERE id IN(` + db.BuildPlaceholder(pl) + `)
+					AND role_id IN (SELECT id FROM ` + tableRoles + ` WHERE tenant_id = ?)`
+			},
+		},
+	}
+	revokeUserRolesQuery = map[db.SupportedDriver]struct {
+		query func(pl int) string
+	}{
+		db.DriverPostgreSQL: {
+			query: func(pl int) string {
+				return `DELETE FROM` + " " + tableUserRoles + ` WHERE tenant_id = ? AND user_id = ? AND role_id IN(` + db.BuildPlaceholder(pl) + `)`
+			},
+		},
+	}
+)
+
+/** Update Query **/
+var (
+	modifyRoleQuery = map[db.SupportedDriver]struct {
+		query func() string
+	}{
+		db.DriverPostgreSQL: {
+			query: func() string {
+				return `UPDATE ` + tableRoles + ` SET
+					name=(CASE WHEN :name <> '' THEN :name ELSE name END),
+					description=(CASE WHEN :description <> '' THEN :description ELSE description END),
+					parent_id=(CASE
+						WHEN CAST(:parent_id AS int) IS NULL THEN parent_id
+						WHEN CAST(:parent_id AS int) < 1 THEN NULL
+						ELSE CAST(:parent_id AS int) END),
+					updated_at=current_timestamp
+				WHERE id=:id AND tenant_id=:tenant_id`
+			},
+		},
+	}
+	modifyRolePermissionQuery = map[db.SupportedDriver]struct {
+		query func() string
+	}{
+		db.DriverPostgreSQL: {
+			query: func() string {
+				return `UPDATE ` + tableRolePermissions + ` SET
+					resource=(CASE WHEN :resource <> '' THEN :resource ELSE resource END),
+					scopes=(CASE WHEN array_length(CAST(:scopes AS access_scope[]), 1) > 0 THEN :scopes ELSE scopes END),
+					updated_at=current_timestamp
+				WHERE id=:id AND role_id IN (SELECT id FROM ` + tableRoles + ` WHERE tenant_id=:tenant_id)`
+			},
+		},
+	}
+)
+
+/** Get Query **/
+var (
+	getRolesByQuery = map[db.SupportedDriver]struct {
+		query func(qf string) string
+	}{
+		db.DriverPostgreSQL: {
+			query: func(qf string) string {
+				return `SELECT
+					id, tenant_id, name, description, parent_id, disabled, created_at, updated_at, disabled_at
+				FROM` + " " + tableRoles + " " + qf
+			},
+		},
+	}
+	getRolePermissionsByQuery = map[db.SupportedDriver]struct {
+		query func(qf string) string
+	}{
+		db.DriverPostgreSQL: {
+			query: func(qf string) string {
+				return `SELECT
+					id, role_id, resource, scopes, disabled, created_at, updated_at, disabled_at
+				FROM` + " " + tableRolePermissions + " " + qf
+			},
+		},
+	}
+	getRolesOfUserQuery = map[db.SupportedDriver]struct {
+		query func() string
+	}{
+		db.DriverPostgreSQL: {
+			query: func() string {
+				return `SELECT
+					r.id, r.tenant_id, r.name, r.description, r.parent_id, r.disabled, r.created_at, r.updated_at, r.disabled_at
+				FROM` + " " + tableRoles + ` r JOIN ` + tableUserRoles + ` ur ON ur.role_id = r.id
+				WHERE ur.user_id = ? AND ur.tenant_id = ?
+				ORDER BY r.id`
+			},
+		},
+	}
+	// getRoleAncestorCountQuery count the given role along the ancestor chain of the parent, parent itself included
+	getRoleAncestorCountQuery = map[db.SupportedDriver]struct {
+		query func() string
+	}{
+		db.DriverPostgreSQL: {
+			query: func() string {
+				return `WITH RECURSIVE ancestors(id, parent_id) AS (
+						SELECT id, parent_id FROM ` + tableRoles + ` WHERE id = ?
+						UNION
+						SELECT p.id, p.parent_id FROM ` + tableRoles + ` p
+							JOIN ancestors a ON a.parent_id = p.id
+					)
+					SELECT count(id) FROM ancestors WHERE id = ?`
+			},
+		},
+	}
+	// countRolesOfTenantQuery used to ensure roles referenced belong to the tenant
+	countRolesOfTenantQuery = map[db.SupportedDriver]struct {
+		query func(pl int) string
+	}{
+		db.DriverPostgreSQL: {
+			query: func(pl int) string {
+				return `SELECT count(DISTINCT id) FROM ` + tableRoles + ` WHERE id IN(` + db.BuildPlaceholder(pl) + `) AND tenant_id = ?`
+			},
+		},
+	}
+	// getResolvedPermissionsOfUserQuery walk up the role hierarchy from the roles assigned to user within tenant,
+	// union are used instead of union all to stop the recursion when cycle occurred
+	getResolvedPermissionsOfUserQuery = map[db.SupportedDriver]struct {
+		query func() string
+	}{
+		db.DriverPostgreSQL: {
+			query: func() string {
+				return `WITH RECURSIVE resolved_roles(id) AS (
+						SELECT r.id FROM ` + tableRoles + ` r
+							JOIN ` + tableUserRoles + ` ur ON ur.role_id = r.id
+						WHERE ur.user_id = ? AND ur.tenant_id = ? AND r.tenant_id = ur.tenant_id AND r.disabled = false
+						UNION
+						SELECT p.id FROM ` + tableRoles + ` p
+							JOIN ` + tableRoles + ` c ON c.parent_id = p.id AND c.tenant_id = p.tenant_id
+							JOIN resolved_roles rr ON rr.id = c.id
+						WHERE p.disabled = false
+					)
+					SELECT rp.resource, rp.scopes
+					FROM ` + tableRolePermissions + ` rp
+						JOIN resolved_roles rr ON rr.id = rp.role_id
+					WHERE rp.disabled = false`
+			},
+		},
+	}
+)
@@ -0,0 +1,46 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: totp_test
+ * @Date: 07/08/24 09.02
+ */
+
+package auth
+
+import (
+	"encoding/base32"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTOTPVerify(t *testing.T) {
+	// test vectors of RFC 6238 appendix B (sha1), truncated into 6 digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	tests := map[string]struct {
+		at   int64
+		code string
+	}{
+		"at 59":         {at: 59, code: "287082"},
+		"at 1111111109": {at: 1111111109, code: "081804"},
+		"at 1234567890": {at: 1234567890, code: "005924"},
+		"at 2000000000": {at: 2000000000, code: "279037"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			step, ok := totpVerify(secret, tc.code, time.Unix(tc.at, 0))
+			assert.True(t, ok)
+			assert.Equal(t, tc.at/totpPeriod, step)
+			// previous step still accepted to tolerate clock drift, but not further
+			_, ok = totpVerify(secret, tc.code, time.Unix(tc.at+totpPeriod, 0))
+			assert.True(t, ok)
+			_, ok = totpVerify(secret, tc.code, time.Unix(tc.at+3*totpPeriod, 0))
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := totpProvisioningURI("evorts", "admin@evorts.com", "JBSWY3DPEHPK3PXP")
+	assert.Equal(t, "otpauth://totp/evorts:admin@evorts.com?algorithm=SHA1&digits=6&issuer=evorts&period=30&secret=JBSWY3DPEHPK3PXP", uri)
+}
@@ -10,19 +10,26 @@ package auth
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/evorts/kevlars/audit"
 	"github.com/evorts/kevlars/common"
+	"github.com/evorts/kevlars/crypt"
+	"github.com/evorts/kevlars/ctime"
 	"github.com/evorts/kevlars/db"
 	"github.com/evorts/kevlars/inmemory"
 	"github.com/evorts/kevlars/jwe"
 	"github.com/evorts/kevlars/logger"
+	"github.com/evorts/kevlars/requests"
 	"github.com/evorts/kevlars/rules"
+	"github.com/evorts/kevlars/rules/eval"
 	"github.com/evorts/kevlars/utils"
 	"github.com/huandu/go-sqlbuilder"
+	"github.com/lib/pq"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type UserManager interface {
@@ -32,6 +39,13 @@ type UserManager interface {
 	RemoveByUserIds(ctx context.Context, userIds ...int) error
 
 	GetByUserIds(ctx context.Context, userIds ...int) (UserAuthRecords, error)
+	// GetByEmail lookup users by blind index of email, so it works while email encrypted
+	GetByEmail(ctx context.Context, email string) (UserAuthRecords, error)
+	// GetByPhone lookup users by blind index of phone, so it works while phone encrypted
+	GetByPhone(ctx context.Context, phone string) (UserAuthRecords, error)
+	// EncryptFields backfill email, phone and totp secret stored as plain text before field encryption enabled,
+	// rebuild blind index of email and phone as well. return number of rows encrypted
+	EncryptFields(ctx context.Context) (int, error)
 
 	AddAccess(ctx context.Context, records ...UserAccessRecord) error
 	DisabledAccessByIds(ctx context.Context, ids ...int) error
@@ -41,6 +55,34 @@ type UserManager interface {
 
 	Introspect(ctx context.Context, token string, bindTo interface{}) error
 	Authenticate(ctx context.Context, id int64, creds string) (token string, err error)
+	// AuthenticateWithRefreshToken issue pair of access and refresh token bound into a new session
+	AuthenticateWithRefreshToken(ctx context.Context, id int64, creds string) (TokenPair, error)
+	// Refresh rotate the given refresh token into new pair of tokens
+	Refresh(ctx context.Context, refreshToken string) (TokenPair, error)
+	// Revoke access or refresh token until it expired, revoking refresh token also end its session
+	Revoke(ctx context.Context, token string) error
+
+	// Sessions of user, the session, lockout and second factor operations below scoped within tenant of the context
+	Sessions(ctx context.Context, userId int64) (Sessions, error)
+	RevokeSession(ctx context.Context, userId int64, sessionId string) error
+	// RevokeAllSessions log out user everywhere
+	RevokeAllSessions(ctx context.Context, userId int64) error
+	// Unlock user locked due to repeated failed authentication
+	Unlock(ctx context.Context, userId int64) error
+	// UnlockIp locked due to repeated failed authentication
+	UnlockIp(ctx context.Context, ip string) error
+
+	// EnrollTOTP start enrolment of time based one time password as second factor,
+	// the enrolment only take effect once confirmed with a valid code
+	EnrollTOTP(ctx context.Context, userId int64, account string) (TOTPEnrolment, error)
+	ConfirmTOTP(ctx context.Context, userId int64, code string) error
+	DisableTOTP(ctx context.Context, userId int64) error
+	// RegenerateRecoveryCodes invalidate the remaining recovery codes and issue new ones
+	RegenerateRecoveryCodes(ctx context.Context, userId int64) ([]string, error)
+	// AuthenticateWithMFA required by user with second factor enabled, code could be totp or recovery code
+	AuthenticateWithMFA(ctx context.Context, id int64, creds, code string) (TokenPair, error)
+	// StepUp verify second factor of already authenticated user and issue tokens carrying the step-up claim
+	StepUp(ctx context.Context, accessToken, code string) (TokenPair, error)
 
 	common.Init[UserManager]
 }
@@ -53,34 +95,55 @@ type userManager struct {
 	driver db.SupportedDriver
 	audit  audit.Manager
 	jwe    jwe.Manager
+	role   RoleManager
+	hasher crypt.Hasher
+
+	tokenExpiration        time.Duration
+	refreshTokenExpiration time.Duration
+	trackSession           bool
+	lockout                LockoutPolicy
+	mfaIssuer              string
 }
 
 const (
 	tableUserAuth   = "user_auth"
 	tableUserAccess = "user_access"
 
-	inMemoryUserCredsHashKey    = "user_creds"    // user_id -> creds
-	inMemoryUserTokenHashKey    = "user_token"    // user_token -> detail/claim
+	inMemoryUserTokenKey        = "user_token"    // user_token_{sha256 of token} -> detail/claim until token expired
 	inMemoryUserDisabledHashKey = "user_disabled" // user_id -> disabled state
+	inMemoryUserSessionsKey     = "user_sessions" // user_sessions_{tenant_id}/{user_id} -> session id -> expired at, index of user sessions
+	inMemoryUserSessionKey      = "user_session"  // user_session_{tenant_id}/{user_id}_{session_id} -> session until expired
+	inMemoryUserRevokedKey      = "user_revoked"  // user_revoked_{token_id} -> revoked state until token expired
+
+	userAuthFieldsBatchSize = 500
 )
 
 //goland:noinspection SqlResolve
 var (
 	userCustomDefinitions = map[db.SupportedDriver][]string{
 		db.DriverPostgreSQL: {
-			fmt.Sprintf(`CREATE TYPE access_scope AS ENUM('read', 'write', 'delete', 'undefined')`),
+			`DO $$ BEGIN
+				CREATE TYPE access_scope AS ENUM('read', 'write', 'delete', 'undefined');
+			EXCEPTION
+				WHEN duplicate_object THEN null;
+			END $$`,
 		},
 	}
 	userAuthTableExistenceCheckQuery = map[db.SupportedDriver][]string{
 		db.DriverPostgreSQL: {
 			fmt.Sprintf(`select count(table_name) as tableCount from information_schema.tables ist
-				       where ist.table_name in ('%s,%s')`, tableUserAuth, tableUserAccess),
+				       where ist.table_name in ('%s','%s')`, tableUserAuth, tableUserAccess),
 		},
 	}
 	userAuthColumnDefinitions = map[db.SupportedDriver][][]string{
 		db.DriverPostgreSQL: {
 			{"id", "serial", "primary key"},
+			{"tenant_id", "varchar(50)", "not null", "default ''"},
 			{"user_id", "int"},
+			{"email", "text", "default ''"},
+			{"phone", "text", "default ''"},
+			{"email_idx", "varchar(64)", "default ''"},
+			{"phone_idx", "varchar(64)", "default ''"},
 			{"creds", "varchar(128)"},
 			{"disabled", "boolean", "default false"},
 			{"created_at", "timestamp with time zone", "default current_timestamp"},
@@ -91,17 +154,32 @@ var (
 	}
 	userAuthIndexDefinitions = map[db.SupportedDriver][]string{
 		db.DriverPostgreSQL: {
-			fmt.Sprintf("create unique index %s_user_id_uidx on public.%s(user_id)", tableUserAuth, tableUserAuth),
-			fmt.Sprintf("create index %s_disabled_idx on public.%s(disabled)", tableUserAuth, tableUserAuth),
-			fmt.Sprintf("create index %s_created_at_idx on public.%s(created_at)", tableUserAuth, tableUserAuth),
+			fmt.Sprintf("create unique index if not exists %s_user_id_uidx on public.%s(user_id)", tableUserAuth, tableUserAuth),
+			fmt.Sprintf("create index if not exists %s_disabled_idx on public.%s(disabled)", tableUserAuth, tableUserAuth),
+			fmt.Sprintf("create index if not exists %s_created_at_idx on public.%s(created_at)", tableUserAuth, tableUserAuth),
+			fmt.Sprintf("create index if not exists %s_email_idx_idx on public.%s(email_idx)", tableUserAuth, tableUserAuth),
+			fmt.Sprintf("create index if not exists %s_phone_idx_idx on public.%s(phone_idx)", tableUserAuth, tableUserAuth),
+		},
+	}
+	// userAuthFieldEncryptionDefinition bring tables created before field encryption into the current shape,
+	// encrypted value longer than the plain one. existing rows has empty index until saved again
+	userAuthFieldEncryptionDefinition = map[db.SupportedDriver][]string{
+		db.DriverPostgreSQL: {
+			fmt.Sprintf("alter table %s alter column email type text", tableUserAuth),
+			fmt.Sprintf("alter table %s alter column phone type text", tableUserAuth),
+			fmt.Sprintf("alter table %s add column if not exists email_idx varchar(64) default ''", tableUserAuth),
+			fmt.Sprintf("alter table %s add column if not exists phone_idx varchar(64) default ''", tableUserAuth),
+			fmt.Sprintf("create index if not exists %s_email_idx_idx on public.%s(email_idx)", tableUserAuth, tableUserAuth),
+			fmt.Sprintf("create index if not exists %s_phone_idx_idx on public.%s(phone_idx)", tableUserAuth, tableUserAuth),
 		},
 	}
 	userAccessColumnsDefinition = map[db.SupportedDriver][][]string{
 		db.DriverPostgreSQL: {
 			{"id", "serial", "primary key"},
+			{"tenant_id", "varchar(50)", "not null", "default ''"},
 			{"user_id", "bigint", "not null"},
 			{"resource", "varchar(255)", "not null"},
-			{"scopes", "access_scope[]", "default '[]'::access_scope[]"},
+			{"scopes", "access_scope[]", "default array[]::access_scope[]"},
 			{"disabled", "boolean", "default false"},
 			{"created_at", "timestamp with time zone", "default current_timestamp"},
 			{"updated_at", "timestamp with time zone"},
@@ -110,52 +188,117 @@ var (
 	}
 	userAccessIndexDefinition = map[db.SupportedDriver][]string{
 		db.DriverPostgreSQL: {
-			fmt.Sprintf("create index if not exists %s_user_id_resource_uidx on public.%s(user_id, resource)", tableUserAccess, tableUserAccess),
+			fmt.Sprintf("create unique index if not exists %s_tenant_id_user_id_resource_uidx on public.%s(tenant_id, user_id, resource)", tableUserAccess, tableUserAccess),
 			fmt.Sprintf("create index if not exists %s_disabled_idx on public.%s(disabled)", tableUserAccess, tableUserAccess),
 			fmt.Sprintf("create index if not exists %s_created_at_idx on public.%s(created_at)", tableUserAccess, tableUserAccess),
 		},
 	}
+	// userTenancyDefinition bring tables created before tenant awareness into the current shape
+	userTenancyDefinition = map[db.SupportedDriver][]string{
+		db.DriverPostgreSQL: {
+			fmt.Sprintf("alter table %s add column if not exists tenant_id varchar(50) not null default ''", tableUserAccess),
+			fmt.Sprintf("drop index if exists %s_user_id_resource_uidx", tableUserAccess),
+			fmt.Sprintf("create unique index if not exists %s_tenant_id_user_id_resource_uidx on public.%s(tenant_id, user_id, resource)", tableUserAccess, tableUserAccess),
+			fmt.Sprintf("alter table %s add column if not exists tenant_id varchar(50) not null default ''", tableUserAuth),
+		},
+	}
+	// user belong to single tenant, saving user of other tenant is not found
 	userAuthSaveQuery = map[db.SupportedDriver]string{
 		db.DriverPostgreSQL: fmt.Sprintf(
-			`INSERT INTO %s (id, user_id, creds, disabled)
-					VALUES(:id, :user_id, :creds, :disabled)
+			`INSERT INTO %s (tenant_id, user_id, email, phone, email_idx, phone_idx, creds, disabled, disabled_at, expired_at)
+					VALUES(:tenant_id, :user_id, :email, :phone, :email_idx, :phone_idx, :creds, :disabled, CASE WHEN :disabled THEN current_timestamp END, :expired_at)
 				ON CONFLICT (user_id) DO 
 					UPDATE SET
-						CASE WHEN disabled <> excluded.disabled
-							THEN disabled = excluded.disabled
-						END,
-						creds = COALESCE(NULLIF(excluded.creds,''),creds),
-						CASE WHEN disabled 
-							THEN disabled_at = current_timestamp
-						END
+						email = COALESCE(NULLIF(excluded.email,''), %[1]s.email),
+						phone = COALESCE(NULLIF(excluded.phone,''), %[1]s.phone),
+						email_idx = COALESCE(NULLIF(excluded.email_idx,''), %[1]s.email_idx),
+						phone_idx = COALESCE(NULLIF(excluded.phone_idx,''), %[1]s.phone_idx),
+						creds = COALESCE(NULLIF(excluded.creds,''), %[1]s.creds),
+						disabled = excluded.disabled,
+						disabled_at = (CASE
+							WHEN excluded.disabled THEN COALESCE(%[1]s.disabled_at, current_timestamp)
+						END),
+						expired_at = COALESCE(excluded.expired_at, %[1]s.expired_at),
+						updated_at = current_timestamp
+					WHERE %[1]s.tenant_id = excluded.tenant_id
 				RETURNING id, user_id, disabled
 		`, tableUserAuth),
 	}
-	userAccessSaveQuery = map[db.SupportedDriver]string{
-		db.DriverPostgreSQL: fmt.Sprintf(
-			`INSERT INTO %s (id, user_id, resource, scopes, disabled, disabled_at)
-					VALUES(:id, :user_id, :creds, :disabled, CASE WHEN :disabled THEN current_timestamp ELSE NULL END)
-				ON CONFLICT (user_id) DO 
-					UPDATE SET
-						CASE WHEN disabled <> excluded.disabled
-							THEN disabled = excluded.disabled
-						END,
-						creds = COALESCE(NULLIF(excluded.creds,''),creds),
-						CASE WHEN disabled 
-							THEN disabled_at = current_timestamp
-						END
-				RETURNING id, user_id, disabled
-		`, tableUserAccess),
+	userAccessAddQuery = map[db.SupportedDriver]struct {
+		placeholder func(int) []string
+		query       func(v string) string
+	}{
+		db.DriverPostgreSQL: {
+			placeholder: func(repeat int) []string {
+				return db.PlaceholderRepeat(
+					fmt.Sprintf(
+						`(%s,CASE WHEN ? THEN current_timestamp END)`,
+						strings.Join(utils.RepeatInSlice("?", 5), ","),
+					), repeat,
+				)
+			},
+			query: func(v string) string {
+				return `INSERT INTO ` + tableUserAccess + `(tenant_id, user_id, resource, scopes, disabled, disabled_at)
+					VALUES ` + v + `
+					ON CONFLICT(tenant_id, user_id, resource) DO
+						UPDATE SET
+							scopes = excluded.scopes,
+							disabled = excluded.disabled,
+							updated_at = current_timestamp,
+							disabled_at = (CASE WHEN excluded.disabled THEN COALESCE(` + tableUserAccess + `.disabled_at, current_timestamp) END)
+				`
+			},
+		},
+	}
+	userAccessDisableByIdsQuery = map[db.SupportedDriver]struct {
+		query func(pl int) string
+	}{
+		db.DriverPostgreSQL: {
+			query: func(pl int) string {
+				return `UPDATE ` + tableUserAccess + ` SET disabled=true, disabled_at=current_timestamp, updated_at=current_timestamp
+					WHERE id IN(` + db.BuildPlaceholder(pl) + `) AND tenant_id = ?`
+			},
+		},
+	}
+	// only replace the hash verified, so concurrent password change is not overridden
+	userAuthRehashQuery = map[db.SupportedDriver]string{
+		db.DriverPostgreSQL: `UPDATE ` + tableUserAuth + ` SET creds = ? WHERE user_id = ? AND creds = ?`,
+	}
+	userAccessScopesQuery = map[db.SupportedDriver]string{
+		db.DriverPostgreSQL: `SELECT scopes FROM ` + tableUserAccess + ` WHERE user_id = ? AND resource = ? AND tenant_id = ? AND disabled = false`,
+	}
+	userAuthFieldsQuery = map[db.SupportedDriver]struct {
+		list   string
+		update string
+	}{
+		db.DriverPostgreSQL: {
+			list: `SELECT id, email, phone FROM ` + tableUserAuth + ` WHERE id > ? ORDER BY id LIMIT ?`,
+			// only replace the value read, so concurrent change is not overridden
+			update: `UPDATE ` + tableUserAuth + ` SET email = ?, phone = ?, email_idx = ?, phone_idx = ?, updated_at = current_timestamp
+				WHERE id = ? AND email = ? AND phone = ?`,
+		},
 	}
 )
 
 func (m *userManager) Add(ctx context.Context, records ...UserAuthRecord) error {
+	if eval.IsEmpty(records) {
+		return db.ErrorEmptyArguments
+	}
 	builder := sqlbuilder.NewInsertBuilder().
 		InsertInto(tableUserAuth).
-		Cols("user_id", "email", "phone", "creds", "disabled")
+		Cols("tenant_id", "user_id", "email", "phone", "email_idx", "phone_idx", "creds", "disabled", "expired_at")
+	tenantId := requests.TenantId(ctx)
 	for _, record := range records {
+		creds, err := hashCreds(m.hasher, record.Creds)
+		if err != nil {
+			return err
+		}
+		if err = record.index(); err != nil {
+			return err
+		}
 		builder.Values(
-			record.UserID, record.Email, record.Phone, record.Creds, record.Disabled,
+			tenantId, record.UserID, record.Email, record.Phone, record.EmailIndex,
+			record.PhoneIndex, creds, record.Disabled, record.ExpiredAt,
 		)
 	}
 	q, args := builder.BuildWithFlavor(m.driver.ToSqlBuilderFlavor())
@@ -168,41 +311,222 @@ func (m *userManager) Save(ctx context.Context, record UserAuthRecord) (UserAuth
 	if !ok {
 		return record, errors.New("not supported yet")
 	}
+	if err := record.index(); err != nil {
+		return record, err
+	}
+	record.TenantID = requests.TenantId(ctx)
+	var err error
+	// empty creds means keep the existing one
+	if len(record.Creds) > 0 {
+		if record.Creds, err = hashCreds(m.hasher, record.Creds); err != nil {
+			return record, err
+		}
+	}
 	rows, err := m.dbw.NamedQuery(ctx, q, record)
+	defer func() {
+		rules.WhenTrue(rows != nil, func() {
+			_ = rows.Close()
+		})
+	}()
 	if err != nil {
 		return record, err
 	}
-	err = rows.Scan(&record.ID, &record.Disabled)
-	if err != nil {
+	if !rows.Next() {
+		return record, db.ErrorRecordNotFound
+	}
+	if err = rows.Scan(&record.ID, &record.UserID, &record.Disabled); err != nil {
 		return record, err
 	}
-	return record, nil
+	return record, m.invalidateUsers(ctx, int64(record.UserID))
 }
 
 func (m *userManager) RemoveByIds(ctx context.Context, ids ...int) error {
+	if eval.IsEmpty(ids) {
+		return db.ErrorEmptyArguments
+	}
 	builder := sqlbuilder.NewDeleteBuilder().
 		DeleteFrom(tableUserAuth)
-	builder.Where(builder.In("id", ids))
+	builder.Where(builder.In("id", sqlbuilder.Flatten(ids)...), builder.Equal("tenant_id", requests.TenantId(ctx)))
 	q, args := builder.BuildWithFlavor(m.driver.ToSqlBuilderFlavor())
-	_, err := m.dbw.Exec(ctx, q, args...)
-	return err
+	rows, err := m.dbw.Query(ctx, q+" RETURNING user_id", args...)
+	defer func() {
+		rules.WhenTrue(rows != nil, func() {
+			_ = rows.Close()
+		})
+	}()
+	if err != nil {
+		return err
+	}
+	userIds := make([]int64, 0)
+	for rows.Next() {
+		var userId int64
+		if err = rows.Scan(&userId); err != nil {
+			return err
+		}
+		userIds = append(userIds, userId)
+	}
+	return m.invalidateUsers(ctx, userIds...)
 }
 
 func (m *userManager) RemoveByUserIds(ctx context.Context, userIds ...int) error {
+	if eval.IsEmpty(userIds) {
+		return db.ErrorEmptyArguments
+	}
 	builder := sqlbuilder.NewDeleteBuilder().
 		DeleteFrom(tableUserAuth)
-	builder.Where(builder.In("user_id", userIds))
+	builder.Where(builder.In("user_id", sqlbuilder.Flatten(userIds)...), builder.Equal("tenant_id", requests.TenantId(ctx)))
 	q, args := builder.BuildWithFlavor(m.driver.ToSqlBuilderFlavor())
-	_, err := m.dbw.Exec(ctx, q, args...)
-	return err
+	if _, err := m.dbw.Exec(ctx, q, args...); err != nil {
+		return err
+	}
+	ids := make([]int64, len(userIds))
+	for i, v := range userIds {
+		ids[i] = int64(v)
+	}
+	return m.invalidateUsers(ctx, ids...)
 }
 
 func (m *userManager) GetByUserIds(ctx context.Context, userIds ...int) (UserAuthRecords, error) {
+	if eval.IsEmpty(userIds) {
+		return make(UserAuthRecords, 0), db.ErrorEmptyArguments
+	}
+	builder := newUserAuthSelectBuilder()
+	builder.Where(builder.In("user_id", sqlbuilder.Flatten(userIds)...), builder.Equal("tenant_id", requests.TenantId(ctx)))
+	return m.getBy(ctx, builder)
+}
+
+func (m *userManager) GetByEmail(ctx context.Context, email string) (UserAuthRecords, error) {
+	index, err := crypt.BlindIndex(email)
+	if err != nil {
+		return make(UserAuthRecords, 0), err
+	}
+	if len(index) < 1 {
+		return make(UserAuthRecords, 0), db.ErrorEmptyArguments
+	}
+	builder := newUserAuthSelectBuilder()
+	builder.Where(builder.Equal("email_idx", index), builder.Equal("tenant_id", requests.TenantId(ctx)))
+	return m.getBy(ctx, builder)
+}
+
+func (m *userManager) GetByPhone(ctx context.Context, phone string) (UserAuthRecords, error) {
+	index, err := crypt.BlindIndex(phone)
+	if err != nil {
+		return make(UserAuthRecords, 0), err
+	}
+	if len(index) < 1 {
+		return make(UserAuthRecords, 0), db.ErrorEmptyArguments
+	}
+	builder := newUserAuthSelectBuilder()
+	builder.Where(builder.Equal("phone_idx", index), builder.Equal("tenant_id", requests.TenantId(ctx)))
+	return m.getBy(ctx, builder)
+}
+
+func (m *userManager) EncryptFields(ctx context.Context) (int, error) {
+	encrypted, err := m.encryptAuthFields(ctx)
+	if err != nil {
+		return encrypted, err
+	}
+	n, err := m.encryptMfaSecrets(ctx)
+	return encrypted + n, err
+}
+
+func (m *userManager) encryptAuthFields(ctx context.Context) (int, error) {
+	q, ok := userAuthFieldsQuery[m.driver]
+	if !ok {
+		return 0, errors.New("not supported yet")
+	}
+	encrypted, lastId := 0, 0
+	for {
+		records, err := m.listFields(ctx, q.list, lastId)
+		if err != nil || len(records) < 1 {
+			return encrypted, err
+		}
+		for _, raw := range records {
+			lastId = raw.ID
+			record, plain, err := plainFields(raw)
+			if err != nil {
+				return encrypted, err
+			}
+			if !plain {
+				continue
+			}
+			rs, err := m.dbw.Exec(
+				ctx, m.dbw.Rebind(q.update), record.Email, record.Phone, record.EmailIndex, record.PhoneIndex,
+				raw.ID, raw.Email.String(), raw.Phone.String(),
+			)
+			if err != nil {
+				return encrypted, err
+			}
+			if n, _ := rs.RowsAffected(); n > 0 {
+				encrypted++
+			}
+		}
+	}
+}
+
+// listFields read email and phone as stored, so plain text value could be read while field encryption enabled
+func (m *userManager) listFields(ctx context.Context, q string, afterId int) (UserAuthRecords, error) {
+	rs := make(UserAuthRecords, 0)
+	rows, err := m.dbw.Query(ctx, m.dbw.Rebind(q), afterId, userAuthFieldsBatchSize)
+	defer func() {
+		rules.WhenTrue(rows != nil, func() {
+			_ = rows.Close()
+		})
+	}()
+	if err != nil {
+		return rs, err
+	}
+	for rows.Next() {
+		var (
+			record       UserAuthRecord
+			email, phone string
+		)
+		if err = rows.Scan(&record.ID, &email, &phone); err != nil {
+			return rs, err
+		}
+		record.Email, record.Phone = crypt.EncryptedString(email), crypt.EncryptedString(phone)
+		rs = append(rs, &record)
+	}
+	return rs, rows.Err()
+}
+
+// plainFields decrypt the encrypted one of stored email and phone, and tell whether any of them still plain text
+func plainFields(raw *UserAuthRecord) (UserAuthRecord, bool, error) {
+	record := UserAuthRecord{ID: raw.ID}
+	plain := false
+	for _, field := range []struct {
+		stored crypt.EncryptedString
+		to     *crypt.EncryptedString
+	}{
+		{raw.Email, &record.Email},
+		{raw.Phone, &record.Phone},
+	} {
+		ok, err := crypt.IsEncryptedField(field.stored.String())
+		if err != nil {
+			return record, false, err
+		}
+		if !ok {
+			plain, *field.to = true, field.stored
+			continue
+		}
+		if err = field.to.Scan(field.stored.String()); err != nil {
+			return record, false, err
+		}
+	}
+	return record, plain, record.index()
+}
+
+func newUserAuthSelectBuilder() *sqlbuilder.SelectBuilder {
+	return sqlbuilder.NewSelectBuilder().
+		Select("id", "tenant_id", "user_id", "email", "phone", "email_idx", "phone_idx", "creds", "disabled",
+			"created_at", "updated_at", "disabled_at", "expired_at").
+		From(tableUserAuth)
+}
+
+func (m *userManager) getBy(ctx context.Context, builder *sqlbuilder.SelectBuilder) (UserAuthRecords, error) {
 	rs := make(UserAuthRecords, 0)
-	builder := sqlbuilder.NewSelectBuilder().From(tableUserAuth)
-	builder.Where(builder.In("user_id", userIds))
 	q, args := builder.BuildWithFlavor(m.driver.ToSqlBuilderFlavor())
-	rows, err := m.dbw.Query(ctx, q, args...)
+	rows, err := m.dbr.Query(ctx, q, args...)
 	defer func() {
 		rules.WhenTrue(rows != nil, func() {
 			_ = rows.Close()
@@ -229,7 +553,7 @@ func (m *userManager) Init() error {
 	if err := m.initSchema(ctx); err != nil {
 		return err
 	}
-	return nil
+	return m.initMfaSchema(ctx, m.dbw.Driver())
 }
 
 func (m *userManager) MustInit() UserManager {
@@ -245,7 +569,7 @@ func (m *userManager) tableCheck(ctx context.Context, driver db.SupportedDriver)
 		return total, errors.New("driver not supported by table existence check")
 	}
 	tableChecks := utils.GetValueOnMap(userAuthTableExistenceCheckQuery, driver, []string{})
-	if len(tableChecks) < 2 {
+	if len(tableChecks) < 1 {
 		return total, errors.New("no table existence check query exists")
 	}
 	for _, checkQuery := range tableChecks {
@@ -268,8 +592,11 @@ func (m *userManager) initSchema(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	if total == 1 {
-		return nil
+	if total == 2 {
+		if err = db.ExecDefinitions(ctx, m.dbw, userTenancyDefinition); err != nil {
+			return err
+		}
+		return db.ExecDefinitions(ctx, m.dbw, userAuthFieldEncryptionDefinition)
 	}
 	// create custom type definitions
 	if !utils.KeyExistsInMap(userCustomDefinitions, driver) {
@@ -331,6 +658,7 @@ func (m *userManager) initSchema(ctx context.Context) error {
 	_, err = tx.Exec(q)
 	if err != nil {
 		_ = tx.Rollback()
+		return err
 	}
 	// execute index creation on user access table if not exists
 	for _, definition := range accessIndexDefinition {
@@ -344,49 +672,213 @@ func (m *userManager) initSchema(ctx context.Context) error {
 }
 
 func (m *userManager) AddAccess(ctx context.Context, records ...UserAccessRecord) error {
-	// build select values
-	svp := make([]string, len(records))
-	svArgs := make([]interface{}, 0)
+	if eval.IsEmpty(records) {
+		return db.ErrorEmptyArguments
+	}
+	placeholders := userAccessAddQuery[m.driver].placeholder(len(records))
+	tenantId := requests.TenantId(ctx)
+	args := make([]interface{}, 0)
 	for _, record := range records {
-		svp = append(svp, fmt.Sprintf(
-			`(%s, CASE WHEN %s THEN current_timestamp ELSE NULL END)`,
-			strings.Join(utils.RepeatInSlice("?", 5), ","),
-			strconv.FormatBool(record.Disabled),
-		))
-		svArgs = append(svArgs, record.ID, record.UserID, record.Resource, record.Scopes, record.Disabled)
-	}
-	q := `INSERT INTO ` + tableUserAccess + `(id, user_id, resource, scopes, disabled)
-		VALUES ` + strings.Join(svp, ",") + `
-		ON CONFLICT(user_id, resource) DO
-			UPDATE SET
-				scopes = 
-				disabled = (CASE WHEN disabled <> excluded.disabled THEN excluded.disabled ELSE disabled END),
-				updated_at = current_timestamp,
-				disabled_at = (CASE WHEN excluded.disabled THEN current_timestamp ELSE disabled_at END)
-		RETURNING id, user_id, resource
-	`
-	_, err := m.dbw.Exec(ctx, m.dbw.Rebind(q), svArgs...)
+		args = append(args, tenantId, record.UserID, record.Resource, pq.Array(record.Scopes), record.Disabled, record.Disabled)
+	}
+	q := userAccessAddQuery[m.driver].query(strings.Join(placeholders, ","))
+	_, err := m.dbw.Exec(ctx, m.dbw.Rebind(q), args...)
+	return err
+}
+
+func (m *userManager) DisabledAccessByIds(ctx context.Context, ids ...int) error {
+	if eval.IsEmpty(ids) {
+		return db.ErrorEmptyArguments
+	}
+	q := userAccessDisableByIdsQuery[m.driver].query(len(ids))
+	_, err := m.dbw.Exec(ctx, m.dbw.Rebind(q), idsWithTenant(ctx, ids)...)
 	return err
 }
 
 func (m *userManager) IsAllowed(ctx context.Context, id int64, resource string, scope Scope) (bool, error) {
-	//TODO implement me
-	panic("implement me")
+	disabled, err := m.isDisabled(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if disabled {
+		return false, ErrUserDisabled
+	}
+	// access granted directly to user take precedence over the one granted through roles
+	var scopes Scopes
+	err = m.dbr.QueryRow(ctx, m.dbr.Rebind(userAccessScopesQuery[m.driver]), id, resource, requests.TenantId(ctx)).Scan(&scopes)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+	if scopes.AllowedTo(scope) {
+		return true, nil
+	}
+	if m.role == nil {
+		return false, nil
+	}
+	return m.role.IsAllowed(ctx, id, resource, scope)
 }
 
 func (m *userManager) Introspect(ctx context.Context, token string, bindTo interface{}) error {
-	//TODO implement me
-	panic("implement me")
+	if len(token) < 1 {
+		return ErrInvalidToken
+	}
+	var (
+		claim jwe.Claim
+		err   error
+	)
+	if cached := m.im.GetString(ctx, tokenKey(token)); len(cached) > 0 {
+		if err = json.Unmarshal([]byte(cached), &claim); err != nil {
+			return err
+		}
+	} else {
+		if m.jwe == nil {
+			return ErrTokenManagerNotDefined
+		}
+		if claim, err = m.jwe.Decode(token); err != nil {
+			return tokenError(err)
+		}
+	}
+	if claim.TokenType != TokenTypeAccess {
+		return ErrInvalidToken
+	}
+	if claim.Expired(ctime.Now()) {
+		_ = m.im.Del(ctx, tokenKey(token))
+		return ErrTokenExpired
+	}
+	if err = m.validateClaim(ctx, claim); err != nil {
+		return err
+	}
+	if bindTo == nil {
+		return nil
+	}
+	b, err := json.Marshal(claim)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, bindTo)
 }
 
 func (m *userManager) Authenticate(ctx context.Context, id int64, creds string) (token string, err error) {
-	//TODO implement me
-	panic("implement me")
+	pair, err := m.AuthenticateWithRefreshToken(ctx, id, creds)
+	if err != nil {
+		return "", err
+	}
+	return pair.AccessToken, nil
 }
 
-func (m *userManager) DisabledAccessByIds(ctx context.Context, ids ...int) error {
-	//TODO implement me
-	panic("implement me")
+func (m *userManager) AuthenticateWithRefreshToken(ctx context.Context, id int64, creds string) (TokenPair, error) {
+	record, err := m.verifyCredential(ctx, id, creds)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	mfa, err := m.mfaEnabled(ctx, id)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if mfa {
+		return TokenPair{}, ErrMFARequired
+	}
+	m.resetAttempts(ctx, id)
+	return m.startSession(ctx, record, 0)
+}
+
+// verifyCredential of user which not locked, disabled nor expired, every rejection counted toward lockout.
+// user only authenticated within its own tenant, tenant of the request is not trusted to be the user's
+// and mismatch told as invalid credential so existence of user in other tenant not revealed
+func (m *userManager) verifyCredential(ctx context.Context, id int64, creds string) (*UserAuthRecord, error) {
+	if m.jwe == nil {
+		return nil, ErrTokenManagerNotDefined
+	}
+	if err := m.ensureNotLocked(ctx, id); err != nil {
+		return nil, err
+	}
+	record, err := m.getCredential(ctx, id)
+	if errors.Is(err, ErrInvalidCredential) {
+		m.registerFailure(ctx, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// password verified first, state of the account only told to the one knowing the password
+	if err = verifyCreds(m.hasher, record.Creds, creds); err != nil || record.TenantID != requests.TenantId(ctx) {
+		m.registerFailure(ctx, id)
+		return nil, ErrInvalidCredential
+	}
+	if record.Disabled {
+		m.registerFailure(ctx, id)
+		return nil, ErrUserDisabled
+	}
+	if record.ExpiredAt != nil && ctime.Now().After(*record.ExpiredAt) {
+		m.registerFailure(ctx, id)
+		return nil, ErrCredentialExpired
+	}
+	if m.hasher.NeedsRehash(record.Creds) {
+		m.rehashCreds(ctx, id, record.Creds, creds)
+	}
+	return record, nil
+}
+
+// rehashCreds of user hashed by older algorithm or parameters, failure only logged as the old hash still valid
+func (m *userManager) rehashCreds(ctx context.Context, id int64, previous, creds string) {
+	hashed, err := hashCreds(m.hasher, creds)
+	if err == nil {
+		_, err = m.dbw.Exec(ctx, m.dbw.Rebind(userAuthRehashQuery[m.driver]), hashed, id, previous)
+	}
+	if err == nil {
+		err = m.invalidateUsers(ctx, id)
+	}
+	if err != nil {
+		m.log.WarnWithProps(map[string]interface{}{
+			"context": "user.rehash_creds",
+			"user_id": id,
+		}, err.Error())
+	}
+}
+
+// getCredential of user from database regardless tenant of the request, credential and personal data
+// never kept in memory, only the disabled state cached for validating token
+func (m *userManager) getCredential(ctx context.Context, id int64) (*UserAuthRecord, error) {
+	builder := newUserAuthSelectBuilder()
+	builder.Where(builder.Equal("user_id", id))
+	records, err := m.getBy(ctx, builder)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 1 {
+		return nil, ErrInvalidCredential
+	}
+	record := records[0]
+	if err = m.im.HSet(ctx, inMemoryUserDisabledHashKey, strconv.FormatInt(id, 10), strconv.FormatBool(record.Disabled)); err != nil {
+		m.log.WarnWithProps(map[string]interface{}{
+			"context": "user.get_credential",
+			"user_id": id,
+		}, err.Error())
+	}
+	return record, nil
+}
+
+func (m *userManager) isDisabled(ctx context.Context, id int64) (bool, error) {
+	var cached string
+	if err := m.im.HGet(ctx, inMemoryUserDisabledHashKey, strconv.FormatInt(id, 10), &cached); err == nil && len(cached) > 0 {
+		return strconv.ParseBool(cached)
+	}
+	record, err := m.getCredential(ctx, id)
+	if err != nil {
+		return true, err
+	}
+	return record.Disabled, nil
+}
+
+// invalidateUsers remove cached disabled state of users
+func (m *userManager) invalidateUsers(ctx context.Context, userIds ...int64) error {
+	if len(userIds) < 1 {
+		return nil
+	}
+	fields := make([]string, len(userIds))
+	for i, userId := range userIds {
+		fields[i] = strconv.FormatInt(userId, 10)
+	}
+	return m.im.HDel(ctx, inMemoryUserDisabledHashKey, fields...)
 }
 
 func NewUserAuthManager(dbm db.Manager, opts ...common.Option[userManager]) UserManager {
@@ -396,8 +888,14 @@ func NewUserAuthManager(dbm db.Manager, opts ...common.Option[userManager]) User
 		}, func() db.SupportedDriver {
 			return dbm.Driver()
 		}),
-		audit: audit.NewNoop(),
-		im:    inmemory.NewNoop(),
+		audit:                  audit.NewNoop(),
+		im:                     inmemory.NewNoop(),
+		log:                    logger.NewNoop(),
+		tokenExpiration:        jwe.DefaultExpiration,
+		refreshTokenExpiration: DefaultRefreshTokenExpiration,
+		lockout:                DefaultLockoutPolicy,
+		mfaIssuer:              jwe.ISSUER,
+		hasher:                 crypt.NewHasher(),
 	}
 	for _, opt := range opts {
 		opt.Apply(m)
@@ -11,14 +11,24 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/alicebob/miniredis/v2"
 	"github.com/evorts/kevlars/common"
+	"github.com/evorts/kevlars/crypt"
 	"github.com/evorts/kevlars/db"
+	"github.com/evorts/kevlars/inmemory"
+	"github.com/evorts/kevlars/jwe"
+	"github.com/evorts/kevlars/requests"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
 	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -34,7 +44,10 @@ type userAuthTestSuite struct {
 	pass      string
 	dsn       string
 	container *postgres.PostgresContainer
-	um        userManager
+	redis     *miniredis.Miniredis
+	im        inmemory.Manager
+	jwe       jwe.Manager
+	um        UserManager
 }
 
 func (ts *userAuthTestSuite) SetupTest() {
@@ -61,6 +74,25 @@ func (ts *userAuthTestSuite) SetupTest() {
 	ts.dsn, err = ts.container.ConnectionString(ts.ctx, "sslmode=disable")
 	ts.Require().NoError(err)
 	ts.db = db.New(db.DriverPostgreSQL, ts.dsn).MustConnect(ts.ctx)
+	ts.redis, err = miniredis.Run()
+	ts.Require().NoError(err)
+	ts.im = inmemory.NewRedis(ts.redis.Addr()).MustConnect(ts.ctx)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	ts.Require().NoError(err)
+	ts.jwe = jwe.NewJWE(key).MustInit()
+	crypt.RegisterFieldManager(crypt.New(crypt.WithCipher(crypt.CipherEnvelope), crypt.WithKeyVersion(1, common.Bytes(strings.Repeat("k", 32)))).MustInit())
+	crypt.RegisterBlindIndexer(crypt.NewBlindIndexer(common.Bytes("index key")))
+	ts.um = NewUserAuthManager(
+		ts.db,
+		UserAuthWithInMemoryManager(ts.im),
+		UserAuthWithJWE(ts.jwe),
+		UserAuthWithSessionTracking(true),
+	).MustInit()
+	err = ts.um.Add(ts.ctx,
+		UserAuthRecord{UserID: 1, Email: "active@evorts.com", Creds: "s3cr3t"},
+		UserAuthRecord{UserID: 2, Email: "disabled@evorts.com", Creds: "s3cr3t", Disabled: true},
+	)
+	ts.Require().NoError(err)
 }
 
 func (ts *userAuthTestSuite) TestInstantiation() {
@@ -102,24 +134,353 @@ func (ts *userAuthTestSuite) TestInstantiation() {
 	}
 }
 
-/*
-	func (ts *userAuthTestSuite) TestAddUser() {
-		tests := map[string]struct {
-			wantErr error
-		}{
-			"test init schema": {
-				wantErr: nil,
-			},
-		}
-		for name, tc := range tests {
-			ts.Run(name, func() {
-				err := ts.um.Add(ts.ctx, UserAuthRecord{})
-				assert.Equal(ts.T(), tc.wantErr, err)
-			})
+func (ts *userAuthTestSuite) TestAuthenticate() {
+	tests := map[string]struct {
+		id      int64
+		creds   string
+		wantErr error
+	}{
+		"valid credential, should return token": {
+			id:    1,
+			creds: "s3cr3t",
+		},
+		"invalid credential, should fail": {
+			id:      1,
+			creds:   "wrong",
+			wantErr: ErrInvalidCredential,
+		},
+		"unknown user, should fail": {
+			id:      99,
+			creds:   "s3cr3t",
+			wantErr: ErrInvalidCredential,
+		},
+		"disabled user, should fail": {
+			id:      2,
+			creds:   "s3cr3t",
+			wantErr: ErrUserDisabled,
+		},
+		"disabled user with invalid credential, should not tell the state": {
+			id:      2,
+			creds:   "wrong",
+			wantErr: ErrInvalidCredential,
+		},
+	}
+	for name, tc := range tests {
+		ts.Run(name, func() {
+			token, err := ts.um.Authenticate(ts.ctx, tc.id, tc.creds)
+			assert.Equal(ts.T(), tc.wantErr, err)
+			if tc.wantErr == nil {
+				assert.NotEmpty(ts.T(), token)
+			}
+		})
+	}
+	// only disabled state kept in memory, never the credential or personal data
+	assert.False(ts.T(), ts.redis.Exists("user_creds"))
+	for _, key := range ts.redis.Keys() {
+		fields, _ := ts.redis.HKeys(key)
+		for _, field := range fields {
+			assert.NotContains(ts.T(), ts.redis.HGet(key, field), "@evorts.com", key)
 		}
 	}
-*/
+}
+
+func (ts *userAuthTestSuite) TestIntrospect() {
+	token, err := ts.um.Authenticate(ts.ctx, 1, "s3cr3t")
+	ts.Require().NoError(err)
+	var claim jwe.Claim
+	assert.NoError(ts.T(), ts.um.Introspect(ts.ctx, token, &claim))
+	assert.Equal(ts.T(), int64(1), claim.ID)
+	// cached claim expire along with the token
+	assert.True(ts.T(), ts.redis.Exists(tokenKey(token)))
+	assert.Positive(ts.T(), ts.redis.TTL(tokenKey(token)))
+	// token still valid when cache are gone, since it could be decoded
+	ts.redis.Del(tokenKey(token))
+	assert.NoError(ts.T(), ts.um.Introspect(ts.ctx, token, &claim))
+	assert.Equal(ts.T(), ErrInvalidToken, ts.um.Introspect(ts.ctx, "invalid-token", &claim))
+	// disabled user should not pass introspection
+	_, err = ts.um.Save(ts.ctx, UserAuthRecord{UserID: 1, Disabled: true})
+	ts.Require().NoError(err)
+	assert.Equal(ts.T(), ErrUserDisabled, ts.um.Introspect(ts.ctx, token, &claim))
+}
+
+func (ts *userAuthTestSuite) TestLockout() {
+	um := NewUserAuthManager(
+		ts.db,
+		UserAuthWithInMemoryManager(ts.im),
+		UserAuthWithJWE(ts.jwe),
+		UserAuthWithLockoutPolicy(LockoutPolicy{
+			MaxAttempts:      3,
+			MaxAttemptsPerIp: 5,
+			Window:           time.Minute,
+			LockoutDuration:  time.Minute,
+		}),
+	)
+	ctx := requests.WithRemoteIp(ts.ctx, "10.0.0.1")
+	for i := 0; i < 3; i++ {
+		_, err := um.Authenticate(ctx, 1, "wrong")
+		assert.Equal(ts.T(), ErrInvalidCredential, err)
+	}
+	// locked even when the right credential given
+	_, err := um.Authenticate(ctx, 1, "s3cr3t")
+	assert.Equal(ts.T(), ErrAccountLocked, err)
+	ts.Require().NoError(um.Unlock(ts.ctx, 1))
+	_, err = um.Authenticate(ctx, 1, "s3cr3t")
+	assert.NoError(ts.T(), err)
+	// guessing across users from the same ip lock the ip
+	for i := int64(10); i < 12; i++ {
+		_, err = um.Authenticate(ctx, i, "wrong")
+		assert.Equal(ts.T(), ErrInvalidCredential, err)
+	}
+	_, err = um.Authenticate(ctx, 1, "s3cr3t")
+	assert.Equal(ts.T(), ErrAccountLocked, err)
+	ts.Require().NoError(um.UnlockIp(ts.ctx, "10.0.0.1"))
+	_, err = um.Authenticate(ctx, 1, "s3cr3t")
+	assert.NoError(ts.T(), err)
+}
+
+func (ts *userAuthTestSuite) TestMFA() {
+	enrolment, err := ts.um.EnrollTOTP(ts.ctx, 1, "active@evorts.com")
+	ts.Require().NoError(err)
+	assert.Len(ts.T(), enrolment.RecoveryCodes, recoveryCodeCount)
+	assert.Contains(ts.T(), enrolment.ProvisioningURI, "secret="+enrolment.Secret)
+	key, err := totpEncoding.DecodeString(enrolment.Secret)
+	ts.Require().NoError(err)
+	// seed stored encrypted
+	var stored string
+	ts.Require().NoError(ts.db.QueryRow(ts.ctx, ts.db.Rebind(`SELECT secret FROM `+tableUserMfa+` WHERE user_id = ?`), 1).Scan(&stored))
+	assert.NotContains(ts.T(), stored, enrolment.Secret)
+	step := totpStep(time.Now())
+	// pending enrolment not required on authentication yet
+	_, err = ts.um.Authenticate(ts.ctx, 1, "s3cr3t")
+	assert.NoError(ts.T(), err)
+	ts.Require().NoError(ts.um.ConfirmTOTP(ts.ctx, 1, hotp(key, uint64(step))))
+	_, err = ts.um.EnrollTOTP(ts.ctx, 1, "active@evorts.com")
+	assert.Equal(ts.T(), ErrMFAAlreadyEnrolled, err)
+	_, err = ts.um.Authenticate(ts.ctx, 1, "s3cr3t")
+	assert.Equal(ts.T(), ErrMFARequired, err)
+	// code of step already accepted could not be replayed
+	_, err = ts.um.AuthenticateWithMFA(ts.ctx, 1, "s3cr3t", hotp(key, uint64(step)))
+	assert.Equal(ts.T(), ErrInvalidMFACode, err)
+	pair, err := ts.um.AuthenticateWithMFA(ts.ctx, 1, "s3cr3t", hotp(key, uint64(step+1)))
+	ts.Require().NoError(err)
+	var claim jwe.Claim
+	ts.Require().NoError(ts.um.Introspect(ts.ctx, pair.AccessToken, &claim))
+	assert.True(ts.T(), claim.SteppedUp(time.Now(), time.Minute))
+	assert.Equal(ts.T(), []string{jwe.AMRPassword, jwe.AMROneTimePassword}, claim.AMR)
+	// step up through recovery code, each code only usable once
+	ts.Require().NoError(ts.um.DisableTOTP(ts.ctx, 1))
+	enrolment, err = ts.um.EnrollTOTP(ts.ctx, 1, "active@evorts.com")
+	ts.Require().NoError(err)
+	key, err = totpEncoding.DecodeString(enrolment.Secret)
+	ts.Require().NoError(err)
+	ts.Require().NoError(ts.um.ConfirmTOTP(ts.ctx, 1, hotp(key, uint64(step))))
+	pair, err = ts.um.AuthenticateWithMFA(ts.ctx, 1, "s3cr3t", enrolment.RecoveryCodes[0])
+	ts.Require().NoError(err)
+	pair, err = ts.um.StepUp(ts.ctx, pair.AccessToken, strings.ToUpper(enrolment.RecoveryCodes[1]))
+	ts.Require().NoError(err)
+	_, err = ts.um.StepUp(ts.ctx, pair.AccessToken, enrolment.RecoveryCodes[1])
+	assert.Equal(ts.T(), ErrInvalidMFACode, err)
+}
+
+func (ts *userAuthTestSuite) TestRefresh() {
+	pair, err := ts.um.AuthenticateWithRefreshToken(ts.ctx, 1, "s3cr3t")
+	ts.Require().NoError(err)
+	assert.NotEmpty(ts.T(), pair.RefreshToken)
+	// refresh token could not be used as access token
+	var claim jwe.Claim
+	assert.Equal(ts.T(), ErrInvalidToken, ts.um.Introspect(ts.ctx, pair.RefreshToken, &claim))
+	rotated, err := ts.um.Refresh(ts.ctx, pair.RefreshToken)
+	ts.Require().NoError(err)
+	assert.Equal(ts.T(), pair.SessionID, rotated.SessionID)
+	assert.NoError(ts.T(), ts.um.Introspect(ts.ctx, rotated.AccessToken, &claim))
+	// presenting the rotated refresh token again end the whole session
+	_, err = ts.um.Refresh(ts.ctx, pair.RefreshToken)
+	assert.Equal(ts.T(), ErrRefreshTokenReused, err)
+	assert.Equal(ts.T(), ErrSessionNotFound, ts.um.Introspect(ts.ctx, rotated.AccessToken, &claim))
+	_, err = ts.um.Refresh(ts.ctx, rotated.RefreshToken)
+	assert.Equal(ts.T(), ErrSessionNotFound, err)
+}
+
+func (ts *userAuthTestSuite) TestRefreshConcurrently() {
+	pair, err := ts.um.AuthenticateWithRefreshToken(ts.ctx, 1, "s3cr3t")
+	ts.Require().NoError(err)
+	var (
+		wg        sync.WaitGroup
+		succeeded atomic.Int32
+	)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, errR := ts.um.Refresh(ts.ctx, pair.RefreshToken); errR == nil {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	// only one rotation win, the losers taken as reuse
+	assert.LessOrEqual(ts.T(), succeeded.Load(), int32(1))
+}
+
+func (ts *userAuthTestSuite) TestRevoke() {
+	pair, err := ts.um.AuthenticateWithRefreshToken(ts.ctx, 1, "s3cr3t")
+	ts.Require().NoError(err)
+	var claim jwe.Claim
+	ts.Require().NoError(ts.um.Revoke(ts.ctx, pair.AccessToken))
+	assert.Equal(ts.T(), ErrTokenRevoked, ts.um.Introspect(ts.ctx, pair.AccessToken, &claim))
+	// session still alive, so refresh should pass
+	rotated, err := ts.um.Refresh(ts.ctx, pair.RefreshToken)
+	ts.Require().NoError(err)
+	ts.Require().NoError(ts.um.Revoke(ts.ctx, rotated.RefreshToken))
+	_, err = ts.um.Refresh(ts.ctx, rotated.RefreshToken)
+	assert.Equal(ts.T(), ErrTokenRevoked, err)
+	sessions, err := ts.um.Sessions(ts.ctx, 1)
+	assert.NoError(ts.T(), err)
+	assert.Empty(ts.T(), sessions)
+}
+
+func (ts *userAuthTestSuite) TestRevokeAllSessions() {
+	first, err := ts.um.AuthenticateWithRefreshToken(ts.ctx, 1, "s3cr3t")
+	ts.Require().NoError(err)
+	second, err := ts.um.AuthenticateWithRefreshToken(ts.ctx, 1, "s3cr3t")
+	ts.Require().NoError(err)
+	sessions, err := ts.um.Sessions(ts.ctx, 1)
+	ts.Require().NoError(err)
+	assert.Len(ts.T(), sessions, 2)
+	ts.Require().NoError(ts.um.RevokeSession(ts.ctx, 1, first.SessionID))
+	var claim jwe.Claim
+	assert.Equal(ts.T(), ErrSessionNotFound, ts.um.Introspect(ts.ctx, first.AccessToken, &claim))
+	assert.NoError(ts.T(), ts.um.Introspect(ts.ctx, second.AccessToken, &claim))
+	ts.Require().NoError(ts.um.RevokeAllSessions(ts.ctx, 1))
+	assert.Equal(ts.T(), ErrSessionNotFound, ts.um.Introspect(ts.ctx, second.AccessToken, &claim))
+}
+
+func (ts *userAuthTestSuite) TestIsAllowed() {
+	err := ts.um.AddAccess(ts.ctx,
+		UserAccessRecord{UserID: 1, Resource: "/res/a", Scopes: Scopes{ScopeRead}},
+		UserAccessRecord{UserID: 1, Resource: "/res/b", Scopes: Scopes{ScopeRead, ScopeWrite}},
+	)
+	ts.Require().NoError(err)
+	allowed, err := ts.um.IsAllowed(ts.ctx, 1, "/res/a", ScopeRead)
+	assert.NoError(ts.T(), err)
+	assert.True(ts.T(), allowed)
+	allowed, err = ts.um.IsAllowed(ts.ctx, 1, "/res/a", ScopeWrite)
+	assert.NoError(ts.T(), err)
+	assert.False(ts.T(), allowed)
+	// disable access to /res/b
+	var id int
+	err = ts.db.QueryRow(ts.ctx, ts.db.Rebind(`SELECT id FROM `+tableUserAccess+` WHERE user_id = ? AND resource = ?`), 1, "/res/b").Scan(&id)
+	ts.Require().NoError(err)
+	ts.Require().NoError(ts.um.DisabledAccessByIds(ts.ctx, id))
+	allowed, err = ts.um.IsAllowed(ts.ctx, 1, "/res/b", ScopeWrite)
+	assert.NoError(ts.T(), err)
+	assert.False(ts.T(), allowed)
+	// disabled user never allowed
+	_, err = ts.um.IsAllowed(ts.ctx, 2, "/res/a", ScopeRead)
+	assert.Equal(ts.T(), ErrUserDisabled, err)
+}
+
+func (ts *userAuthTestSuite) TestTenantIsolation() {
+	acme := requests.WithTenantId(ts.ctx, "acme")
+	ts.Require().NoError(ts.um.AddAccess(acme, UserAccessRecord{UserID: 1, Resource: "/res/t", Scopes: Scopes{ScopeRead}}))
+	allowed, err := ts.um.IsAllowed(acme, 1, "/res/t", ScopeRead)
+	assert.NoError(ts.T(), err)
+	assert.True(ts.T(), allowed)
+	// access granted within tenant not leaked into another tenant
+	allowed, err = ts.um.IsAllowed(requests.WithTenantId(ts.ctx, "globex"), 1, "/res/t", ScopeRead)
+	assert.NoError(ts.T(), err)
+	assert.False(ts.T(), allowed)
+	// user only authenticated within its own tenant, the tenant of request is not trusted
+	ts.Require().NoError(ts.um.Add(acme, UserAuthRecord{UserID: 5, Email: "acme@evorts.com", Creds: "s3cr3t"}))
+	_, err = ts.um.Authenticate(acme, 1, "s3cr3t")
+	assert.Equal(ts.T(), ErrInvalidCredential, err)
+	_, err = ts.um.Authenticate(requests.WithTenantId(ts.ctx, "globex"), 5, "s3cr3t")
+	assert.Equal(ts.T(), ErrInvalidCredential, err)
+	records, err := ts.um.GetByUserIds(ts.ctx, 5)
+	ts.Require().NoError(err)
+	assert.Empty(ts.T(), records)
+	_, err = ts.um.Save(ts.ctx, UserAuthRecord{UserID: 5, Disabled: true})
+	assert.Equal(ts.T(), db.ErrorRecordNotFound, err)
+	// token issued within tenant only accepted by the same tenant
+	token, err := ts.um.Authenticate(acme, 5, "s3cr3t")
+	ts.Require().NoError(err)
+	var claim jwe.Claim
+	assert.NoError(ts.T(), ts.um.Introspect(acme, token, &claim))
+	assert.Equal(ts.T(), "acme", claim.TenantID)
+	assert.Equal(ts.T(), ErrTenantMismatch, ts.um.Introspect(ts.ctx, token, &claim))
+	// session and second factor of user only reachable within its own tenant
+	globex := requests.WithTenantId(ts.ctx, "globex")
+	sessions, err := ts.um.Sessions(globex, 5)
+	ts.Require().NoError(err)
+	assert.Empty(ts.T(), sessions)
+	ts.Require().NoError(ts.um.RevokeAllSessions(globex, 5))
+	assert.NoError(ts.T(), ts.um.Introspect(acme, token, &claim))
+	sessions, err = ts.um.Sessions(acme, 5)
+	ts.Require().NoError(err)
+	assert.Len(ts.T(), sessions, 1)
+	_, err = ts.um.EnrollTOTP(globex, 5, "acme@evorts.com")
+	assert.Equal(ts.T(), db.ErrorRecordNotFound, err)
+	_, err = ts.um.EnrollTOTP(acme, 5, "acme@evorts.com")
+	ts.Require().NoError(err)
+	assert.Equal(ts.T(), ErrMFANotEnrolled, ts.um.DisableTOTP(globex, 5))
+	assert.NoError(ts.T(), ts.um.DisableTOTP(acme, 5))
+}
+
+func (ts *userAuthTestSuite) TestFieldEncryption() {
+	_, err := ts.um.Save(ts.ctx, UserAuthRecord{UserID: 3, Email: "secret@evorts.com", Phone: "+6281234", Creds: "s3cr3t"})
+	ts.Require().NoError(err)
+	var stored string
+	ts.Require().NoError(ts.db.QueryRow(ts.ctx, ts.db.Rebind(`SELECT email FROM `+tableUserAuth+` WHERE user_id = ?`), 3).Scan(&stored))
+	assert.NotContains(ts.T(), stored, "secret")
+	records, err := ts.um.GetByEmail(ts.ctx, " Secret@evorts.com")
+	ts.Require().NoError(err)
+	ts.Require().Len(records, 1)
+	assert.Equal(ts.T(), crypt.EncryptedString("secret@evorts.com"), records[0].Email)
+	records, err = ts.um.GetByPhone(ts.ctx, "+6281234")
+	ts.Require().NoError(err)
+	ts.Require().Len(records, 1)
+	assert.Equal(ts.T(), 3, records[0].UserID)
+}
+
+func (ts *userAuthTestSuite) TestEncryptFields() {
+	// written as plain text before field encryption enabled
+	ts.db.MustExec(ts.ctx, ts.db.Rebind(`INSERT INTO `+tableUserAuth+` (user_id, email, phone, email_idx, phone_idx, creds)
+		VALUES (?, ?, '', ?, '', '')`), 4, "plain@evorts.com", "plain@evorts.com")
+	// still readable before the backfill
+	records, err := ts.um.GetByUserIds(ts.ctx, 4)
+	ts.Require().NoError(err)
+	ts.Require().Len(records, 1)
+	assert.Equal(ts.T(), crypt.EncryptedString("plain@evorts.com"), records[0].Email)
+	encrypted, err := ts.um.EncryptFields(ts.ctx)
+	ts.Require().NoError(err)
+	assert.Equal(ts.T(), 1, encrypted)
+	var stored string
+	ts.Require().NoError(ts.db.QueryRow(ts.ctx, ts.db.Rebind(`SELECT email FROM `+tableUserAuth+` WHERE user_id = ?`), 4).Scan(&stored))
+	assert.NotContains(ts.T(), stored, "plain")
+	records, err = ts.um.GetByEmail(ts.ctx, "plain@evorts.com")
+	ts.Require().NoError(err)
+	ts.Require().Len(records, 1)
+	assert.Equal(ts.T(), crypt.EncryptedString("plain@evorts.com"), records[0].Email)
+	// totp secret written as plain text before field encryption enabled
+	ts.db.MustExec(ts.ctx, ts.db.Rebind(`INSERT INTO `+tableUserMfa+` (user_id, secret) VALUES (?, ?)`), 4, "PLAINTOTPSECRET")
+	encrypted, err = ts.um.EncryptFields(ts.ctx)
+	ts.Require().NoError(err)
+	assert.Equal(ts.T(), 1, encrypted)
+	ts.Require().NoError(ts.db.QueryRow(ts.ctx, ts.db.Rebind(`SELECT secret FROM `+tableUserMfa+` WHERE user_id = ?`), 4).Scan(&stored))
+	assert.NotContains(ts.T(), stored, "PLAINTOTPSECRET")
+	record, err := ts.um.(*userManager).getMfa(ts.ctx, 4)
+	ts.Require().NoError(err)
+	assert.Equal(ts.T(), crypt.EncryptedString("PLAINTOTPSECRET"), record.Secret)
+	// already encrypted rows left as is
+	encrypted, err = ts.um.EncryptFields(ts.ctx)
+	ts.Require().NoError(err)
+	assert.Equal(ts.T(), 0, encrypted)
+}
+
 func (ts *userAuthTestSuite) TearDownTest() {
+	crypt.RegisterFieldManager(nil)
+	crypt.RegisterBlindIndexer(nil)
+	ts.redis.Close()
 	if err := ts.container.Terminate(ts.ctx); err != nil {
 		log.Fatalf("failed to terminate container: %s", err)
 	}
@@ -0,0 +1,198 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: user_lockout
+ * @Date: 06/08/24 09.35
+ */
+
+package auth
+
+import (
+	"context"
+	"github.com/evorts/kevlars/audit"
+	"github.com/evorts/kevlars/ctime"
+	"github.com/evorts/kevlars/requests"
+	"strconv"
+	"time"
+)
+
+// LockoutPolicy of failed authentication attempts.
+// attempts counted per user id and per ip address, each with its own threshold
+type LockoutPolicy struct {
+	MaxAttempts      int           // failed attempts of user before locked, zero disable user lockout
+	MaxAttemptsPerIp int           // failed attempts from single ip before locked, zero disable ip lockout
+	Window           time.Duration // failed attempts forgotten after this period since the first failure
+	LockoutDuration  time.Duration // period of temporary lockout
+	DelayBase        time.Duration // delay after failed attempt, doubled on each consecutive failure
+	MaxDelay         time.Duration
+}
+
+var DefaultLockoutPolicy = LockoutPolicy{
+	MaxAttempts:      5,
+	MaxAttemptsPerIp: 20,
+	Window:           15 * time.Minute,
+	LockoutDuration:  15 * time.Minute,
+	DelayBase:        200 * time.Millisecond,
+	MaxDelay:         3 * time.Second,
+}
+
+const (
+	inMemoryUserAttemptsKey   = "user_attempts"    // user_attempts_{tenant_id}/{user_id} -> failed attempts counter
+	inMemoryIpAttemptsKey     = "user_attempts_ip" // user_attempts_ip_{ip} -> failed attempts counter
+	inMemoryUserLockedKey     = "user_locked"      // user_locked_{tenant_id}/{user_id} -> locked until, unix timestamp
+	inMemoryIpLockedKey       = "user_locked_ip"   // user_locked_ip_{ip} -> locked until, unix timestamp
+	auditActionUserLocked     = "auth.user.locked"
+	auditActionIpLocked       = "auth.ip.locked"
+	auditActionUserUnlocked   = "auth.user.unlocked"
+	auditActionIpUnlocked     = "auth.ip.unlocked"
+	auditCreatedBySystemName  = "system"
+	auditCreatedBySystemValue = "0"
+)
+
+func (m *userManager) Unlock(ctx context.Context, userId int64) error {
+	tenantId := requests.TenantId(ctx)
+	if err := m.im.Del(ctx, userAttemptsKey(tenantId, userId), userLockedKey(tenantId, userId)); err != nil {
+		return err
+	}
+	m.recordAudit(ctx, auditActionUserUnlocked, map[string]interface{}{"user_id": userId})
+	return nil
+}
+
+func (m *userManager) UnlockIp(ctx context.Context, ip string) error {
+	if err := m.im.Del(ctx, ipAttemptsKey(ip), ipLockedKey(ip)); err != nil {
+		return err
+	}
+	m.recordAudit(ctx, auditActionIpUnlocked, map[string]interface{}{"ip": ip})
+	return nil
+}
+
+// ensureNotLocked both user and ip address of the requester
+func (m *userManager) ensureNotLocked(ctx context.Context, userId int64) error {
+	now := ctime.Now()
+	if m.lockout.MaxAttempts > 0 && m.lockedAt(ctx, userLockedKey(requests.TenantId(ctx), userId), now) {
+		return ErrAccountLocked
+	}
+	if ip := requests.RemoteIp(ctx); m.lockout.MaxAttemptsPerIp > 0 && len(ip) > 0 &&
+		m.lockedAt(ctx, ipLockedKey(ip), now) {
+		return ErrAccountLocked
+	}
+	return nil
+}
+
+// registerFailure of authentication, lock the user or ip when threshold reached
+// and hold the caller with progressive delay to slow down guessing
+func (m *userManager) registerFailure(ctx context.Context, userId int64) {
+	ip := requests.RemoteIp(ctx)
+	count := 0
+	if m.lockout.MaxAttempts > 0 {
+		tenantId := requests.TenantId(ctx)
+		count = m.incrAttempts(ctx, userAttemptsKey(tenantId, userId), userLockedKey(tenantId, userId), m.lockout.MaxAttempts, auditActionUserLocked, map[string]interface{}{
+			"user_id": userId,
+			"ip":      ip,
+		})
+	}
+	if m.lockout.MaxAttemptsPerIp > 0 && len(ip) > 0 {
+		m.incrAttempts(ctx, ipAttemptsKey(ip), ipLockedKey(ip), m.lockout.MaxAttemptsPerIp, auditActionIpLocked, map[string]interface{}{
+			"user_id": userId,
+			"ip":      ip,
+		})
+	}
+	m.delay(ctx, count)
+}
+
+func (m *userManager) resetAttempts(ctx context.Context, userId int64) {
+	if m.lockout.MaxAttempts < 1 {
+		return
+	}
+	_ = m.im.Del(ctx, userAttemptsKey(requests.TenantId(ctx), userId))
+}
+
+// incrAttempts of failed authentication atomically and return the number of consecutive failures.
+// counter expire after the window since the first failure, lock kept on its own key until the lockout ended
+func (m *userManager) incrAttempts(ctx context.Context, key, lockKey string, threshold int, action string, props map[string]interface{}) int {
+	count, err := m.im.Incr(ctx, key, m.lockout.Window)
+	if err != nil {
+		m.log.WarnWithProps(map[string]interface{}{
+			"context": "user.incr_attempts",
+			"key":     key,
+		}, err.Error())
+		return 0
+	}
+	if count < int64(threshold) {
+		return int(count)
+	}
+	lockedUntil := ctime.Now().Add(m.lockout.LockoutDuration).Unix()
+	if err = m.im.SetString(ctx, lockKey, strconv.FormatInt(lockedUntil, 10), m.lockout.LockoutDuration); err != nil {
+		m.log.WarnWithProps(map[string]interface{}{
+			"context": "user.incr_attempts",
+			"key":     lockKey,
+		}, err.Error())
+	}
+	props["attempts"] = count
+	props["locked_until"] = lockedUntil
+	m.recordAudit(ctx, action, props)
+	// counter start over once lockout ended
+	_ = m.im.Del(ctx, key)
+	return int(count)
+}
+
+// lockedAt the given time, lock key hold the unix timestamp of when it ends
+func (m *userManager) lockedAt(ctx context.Context, lockKey string, now time.Time) bool {
+	lockedUntil, err := strconv.ParseInt(m.im.GetString(ctx, lockKey), 10, 64)
+	return err == nil && now.Unix() < lockedUntil
+}
+
+// delay of the n-th consecutive failure, doubled each time until reaching max delay
+func (m *userManager) delay(ctx context.Context, n int) {
+	if m.lockout.DelayBase <= 0 || n < 1 {
+		return
+	}
+	d := m.lockout.DelayBase
+	for i := 1; i < n && d < m.lockout.MaxDelay; i++ {
+		d *= 2
+	}
+	if m.lockout.MaxDelay > 0 && d > m.lockout.MaxDelay {
+		d = m.lockout.MaxDelay
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+func (m *userManager) recordAudit(ctx context.Context, action string, props map[string]interface{}) {
+	createdById := auditCreatedBySystemValue
+	if v, ok := props["user_id"].(int64); ok {
+		createdById = strconv.FormatInt(v, 10)
+	}
+	err := m.audit.Add(ctx, audit.Record{
+		Action:          action,
+		CreatedById:     createdById,
+		CreatedByName:   auditCreatedBySystemName,
+		AdditionalProps: props,
+	})
+	if err != nil {
+		m.log.WarnWithProps(map[string]interface{}{
+			"context": "user.record_audit",
+			"action":  action,
+		}, err.Error())
+	}
+}
+
+func userAttemptsKey(tenantId string, userId int64) string {
+	return userKey(inMemoryUserAttemptsKey, tenantId, userId)
+}
+
+func ipAttemptsKey(ip string) string {
+	return inMemoryIpAttemptsKey + "_" + ip
+}
+
+func userLockedKey(tenantId string, userId int64) string {
+	return userKey(inMemoryUserLockedKey, tenantId, userId)
+}
+
+func ipLockedKey(ip string) string {
+	return inMemoryIpLockedKey + "_" + ip
+}
@@ -0,0 +1,462 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: user_mfa
+ * @Date: 07/08/24 09.18
+ */
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/evorts/kevlars/crypt"
+	"github.com/evorts/kevlars/ctime"
+	"github.com/evorts/kevlars/db"
+	"github.com/evorts/kevlars/jwe"
+	"github.com/evorts/kevlars/requests"
+	"github.com/evorts/kevlars/rules"
+	"github.com/evorts/kevlars/utils"
+	"github.com/huandu/go-sqlbuilder"
+	"github.com/lib/pq"
+	"strings"
+)
+
+const (
+	tableUserMfa = "user_mfa"
+
+	recoveryCodeCount    = 10
+	recoveryCodeSize     = 10                                 // characters, displayed as two groups separated by dash
+	recoveryCodeAlphabet = "abcdefghijkmnpqrstuvwxyz23456789" // 32 characters keep random bytes unbiased
+
+	auditActionMfaEnrolled      = "auth.mfa.enrolled"
+	auditActionMfaDisabled      = "auth.mfa.disabled"
+	auditActionMfaRecoveryUsed  = "auth.mfa.recovery_used"
+	auditActionMfaRecoveryReset = "auth.mfa.recovery_reset"
+)
+
+//goland:noinspection SqlResolve
+var (
+	userMfaColumnDefinitions = map[db.SupportedDriver][][]string{
+		db.DriverPostgreSQL: {
+			{"id", "serial", "primary key"},
+			{"tenant_id", "varchar(50)", "not null", "default ''"},
+			{"user_id", "bigint", "not null"},
+			{"secret", "text", "not null"},
+			{"recovery_codes", "text[]", "default array[]::text[]"},
+			{"enabled", "boolean", "default false"},
+			{"last_step", "bigint", "default 0"},
+			{"created_at", "timestamp with time zone", "default current_timestamp"},
+			{"updated_at", "timestamp with time zone"},
+			{"enabled_at", "timestamp with time zone"},
+		},
+	}
+	userMfaIndexDefinitions = map[db.SupportedDriver][]string{
+		db.DriverPostgreSQL: {
+			fmt.Sprintf("create unique index if not exists %s_user_id_uidx on public.%s(user_id)", tableUserMfa, tableUserMfa),
+		},
+	}
+	// userMfaTenancyDefinition bring table created before tenant awareness into the current shape,
+	// existing enrolment take the tenant of its user
+	userMfaTenancyDefinition = map[db.SupportedDriver][]string{
+		db.DriverPostgreSQL: {
+			fmt.Sprintf("alter table %s add column if not exists tenant_id varchar(50) not null default ''", tableUserMfa),
+			fmt.Sprintf(`update %[1]s set tenant_id = a.tenant_id from %[2]s a
+				where a.user_id = %[1]s.user_id and %[1]s.tenant_id = '' and a.tenant_id <> ''`, tableUserMfa, tableUserAuth),
+		},
+	}
+	// userMfaFieldEncryptionDefinition bring table created before secret encryption into the current shape
+	userMfaFieldEncryptionDefinition = map[db.SupportedDriver][]string{
+		db.DriverPostgreSQL: {
+			fmt.Sprintf("alter table %s alter column secret type text", tableUserMfa),
+		},
+	}
+	userMfaSecretsQuery = map[db.SupportedDriver]struct {
+		list   string
+		update string
+	}{
+		db.DriverPostgreSQL: {
+			list: `SELECT id, secret FROM ` + tableUserMfa + ` WHERE id > ? ORDER BY id LIMIT ?`,
+			// only replace the value read, so concurrent enrolment is not overridden
+			update: `UPDATE ` + tableUserMfa + ` SET secret = ?, updated_at = current_timestamp WHERE id = ? AND secret = ?`,
+		},
+	}
+	// userMfaUserOfTenantQuery ensure second factor only enrolled for user of the same tenant
+	userMfaUserOfTenantQuery = map[db.SupportedDriver]string{
+		db.DriverPostgreSQL: `SELECT count(id) FROM ` + tableUserAuth + ` WHERE user_id = ? AND tenant_id = ?`,
+	}
+	// userMfaEnrolQuery replace pending enrolment, enabled one should be disabled first
+	userMfaEnrolQuery = map[db.SupportedDriver]string{
+		db.DriverPostgreSQL: `INSERT INTO ` + tableUserMfa + ` (tenant_id, user_id, secret, recovery_codes) VALUES (?, ?, ?, ?)
+			ON CONFLICT (user_id) DO
+				UPDATE SET
+					secret = excluded.secret,
+					recovery_codes = excluded.recovery_codes,
+					last_step = 0,
+					updated_at = current_timestamp
+				WHERE ` + tableUserMfa + `.enabled = false AND ` + tableUserMfa + `.tenant_id = excluded.tenant_id
+			RETURNING id`,
+	}
+	userMfaGetQuery = map[db.SupportedDriver]string{
+		db.DriverPostgreSQL: `SELECT id, tenant_id, user_id, secret, recovery_codes, enabled, last_step, created_at, updated_at, enabled_at
+			FROM ` + tableUserMfa + ` WHERE user_id = ? AND tenant_id = ?`,
+	}
+	// userMfaConsumeStepQuery accept each time step only once to prevent replay of intercepted code
+	userMfaConsumeStepQuery = map[db.SupportedDriver]string{
+		db.DriverPostgreSQL: `UPDATE ` + tableUserMfa + ` SET
+				last_step = ?,
+				enabled = true,
+				enabled_at = COALESCE(enabled_at, current_timestamp),
+				updated_at = current_timestamp
+			WHERE user_id = ? AND tenant_id = ? AND enabled = ? AND last_step < ?`,
+	}
+	// userMfaRecoveryCodesQuery compare against the previous codes so concurrent use of the same code only succeed once
+	userMfaRecoveryCodesQuery = map[db.SupportedDriver]string{
+		db.DriverPostgreSQL: `UPDATE ` + tableUserMfa + ` SET recovery_codes = ?, updated_at = current_timestamp
+			WHERE user_id = ? AND tenant_id = ? AND enabled = true AND recovery_codes = ?`,
+	}
+	userMfaRemoveQuery = map[db.SupportedDriver]string{
+		db.DriverPostgreSQL: `DELETE FROM ` + tableUserMfa + ` WHERE user_id = ? AND tenant_id = ?`,
+	}
+)
+
+func (m *userManager) EnrollTOTP(ctx context.Context, userId int64, account string) (TOTPEnrolment, error) {
+	tenantId := requests.TenantId(ctx)
+	var total int
+	if err := m.dbr.QueryRow(ctx, m.dbr.Rebind(userMfaUserOfTenantQuery[m.driver]), userId, tenantId).Scan(&total); err != nil {
+		return TOTPEnrolment{}, err
+	}
+	if total < 1 {
+		return TOTPEnrolment{}, db.ErrorRecordNotFound
+	}
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return TOTPEnrolment{}, err
+	}
+	codes, hashed, err := newRecoveryCodes(m.hasher)
+	if err != nil {
+		return TOTPEnrolment{}, err
+	}
+	rows, err := m.dbw.Query(ctx, m.dbw.Rebind(userMfaEnrolQuery[m.driver]), tenantId, userId, crypt.EncryptedString(secret), pq.Array(hashed))
+	defer func() {
+		rules.WhenTrue(rows != nil, func() {
+			_ = rows.Close()
+		})
+	}()
+	if err != nil {
+		return TOTPEnrolment{}, err
+	}
+	if !rows.Next() {
+		return TOTPEnrolment{}, ErrMFAAlreadyEnrolled
+	}
+	return TOTPEnrolment{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(m.mfaIssuer, account, secret),
+		RecoveryCodes:   codes,
+	}, nil
+}
+
+func (m *userManager) ConfirmTOTP(ctx context.Context, userId int64, code string) error {
+	record, err := m.getMfa(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if record.Enabled {
+		return ErrMFAAlreadyEnrolled
+	}
+	step, ok := totpVerify(record.Secret.String(), code, ctime.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	if err = m.consumeStep(ctx, userId, step, false); err != nil {
+		return err
+	}
+	m.recordAudit(ctx, auditActionMfaEnrolled, map[string]interface{}{"user_id": userId})
+	return nil
+}
+
+func (m *userManager) DisableTOTP(ctx context.Context, userId int64) error {
+	rs, err := m.dbw.Exec(ctx, m.dbw.Rebind(userMfaRemoveQuery[m.driver]), userId, requests.TenantId(ctx))
+	if err != nil {
+		return err
+	}
+	if affected, _ := rs.RowsAffected(); affected < 1 {
+		return ErrMFANotEnrolled
+	}
+	m.recordAudit(ctx, auditActionMfaDisabled, map[string]interface{}{"user_id": userId})
+	return nil
+}
+
+func (m *userManager) RegenerateRecoveryCodes(ctx context.Context, userId int64) ([]string, error) {
+	record, err := m.getMfa(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !record.Enabled {
+		return nil, ErrMFANotEnrolled
+	}
+	codes, hashed, err := newRecoveryCodes(m.hasher)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.replaceRecoveryCodes(ctx, userId, record.RecoveryCodes, hashed); err != nil {
+		return nil, err
+	}
+	m.recordAudit(ctx, auditActionMfaRecoveryReset, map[string]interface{}{"user_id": userId})
+	return codes, nil
+}
+
+func (m *userManager) AuthenticateWithMFA(ctx context.Context, id int64, creds, code string) (TokenPair, error) {
+	record, err := m.verifyCredential(ctx, id, creds)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if err = m.verifyMfa(ctx, id, code); err != nil {
+		return TokenPair{}, err
+	}
+	m.resetAttempts(ctx, id)
+	return m.startSession(ctx, record, ctime.Now().Unix())
+}
+
+func (m *userManager) StepUp(ctx context.Context, accessToken, code string) (TokenPair, error) {
+	var claim jwe.Claim
+	if err := m.Introspect(ctx, accessToken, &claim); err != nil {
+		return TokenPair{}, err
+	}
+	if err := m.ensureNotLocked(ctx, claim.ID); err != nil {
+		return TokenPair{}, err
+	}
+	if err := m.verifyMfa(ctx, claim.ID, code); err != nil {
+		return TokenPair{}, err
+	}
+	m.resetAttempts(ctx, claim.ID)
+	now := ctime.Now()
+	if !m.trackSession {
+		return m.issueTokenPair(ctx, &Session{
+			ID:        claim.SessionID,
+			UserID:    claim.ID,
+			TenantID:  claim.TenantID,
+			StepUpAt:  now.Unix(),
+			ExpiredAt: now.Add(m.refreshTokenExpiration),
+		})
+	}
+	session, raw, err := m.loadSession(ctx, claim.ID, claim.SessionID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	session.StepUpAt = now.Unix()
+	pair, swapped, err := m.rotateSession(ctx, raw, session)
+	if err == nil && !swapped {
+		// session refreshed or revoked in the meantime
+		return TokenPair{}, ErrSessionNotFound
+	}
+	return pair, err
+}
+
+// mfaEnabled of user, pending enrolment not yet confirmed does not count
+func (m *userManager) mfaEnabled(ctx context.Context, userId int64) (bool, error) {
+	record, err := m.getMfa(ctx, userId)
+	if errors.Is(err, ErrMFANotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return record.Enabled, nil
+}
+
+// verifyMfa code of user which could be either totp code or one of the recovery codes
+func (m *userManager) verifyMfa(ctx context.Context, userId int64, code string) error {
+	record, err := m.getMfa(ctx, userId)
+	if err == nil && !record.Enabled {
+		err = ErrMFANotEnrolled
+	}
+	if err != nil {
+		return err
+	}
+	code = strings.TrimSpace(code)
+	if step, ok := totpVerify(record.Secret.String(), code, ctime.Now()); ok {
+		if err = m.consumeStep(ctx, userId, step, true); err != nil {
+			m.registerFailure(ctx, userId)
+		}
+		return err
+	}
+	normalized := normalizeRecoveryCode(code)
+	for i, hashed := range record.RecoveryCodes {
+		if verifyCreds(m.hasher, hashed, normalized) != nil {
+			continue
+		}
+		remaining := make([]string, 0, len(record.RecoveryCodes)-1)
+		remaining = append(remaining, record.RecoveryCodes[:i]...)
+		remaining = append(remaining, record.RecoveryCodes[i+1:]...)
+		if err = m.replaceRecoveryCodes(ctx, userId, record.RecoveryCodes, remaining); err != nil {
+			m.registerFailure(ctx, userId)
+			return err
+		}
+		m.recordAudit(ctx, auditActionMfaRecoveryUsed, map[string]interface{}{
+			"user_id":   userId,
+			"remaining": len(remaining),
+		})
+		return nil
+	}
+	m.registerFailure(ctx, userId)
+	return ErrInvalidMFACode
+}
+
+func (m *userManager) getMfa(ctx context.Context, userId int64) (UserMfaRecord, error) {
+	var record UserMfaRecord
+	rows, err := m.dbr.Query(ctx, m.dbr.Rebind(userMfaGetQuery[m.driver]), userId, requests.TenantId(ctx))
+	defer func() {
+		rules.WhenTrue(rows != nil, func() {
+			_ = rows.Close()
+		})
+	}()
+	if err != nil {
+		return record, err
+	}
+	if !rows.Next() {
+		return record, ErrMFANotEnrolled
+	}
+	return record, rows.StructScan(&record)
+}
+
+// consumeStep of totp, code of the same or earlier step than the last accepted one is rejected
+func (m *userManager) consumeStep(ctx context.Context, userId, step int64, enabled bool) error {
+	rs, err := m.dbw.Exec(ctx, m.dbw.Rebind(userMfaConsumeStepQuery[m.driver]), step, userId, requests.TenantId(ctx), enabled, step)
+	if err != nil {
+		return err
+	}
+	if affected, _ := rs.RowsAffected(); affected < 1 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (m *userManager) replaceRecoveryCodes(ctx context.Context, userId int64, previous, codes []string) error {
+	rs, err := m.dbw.Exec(ctx, m.dbw.Rebind(userMfaRecoveryCodesQuery[m.driver]), pq.Array(codes), userId, requests.TenantId(ctx), pq.Array(previous))
+	if err != nil {
+		return err
+	}
+	if affected, _ := rs.RowsAffected(); affected < 1 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// encryptMfaSecrets of totp stored as plain text before field encryption enabled
+func (m *userManager) encryptMfaSecrets(ctx context.Context) (int, error) {
+	q, ok := userMfaSecretsQuery[m.driver]
+	if !ok {
+		return 0, errors.New("not supported yet")
+	}
+	encrypted, lastId := 0, int64(0)
+	for {
+		secrets, err := m.listMfaSecrets(ctx, q.list, lastId)
+		if err != nil || len(secrets) < 1 {
+			return encrypted, err
+		}
+		for _, raw := range secrets {
+			lastId = raw.ID
+			isEncrypted, err := crypt.IsEncryptedField(raw.Secret.String())
+			if err != nil {
+				return encrypted, err
+			}
+			if isEncrypted {
+				continue
+			}
+			rs, err := m.dbw.Exec(ctx, m.dbw.Rebind(q.update), raw.Secret, raw.ID, raw.Secret.String())
+			if err != nil {
+				return encrypted, err
+			}
+			if n, _ := rs.RowsAffected(); n > 0 {
+				encrypted++
+			}
+		}
+	}
+}
+
+// listMfaSecrets read secret as stored, so plain text value could be read while field encryption enabled
+func (m *userManager) listMfaSecrets(ctx context.Context, q string, afterId int64) ([]UserMfaRecord, error) {
+	rs := make([]UserMfaRecord, 0)
+	rows, err := m.dbw.Query(ctx, m.dbw.Rebind(q), afterId, userAuthFieldsBatchSize)
+	defer func() {
+		rules.WhenTrue(rows != nil, func() {
+			_ = rows.Close()
+		})
+	}()
+	if err != nil {
+		return rs, err
+	}
+	for rows.Next() {
+		var (
+			record UserMfaRecord
+			secret string
+		)
+		if err = rows.Scan(&record.ID, &secret); err != nil {
+			return rs, err
+		}
+		record.Secret = crypt.EncryptedString(secret)
+		rs = append(rs, record)
+	}
+	return rs, rows.Err()
+}
+
+// initMfaSchema create table of second factor, separated from the main schema
+// so that installation prior to mfa support get it created as well, along with tenant awareness
+func (m *userManager) initMfaSchema(ctx context.Context, driver db.SupportedDriver) error {
+	columnDefinitions := utils.GetValueOnMap(userMfaColumnDefinitions, driver, [][]string{})
+	if len(columnDefinitions) < 1 {
+		return errors.New("user mfa column definitions is empty")
+	}
+	builder := sqlbuilder.NewCreateTableBuilder().CreateTable(tableUserMfa).IfNotExists()
+	for _, definition := range columnDefinitions {
+		builder = builder.Define(definition...)
+	}
+	q, _ := builder.BuildWithFlavor(getFlavorByDriver(driver))
+	tx := m.dbw.MustBegin(ctx, &sql.TxOptions{})
+	if _, err := tx.Exec(q); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	for _, definition := range utils.GetValueOnMap(userMfaIndexDefinitions, driver, []string{}) {
+		if _, err := tx.Exec(definition); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if err := db.ExecDefinitions(ctx, m.dbw, userMfaTenancyDefinition); err != nil {
+		return err
+	}
+	return db.ExecDefinitions(ctx, m.dbw, userMfaFieldEncryptionDefinition)
+}
+
+// newRecoveryCodes return the plain codes to be shown once to the user along with its hashes to be stored
+func newRecoveryCodes(h crypt.Hasher) (codes []string, hashed []string, err error) {
+	codes = make([]string, recoveryCodeCount)
+	hashed = make([]string, recoveryCodeCount)
+	b := make([]byte, recoveryCodeSize)
+	for i := range codes {
+		if _, err = rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := make([]byte, recoveryCodeSize)
+		for j, v := range b {
+			raw[j] = recoveryCodeAlphabet[int(v)%len(recoveryCodeAlphabet)]
+		}
+		codes[i] = string(raw[:recoveryCodeSize/2]) + "-" + string(raw[recoveryCodeSize/2:])
+		if hashed[i], err = hashCreds(h, string(raw)); err != nil {
+			return nil, nil, err
+		}
+	}
+	return codes, hashed, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -9,26 +9,41 @@ package auth
 
 import (
 	"database/sql"
+	"github.com/evorts/kevlars/crypt"
+	"github.com/lib/pq"
 	"time"
 )
 
 type UserAuthRecord struct {
-	ID         int        `db:"id"`
-	UserID     int        `db:"user_id"`
-	Email      string     `db:"email"`
-	Phone      string     `db:"phone"`
-	Creds      string     `db:"creds"`
-	Disabled   bool       `db:"disabled"`
-	CreatedAt  time.Time  `db:"created_at"`
-	UpdatedAt  *time.Time `db:"updated_at"`
-	DisabledAt *time.Time `db:"disabled_at"`
-	ExpiredAt  *time.Time `db:"expired_at"`
+	ID         int                   `db:"id"`
+	TenantID   string                `db:"tenant_id"` // populated from context on write
+	UserID     int                   `db:"user_id"`
+	Email      crypt.EncryptedString `db:"email"`
+	Phone      crypt.EncryptedString `db:"phone"`
+	EmailIndex string                `db:"email_idx"` // blind index of email, populated on write
+	PhoneIndex string                `db:"phone_idx"` // blind index of phone, populated on write
+	Creds      string                `db:"creds"`
+	Disabled   bool                  `db:"disabled"`
+	CreatedAt  time.Time             `db:"created_at"`
+	UpdatedAt  *time.Time            `db:"updated_at"`
+	DisabledAt *time.Time            `db:"disabled_at"`
+	ExpiredAt  *time.Time            `db:"expired_at"`
 }
 
 type UserAuthRecords []*UserAuthRecord
 
+// index populate blind index of email and phone
+func (r *UserAuthRecord) index() (err error) {
+	if r.EmailIndex, err = crypt.BlindIndex(r.Email.String()); err != nil {
+		return err
+	}
+	r.PhoneIndex, err = crypt.BlindIndex(r.Phone.String())
+	return err
+}
+
 type UserAccessRecord struct {
 	ID         int64        `db:"id"`
+	TenantID   string       `db:"tenant_id"` // populated from context on write
 	UserID     int64        `db:"user_id"`
 	Resource   string       `db:"resource"`
 	Scopes     Scopes       `db:"scopes"`
@@ -46,3 +61,44 @@ type UserWithAccessRecord struct {
 }
 
 type UserWithAccessRecords []*UserAccessRecord
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"` // lifetime of access token in seconds
+	SessionID    string `json:"session_id"`
+}
+
+type Session struct {
+	ID          string     `json:"id"`
+	UserID      int64      `json:"user_id"`
+	TenantID    string     `json:"tenant_id,omitempty"`
+	RefreshID   string     `json:"refresh_id"`           // token id of the latest issued refresh token
+	StepUpAt    int64      `json:"step_up_at,omitempty"` // unix timestamp of the latest second factor verification
+	CreatedAt   time.Time  `json:"created_at"`
+	RefreshedAt *time.Time `json:"refreshed_at,omitempty"`
+	ExpiredAt   time.Time  `json:"expired_at"`
+}
+
+type Sessions []*Session
+
+type UserMfaRecord struct {
+	ID            int64                 `db:"id"`
+	TenantID      string                `db:"tenant_id"`
+	UserID        int64                 `db:"user_id"`
+	Secret        crypt.EncryptedString `db:"secret"`
+	RecoveryCodes pq.StringArray        `db:"recovery_codes"` // hashed
+	Enabled       bool                  `db:"enabled"`
+	LastStep      int64                 `db:"last_step"` // last accepted time step of totp
+	CreatedAt     time.Time             `db:"created_at"`
+	UpdatedAt     sql.NullTime          `db:"updated_at"`
+	EnabledAt     sql.NullTime          `db:"enabled_at"`
+}
+
+// TOTPEnrolment to be presented to user once, provisioning uri usually rendered as QR code
+type TOTPEnrolment struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioning_uri"`
+	RecoveryCodes   []string `json:"recovery_codes"`
+}
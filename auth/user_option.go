@@ -10,9 +10,12 @@ package auth
 import (
 	"github.com/evorts/kevlars/audit"
 	"github.com/evorts/kevlars/common"
+	"github.com/evorts/kevlars/crypt"
 	"github.com/evorts/kevlars/db"
 	"github.com/evorts/kevlars/inmemory"
+	"github.com/evorts/kevlars/jwe"
 	"github.com/evorts/kevlars/logger"
+	"time"
 )
 
 func UserAuthWithLogger(v logger.Manager) common.Option[userManager] {
@@ -38,3 +41,57 @@ func UserAuthWithInMemoryManager(im inmemory.Manager) common.Option[userManager]
 		u.im = im
 	})
 }
+
+func UserAuthWithRoleManager(rm RoleManager) common.Option[userManager] {
+	return common.OptionFunc[userManager](func(u *userManager) {
+		u.role = rm
+	})
+}
+
+func UserAuthWithJWE(v jwe.Manager) common.Option[userManager] {
+	return common.OptionFunc[userManager](func(u *userManager) {
+		u.jwe = v
+	})
+}
+
+func UserAuthWithTokenExpiration(v time.Duration) common.Option[userManager] {
+	return common.OptionFunc[userManager](func(u *userManager) {
+		u.tokenExpiration = v
+	})
+}
+
+func UserAuthWithRefreshTokenExpiration(v time.Duration) common.Option[userManager] {
+	return common.OptionFunc[userManager](func(u *userManager) {
+		u.refreshTokenExpiration = v
+	})
+}
+
+// UserAuthWithSessionTracking keep track of issued sessions in the in memory storage,
+// required by refresh token reuse detection and sessions revocation
+func UserAuthWithSessionTracking(enabled bool) common.Option[userManager] {
+	return common.OptionFunc[userManager](func(u *userManager) {
+		u.trackSession = enabled
+	})
+}
+
+// UserAuthWithLockoutPolicy of failed authentication, failed attempts are kept in memory
+func UserAuthWithLockoutPolicy(v LockoutPolicy) common.Option[userManager] {
+	return common.OptionFunc[userManager](func(u *userManager) {
+		u.lockout = v
+	})
+}
+
+// UserAuthWithMFAIssuer shown by authenticator apps along with the account of enrolled totp
+func UserAuthWithMFAIssuer(v string) common.Option[userManager] {
+	return common.OptionFunc[userManager](func(u *userManager) {
+		u.mfaIssuer = v
+	})
+}
+
+// UserAuthWithHasher of credentials and recovery codes, default to argon2id. existing hash of other algorithm
+// still accepted and upgraded on the next successful authentication
+func UserAuthWithHasher(v crypt.Hasher) common.Option[userManager] {
+	return common.OptionFunc[userManager](func(u *userManager) {
+		u.hasher = v
+	})
+}
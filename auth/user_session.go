@@ -0,0 +1,362 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: user_session
+ * @Date: 04/08/24 13.26
+ */
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/evorts/kevlars/ctime"
+	"github.com/evorts/kevlars/jwe"
+	"github.com/evorts/kevlars/requests"
+	"sort"
+	"strconv"
+	"time"
+)
+
+func (m *userManager) Refresh(ctx context.Context, refreshToken string) (TokenPair, error) {
+	if m.jwe == nil {
+		return TokenPair{}, ErrTokenManagerNotDefined
+	}
+	claim, err := m.jwe.Decode(refreshToken)
+	if err != nil {
+		return TokenPair{}, tokenError(err)
+	}
+	if claim.TokenType != TokenTypeRefresh {
+		return TokenPair{}, ErrInvalidToken
+	}
+	if claim.TenantID != requests.TenantId(ctx) {
+		return TokenPair{}, ErrTenantMismatch
+	}
+	now := ctime.Now()
+	if claim.Expired(now) {
+		return TokenPair{}, ErrTokenExpired
+	}
+	if m.isRevoked(ctx, claim.TokenID) {
+		return TokenPair{}, ErrTokenRevoked
+	}
+	disabled, err := m.isDisabled(ctx, claim.ID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if disabled {
+		return TokenPair{}, ErrUserDisabled
+	}
+	if !m.trackSession {
+		// without session, rotation could only be done by revoking the presented refresh token
+		if err = m.revoke(ctx, claim); err != nil {
+			return TokenPair{}, err
+		}
+		return m.issueTokenPair(ctx, &Session{
+			ID:        claim.SessionID,
+			UserID:    claim.ID,
+			TenantID:  claim.TenantID,
+			StepUpAt:  claim.StepUpAt,
+			ExpiredAt: now.Add(m.refreshTokenExpiration),
+		})
+	}
+	session, raw, err := m.loadSession(ctx, claim.ID, claim.SessionID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if session.RefreshID == claim.TokenID {
+		session.RefreshedAt = &now
+		session.ExpiredAt = now.Add(m.refreshTokenExpiration)
+		pair, swapped, errR := m.rotateSession(ctx, raw, session)
+		if errR != nil || swapped {
+			return pair, errR
+		}
+		// lost the race against concurrent refresh of the same token, or the session revoked in the meantime
+	}
+	// refresh token which already rotated presented again, the token family most likely compromised.
+	// ending the session invalidate every token issued under it.
+	m.log.WarnWithProps(map[string]interface{}{
+		"context":    "user.refresh",
+		"user_id":    claim.ID,
+		"session_id": session.ID,
+	}, ErrRefreshTokenReused.Error())
+	if err = m.endSession(ctx, claim.ID, session.ID); err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{}, ErrRefreshTokenReused
+}
+
+func (m *userManager) Revoke(ctx context.Context, token string) error {
+	if m.jwe == nil {
+		return ErrTokenManagerNotDefined
+	}
+	claim, err := m.jwe.Decode(token)
+	if errors.Is(err, jwe.ErrTokenExpired) {
+		// expired token could not be used anymore, nothing to revoke
+		return nil
+	}
+	if err != nil {
+		return ErrInvalidToken
+	}
+	_ = m.im.Del(ctx, tokenKey(token))
+	if err = m.revoke(ctx, claim); err != nil {
+		return err
+	}
+	if claim.TokenType == TokenTypeRefresh && m.trackSession {
+		return m.RevokeSession(ctx, claim.ID, claim.SessionID)
+	}
+	return nil
+}
+
+func (m *userManager) Sessions(ctx context.Context, userId int64) (Sessions, error) {
+	if !m.trackSession {
+		return nil, ErrSessionNotTracked
+	}
+	tenantId := requests.TenantId(ctx)
+	ids, err := m.im.HKeys(ctx, sessionsKey(tenantId, userId))
+	if err != nil {
+		return nil, err
+	}
+	rs := make(Sessions, 0, len(ids))
+	stale := make([]string, 0)
+	for _, id := range ids {
+		session, _, errL := m.loadSession(ctx, userId, id)
+		if errors.Is(errL, ErrSessionNotFound) {
+			stale = append(stale, id)
+			continue
+		}
+		if errL != nil {
+			return nil, errL
+		}
+		rs = append(rs, session)
+	}
+	if len(stale) > 0 {
+		_ = m.im.HDel(ctx, sessionsKey(tenantId, userId), stale...)
+	}
+	sort.Slice(rs, func(i, j int) bool {
+		return rs[i].CreatedAt.Before(rs[j].CreatedAt)
+	})
+	return rs, nil
+}
+
+func (m *userManager) RevokeSession(ctx context.Context, userId int64, sessionId string) error {
+	if !m.trackSession {
+		return ErrSessionNotTracked
+	}
+	if len(m.im.GetString(ctx, sessionKey(requests.TenantId(ctx), userId, sessionId))) < 1 {
+		return ErrSessionNotFound
+	}
+	return m.endSession(ctx, userId, sessionId)
+}
+
+func (m *userManager) RevokeAllSessions(ctx context.Context, userId int64) error {
+	if !m.trackSession {
+		return ErrSessionNotTracked
+	}
+	tenantId := requests.TenantId(ctx)
+	ids, err := m.im.HKeys(ctx, sessionsKey(tenantId, userId))
+	if err != nil {
+		return err
+	}
+	keys := []string{sessionsKey(tenantId, userId)}
+	for _, id := range ids {
+		keys = append(keys, sessionKey(tenantId, userId, id))
+	}
+	return m.im.Del(ctx, keys...)
+}
+
+// startSession of authenticated user and issue the first pair of tokens, session bound to tenant of the user
+// stepUpAt is the time of second factor verification, zero when only password verified
+func (m *userManager) startSession(ctx context.Context, user *UserAuthRecord, stepUpAt int64) (TokenPair, error) {
+	now := ctime.Now()
+	userId := int64(user.UserID)
+	session := &Session{
+		ID:        newTokenId(),
+		UserID:    userId,
+		TenantID:  user.TenantID,
+		StepUpAt:  stepUpAt,
+		CreatedAt: now,
+		ExpiredAt: now.Add(m.refreshTokenExpiration),
+	}
+	pair, err := m.issueTokenPair(ctx, session)
+	if err != nil || !m.trackSession {
+		return pair, err
+	}
+	b, err := json.Marshal(session)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	// index first, thus session always reachable by revoking all sessions of the user
+	if err = m.im.HSet(ctx, sessionsKey(user.TenantID, userId), session.ID, session.ExpiredAt.Unix()); err != nil {
+		return TokenPair{}, err
+	}
+	return pair, m.im.SetString(ctx, sessionKey(user.TenantID, userId, session.ID), string(b), time.Until(session.ExpiredAt))
+}
+
+// rotateSession issue new pair of tokens under the session, then swap the stored session only when
+// it is still the one loaded as raw. not swapped when other refresh, step up or revoke came first
+func (m *userManager) rotateSession(ctx context.Context, raw string, session *Session) (TokenPair, bool, error) {
+	pair, err := m.issueTokenPair(ctx, session)
+	if err != nil {
+		return TokenPair{}, false, err
+	}
+	b, err := json.Marshal(session)
+	if err != nil {
+		return TokenPair{}, false, err
+	}
+	swapped, err := m.im.CompareAndSwap(ctx, sessionKey(session.TenantID, session.UserID, session.ID), raw, string(b), time.Until(session.ExpiredAt))
+	if err != nil || !swapped {
+		return TokenPair{}, false, err
+	}
+	return pair, true, nil
+}
+
+// issueTokenPair under the given session, session refresh id updated with the new refresh token
+func (m *userManager) issueTokenPair(ctx context.Context, session *Session) (TokenPair, error) {
+	now := ctime.Now()
+	amr := []string{jwe.AMRPassword}
+	if session.StepUpAt > 0 {
+		amr = append(amr, jwe.AMROneTimePassword)
+	}
+	subject := strconv.FormatInt(session.UserID, 10)
+	access := jwe.Claim{
+		RegisteredClaims: jwe.RegisteredClaims{
+			Subject:   subject,
+			TokenID:   newTokenId(),
+			IssuedAt:  now.Unix(),
+			ExpiredAt: now.Add(m.tokenExpiration).Unix(),
+		},
+		ID:        session.UserID,
+		TokenType: TokenTypeAccess,
+		SessionID: session.ID,
+		TenantID:  session.TenantID,
+		AMR:       amr,
+		StepUpAt:  session.StepUpAt,
+	}
+	refresh := jwe.Claim{
+		RegisteredClaims: jwe.RegisteredClaims{
+			Subject:   subject,
+			TokenID:   newTokenId(),
+			IssuedAt:  now.Unix(),
+			ExpiredAt: session.ExpiredAt.Unix(),
+		},
+		ID:        session.UserID,
+		TokenType: TokenTypeRefresh,
+		SessionID: session.ID,
+		TenantID:  session.TenantID,
+		AMR:       amr,
+		StepUpAt:  session.StepUpAt,
+	}
+	var (
+		pair = TokenPair{
+			TokenType: tokenTypeBearer,
+			ExpiresIn: int64(m.tokenExpiration.Seconds()),
+			SessionID: session.ID,
+		}
+		err error
+	)
+	if pair.AccessToken, err = m.jwe.Encode(access); err != nil {
+		return TokenPair{}, err
+	}
+	if pair.RefreshToken, err = m.jwe.Encode(refresh); err != nil {
+		return TokenPair{}, err
+	}
+	session.RefreshID = refresh.TokenID
+	b, err := json.Marshal(access)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	// one key per token, thus cached claim gone along with the token expiration
+	if err = m.im.SetWithExpire(ctx, tokenKey(pair.AccessToken), string(b), m.tokenExpiration); err != nil {
+		m.log.WarnWithProps(map[string]interface{}{
+			"context": "user.issue_token_pair",
+			"user_id": session.UserID,
+		}, err.Error())
+	}
+	return pair, nil
+}
+
+// validateClaim of access token against revocation list, session and user state
+func (m *userManager) validateClaim(ctx context.Context, claim jwe.Claim) error {
+	if claim.TenantID != requests.TenantId(ctx) {
+		return ErrTenantMismatch
+	}
+	if m.isRevoked(ctx, claim.TokenID) {
+		return ErrTokenRevoked
+	}
+	if m.trackSession && len(m.im.GetString(ctx, sessionKey(claim.TenantID, claim.ID, claim.SessionID))) < 1 {
+		return ErrSessionNotFound
+	}
+	disabled, err := m.isDisabled(ctx, claim.ID)
+	if err != nil {
+		return err
+	}
+	if disabled {
+		return ErrUserDisabled
+	}
+	return nil
+}
+
+// revoke token by keeping its id in memory until the token expired by itself
+func (m *userManager) revoke(ctx context.Context, claim jwe.Claim) error {
+	if len(claim.TokenID) < 1 {
+		return ErrInvalidToken
+	}
+	ttl := time.Until(time.Unix(claim.ExpiredAt, 0))
+	if ttl <= 0 {
+		return nil
+	}
+	return m.im.SetWithExpire(ctx, revokedKey(claim.TokenID), "1", ttl)
+}
+
+func (m *userManager) isRevoked(ctx context.Context, tokenId string) bool {
+	if len(tokenId) < 1 {
+		return true
+	}
+	return len(m.im.GetString(ctx, revokedKey(tokenId))) > 0
+}
+
+// loadSession of user within tenant of the context along with its stored value, expired session taken as not found
+func (m *userManager) loadSession(ctx context.Context, userId int64, sessionId string) (*Session, string, error) {
+	raw := m.im.GetString(ctx, sessionKey(requests.TenantId(ctx), userId, sessionId))
+	if len(raw) < 1 {
+		return nil, "", ErrSessionNotFound
+	}
+	var session Session
+	if err := json.Unmarshal([]byte(raw), &session); err != nil {
+		return nil, "", err
+	}
+	if ctime.Now().After(session.ExpiredAt) {
+		return nil, "", ErrSessionNotFound
+	}
+	return &session, raw, nil
+}
+
+// endSession remove the session along with its entry on the index of user sessions within tenant of the context
+func (m *userManager) endSession(ctx context.Context, userId int64, sessionId string) error {
+	tenantId := requests.TenantId(ctx)
+	if err := m.im.Del(ctx, sessionKey(tenantId, userId, sessionId)); err != nil {
+		return err
+	}
+	return m.im.HDel(ctx, sessionsKey(tenantId, userId), sessionId)
+}
+
+func sessionsKey(tenantId string, userId int64) string {
+	return userKey(inMemoryUserSessionsKey, tenantId, userId)
+}
+
+func sessionKey(tenantId string, userId int64, sessionId string) string {
+	return userKey(inMemoryUserSessionKey, tenantId, userId) + "_" + sessionId
+}
+
+func revokedKey(tokenId string) string {
+	return inMemoryUserRevokedKey + "_" + tokenId
+}
+
+// tokenKey of cached claim, token hashed to keep the key short and the token itself out of the storage
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return inMemoryUserTokenKey + "_" + hex.EncodeToString(sum[:])
+}
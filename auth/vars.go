@@ -0,0 +1,51 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: vars
+ * @Date: 02/08/24 10.21
+ */
+
+package auth
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	// TokenTypeClient issued through client credentials grant, never accepted as user token
+	TokenTypeClient = "client"
+
+	GrantTypeClientCredentials = "client_credentials"
+
+	tokenTypeBearer = "Bearer"
+
+	DefaultRefreshTokenExpiration = 7 * 24 * time.Hour
+	DefaultClientTokenExpiration  = 15 * time.Minute
+)
+
+var (
+	ErrRoleSelfInheritance    = errors.New("role could not inherit from itself")
+	ErrRoleCycle              = errors.New("role could not inherit from its descendant")
+	ErrInvalidCredential      = errors.New("invalid credential")
+	ErrCredentialExpired      = errors.New("credential expired")
+	ErrUserDisabled           = errors.New("user disabled")
+	ErrInvalidToken           = errors.New("invalid token")
+	ErrTokenExpired           = errors.New("token expired")
+	ErrTokenManagerNotDefined = errors.New("token manager not defined")
+	ErrTokenRevoked           = errors.New("token revoked")
+	ErrRefreshTokenReused     = errors.New("refresh token reused")
+	ErrSessionNotFound        = errors.New("session not found")
+	ErrSessionNotTracked      = errors.New("session tracking not enabled")
+	ErrInvalidClient          = errors.New("invalid client")
+	ErrInvalidScope           = errors.New("invalid scope")
+	ErrUnsupportedGrantType   = errors.New("unsupported grant type")
+	ErrTenantMismatch         = errors.New("tenant mismatch")
+	ErrAccountLocked          = errors.New("account temporarily locked")
+	ErrMFARequired            = errors.New("second factor required")
+	ErrMFANotEnrolled         = errors.New("second factor not enrolled")
+	ErrMFAAlreadyEnrolled     = errors.New("second factor already enrolled")
+	ErrInvalidMFACode         = errors.New("invalid second factor code")
+)
@@ -9,14 +9,18 @@ package captcha
 
 import (
 	"github.com/evorts/kevlars/common"
+	"github.com/evorts/kevlars/rules"
 	"github.com/mojocn/base64Captcha"
 )
 
 type B64Type string
 
 const (
-	B64TypeDigit  B64Type = "digit"
-	B64TypeString B64Type = "string"
+	B64TypeDigit   B64Type = "digit"
+	B64TypeString  B64Type = "string"
+	B64TypeMath    B64Type = "math"    // arithmetic question, the answer is its result
+	B64TypeChinese B64Type = "chinese" // chinese characters
+	B64TypeAudio   B64Type = "audio"   // spoken digits as base64 wav, for accessibility
 )
 
 const (
@@ -31,6 +35,11 @@ const (
 
 	defaultDigitSkew     = 0.7
 	defaultDigitDotCount = 70
+
+	defaultChineseLength = 4
+	defaultChineseFont   = "wqy-microhei.ttc"
+
+	defaultAudioLanguage = "en"
 )
 
 type b64Manager struct {
@@ -38,32 +47,68 @@ type b64Manager struct {
 	driver      base64Captcha.Driver
 	store       base64Captcha.Store
 	captcha     *base64Captcha.Captcha
+
+	width       int
+	height      int
+	length      int      // zero means default of the type
+	noise       int      // negative means default of the type
+	lineOptions int      // combination of base64Captcha.OptionShow*
+	source      string   // characters of string and chinese captcha, empty means default of the type
+	fonts       []string // empty means default of the type
+	language    string   // of audio captcha: en, ja, ru or zh
 }
 
 func (m *b64Manager) Init() error {
+	length := rules.Iif(m.length > 0, m.length, defaultCaptchaLength)
 	switch m.captchaType {
 	case B64TypeDigit:
 		m.driver = base64Captcha.NewDriverDigit(
-			defaultCaptchaHeight,
-			defaultCaptchaWidth,
-			defaultCaptchaLength,
+			m.height,
+			m.width,
+			length,
 			defaultDigitSkew,
-			defaultDigitDotCount,
+			rules.Iif(m.noise < 0, defaultDigitDotCount, m.noise),
+		)
+	case B64TypeMath:
+		m.driver = base64Captcha.NewDriverMath(
+			m.height,
+			m.width,
+			rules.Iif(m.noise < 0, defaultStringNoiseCount, m.noise),
+			m.lineOptions,
+			nil,
+			base64Captcha.DefaultEmbeddedFonts,
+			rules.Iif(len(m.fonts) > 0, m.fonts, []string{defaultStringFont}),
 		)
+	case B64TypeChinese:
+		m.driver = base64Captcha.NewDriverChinese(
+			m.height,
+			m.width,
+			rules.Iif(m.noise < 0, defaultStringNoiseCount, m.noise),
+			m.lineOptions,
+			rules.Iif(m.length > 0, m.length, defaultChineseLength),
+			rules.Iif(len(m.source) > 0, m.source, base64Captcha.TxtChineseCharaters),
+			nil,
+			base64Captcha.DefaultEmbeddedFonts,
+			rules.Iif(len(m.fonts) > 0, m.fonts, []string{defaultChineseFont}),
+		)
+	case B64TypeAudio:
+		m.driver = base64Captcha.NewDriverAudio(length, m.language)
 	default:
 		m.driver = base64Captcha.NewDriverString(
-			defaultCaptchaHeight,
-			defaultCaptchaWidth,
-			defaultStringNoiseCount,
-			defaultShowLineOptions,
-			defaultCaptchaLength,
-			defaultStringSource,
+			m.height,
+			m.width,
+			rules.Iif(m.noise < 0, defaultStringNoiseCount, m.noise),
+			m.lineOptions,
+			length,
+			rules.Iif(len(m.source) > 0, m.source, defaultStringSource),
 			nil,
 			base64Captcha.DefaultEmbeddedFonts,
-			[]string{defaultStringFont},
+			rules.Iif(len(m.fonts) > 0, m.fonts, []string{defaultStringFont}),
 		)
 	}
-	m.store = base64Captcha.DefaultMemStore
+	if m.store == nil {
+		m.store = base64Captcha.DefaultMemStore
+	}
 	m.captcha = base64Captcha.NewCaptcha(m.driver, m.store)
 	return nil
 }
@@ -90,6 +135,11 @@ func (m *b64Manager) Verify(id, value string, clear bool) bool {
 func NewB64(opts ...common.Option[b64Manager]) Manager[string] {
 	m := &b64Manager{
 		captchaType: B64TypeString,
+		width:       defaultCaptchaWidth,
+		height:      defaultCaptchaHeight,
+		noise:       -1,
+		lineOptions: defaultShowLineOptions,
+		language:    defaultAudioLanguage,
 	}
 	for _, opt := range opts {
 		opt.Apply(m)
@@ -7,10 +7,97 @@
 
 package captcha
 
-import "github.com/evorts/kevlars/common"
+import (
+	"github.com/evorts/kevlars/common"
+	"github.com/evorts/kevlars/inmemory"
+	"github.com/mojocn/base64Captcha"
+	"time"
+)
 
 func B64WithType(v B64Type) common.Option[b64Manager] {
 	return common.OptionFunc[b64Manager](func(m *b64Manager) {
 		m.captchaType = v
 	})
 }
+
+// B64WithStore of captcha answer, default to in process memory store which only works on single replica
+func B64WithStore(v base64Captcha.Store) common.Option[b64Manager] {
+	return common.OptionFunc[b64Manager](func(m *b64Manager) {
+		m.store = v
+	})
+}
+
+// B64WithInMemoryStore keep captcha answer on the shared in memory storage, see NewInMemoryStore
+func B64WithInMemoryStore(im inmemory.Manager, opts ...common.Option[inMemoryStore]) common.Option[b64Manager] {
+	return B64WithStore(NewInMemoryStore(im, opts...))
+}
+
+func StoreWithTTL(v time.Duration) common.Option[inMemoryStore] {
+	return common.OptionFunc[inMemoryStore](func(s *inMemoryStore) {
+		s.ttl = v
+	})
+}
+
+// StoreWithMaxAttempts of verification before the captcha removed, zero means unlimited
+func StoreWithMaxAttempts(v int) common.Option[inMemoryStore] {
+	return common.OptionFunc[inMemoryStore](func(s *inMemoryStore) {
+		s.maxAttempts = int64(v)
+	})
+}
+
+// StoreWithPrefix of keys, to separate captcha of different purposes
+func StoreWithPrefix(v string) common.Option[inMemoryStore] {
+	return common.OptionFunc[inMemoryStore](func(s *inMemoryStore) {
+		s.prefix = v
+	})
+}
+
+// B64WithDimension of captcha image in pixel
+func B64WithDimension(width, height int) common.Option[b64Manager] {
+	return common.OptionFunc[b64Manager](func(m *b64Manager) {
+		m.width = width
+		m.height = height
+	})
+}
+
+// B64WithLength of answer, not applicable to math captcha
+func B64WithLength(v int) common.Option[b64Manager] {
+	return common.OptionFunc[b64Manager](func(m *b64Manager) {
+		m.length = v
+	})
+}
+
+// B64WithNoise count of text noise, or dots on digit captcha
+func B64WithNoise(v int) common.Option[b64Manager] {
+	return common.OptionFunc[b64Manager](func(m *b64Manager) {
+		m.noise = v
+	})
+}
+
+// B64WithLineOptions combination of base64Captcha.OptionShowHollowLine, OptionShowSlimeLine and OptionShowSineLine
+func B64WithLineOptions(v int) common.Option[b64Manager] {
+	return common.OptionFunc[b64Manager](func(m *b64Manager) {
+		m.lineOptions = v
+	})
+}
+
+// B64WithSource characters of string captcha, or comma separated words of chinese captcha
+func B64WithSource(v string) common.Option[b64Manager] {
+	return common.OptionFunc[b64Manager](func(m *b64Manager) {
+		m.source = v
+	})
+}
+
+// B64WithFonts by name of the embedded fonts, e.g. "RitaSmith.ttf" or "wqy-microhei.ttc" for chinese
+func B64WithFonts(v ...string) common.Option[b64Manager] {
+	return common.OptionFunc[b64Manager](func(m *b64Manager) {
+		m.fonts = v
+	})
+}
+
+// B64WithLanguage of audio captcha: en, ja, ru or zh
+func B64WithLanguage(v string) common.Option[b64Manager] {
+	return common.OptionFunc[b64Manager](func(m *b64Manager) {
+		m.language = v
+	})
+}
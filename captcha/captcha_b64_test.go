@@ -0,0 +1,39 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: captcha_b64_test
+ * @Date: 16/08/24 08.40
+ */
+
+package captcha
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestB64Types(t *testing.T) {
+	tests := map[B64Type]string{
+		B64TypeDigit:   "data:image/png;base64,",
+		B64TypeString:  "data:image/png;base64,",
+		B64TypeMath:    "data:image/png;base64,",
+		B64TypeChinese: "data:image/png;base64,",
+		B64TypeAudio:   "data:audio/wav;base64,",
+	}
+	for typ, prefix := range tests {
+		t.Run(string(typ), func(t *testing.T) {
+			m := NewB64(B64WithType(typ), B64WithDimension(240, 80), B64WithLength(4), B64WithNoise(10)).MustInit()
+			id, answer, result := m.Generate()
+			assert.NotEmpty(t, id)
+			assert.True(t, strings.HasPrefix(result, prefix), result[:min(len(result), 32)])
+			if typ != B64TypeMath {
+				assert.Equal(t, 4, utf8.RuneCountInString(answer))
+			}
+			assert.False(t, m.Verify(id, answer+"x", false))
+			assert.True(t, m.Verify(id, answer, true))
+			assert.False(t, m.Verify(id, answer, true))
+		})
+	}
+}
@@ -0,0 +1,97 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: captcha_store
+ * @Date: 15/08/24 09.20
+ */
+
+package captcha
+
+import (
+	"context"
+	"crypto/subtle"
+	"github.com/evorts/kevlars/common"
+	"github.com/evorts/kevlars/inmemory"
+	"github.com/mojocn/base64Captcha"
+	"time"
+)
+
+const (
+	defaultStoreTTL         = 5 * time.Minute
+	defaultStoreMaxAttempts = 5
+	defaultStorePrefix      = "captcha"
+)
+
+// inMemoryStore keep captcha answer on shared in memory storage (redis or valkey), so captcha generated
+// by one replica could be verified by another one. answer expired after ttl, could only be verified
+// successfully once, and removed once the attempts exceed the limit
+type inMemoryStore struct {
+	im          inmemory.Manager
+	ttl         time.Duration
+	maxAttempts int64
+	prefix      string
+}
+
+func (s *inMemoryStore) answerKey(id string) string {
+	return s.prefix + "_" + id
+}
+
+func (s *inMemoryStore) attemptsKey(id string) string {
+	return s.prefix + "_attempts_" + id
+}
+
+func (s *inMemoryStore) usedKey(id string) string {
+	return s.prefix + "_used_" + id
+}
+
+func (s *inMemoryStore) Set(id string, value string) error {
+	return s.im.SetString(context.Background(), s.answerKey(id), value, s.ttl)
+}
+
+func (s *inMemoryStore) Get(id string, clear bool) string {
+	ctx := context.Background()
+	rs := s.im.GetString(ctx, s.answerKey(id))
+	if clear {
+		_ = s.im.Del(ctx, s.answerKey(id))
+	}
+	return rs
+}
+
+func (s *inMemoryStore) Verify(id, answer string, clear bool) bool {
+	if id == "" || answer == "" {
+		return false
+	}
+	ctx := context.Background()
+	attempts, err := s.im.Incr(ctx, s.attemptsKey(id), s.ttl)
+	if err != nil {
+		return false
+	}
+	if s.maxAttempts > 0 && attempts > s.maxAttempts {
+		_ = s.im.Del(ctx, s.answerKey(id))
+		return false
+	}
+	stored := s.Get(id, clear)
+	if stored == "" || subtle.ConstantTimeCompare([]byte(stored), []byte(answer)) != 1 {
+		return false
+	}
+	// single use, only the first of concurrent verifications win
+	if used, err := s.im.Incr(ctx, s.usedKey(id), s.ttl); err != nil || used > 1 {
+		return false
+	}
+	_ = s.im.Del(ctx, s.answerKey(id), s.attemptsKey(id))
+	return true
+}
+
+// NewInMemoryStore of captcha answer, default to 5 minutes ttl and 5 attempts
+func NewInMemoryStore(im inmemory.Manager, opts ...common.Option[inMemoryStore]) base64Captcha.Store {
+	s := &inMemoryStore{
+		im:          im,
+		ttl:         defaultStoreTTL,
+		maxAttempts: defaultStoreMaxAttempts,
+		prefix:      defaultStorePrefix,
+	}
+	for _, opt := range opts {
+		opt.Apply(s)
+	}
+	return s
+}
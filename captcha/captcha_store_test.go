@@ -0,0 +1,54 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: captcha_store_test
+ * @Date: 15/08/24 10.05
+ */
+
+package captcha
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/evorts/kevlars/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestInMemoryStore(t *testing.T) {
+	mr, err := miniredis.Run()
+	require.NoError(t, err)
+	defer mr.Close()
+	im := inmemory.NewRedis(mr.Addr()).MustConnect(context.Background())
+	store := NewInMemoryStore(im, StoreWithTTL(time.Minute), StoreWithMaxAttempts(3))
+
+	// verified on another replica sharing the same storage, only once
+	require.NoError(t, store.Set("a", "1234"))
+	other := NewInMemoryStore(im, StoreWithTTL(time.Minute), StoreWithMaxAttempts(3))
+	assert.False(t, other.Verify("a", "0000", false))
+	assert.True(t, other.Verify("a", "1234", false))
+	assert.False(t, store.Verify("a", "1234", false))
+
+	// removed once attempts exceeded
+	require.NoError(t, store.Set("b", "1234"))
+	for i := 0; i < 3; i++ {
+		assert.False(t, store.Verify("b", "0000", false))
+	}
+	assert.False(t, store.Verify("b", "1234", false))
+
+	// expired after ttl
+	require.NoError(t, store.Set("c", "1234"))
+	mr.FastForward(2 * time.Minute)
+	assert.False(t, store.Verify("c", "1234", false))
+
+	// clear remove the answer even on wrong answer
+	require.NoError(t, store.Set("d", "1234"))
+	assert.False(t, store.Verify("d", "0000", true))
+	assert.False(t, store.Verify("d", "1234", true))
+
+	m := NewB64(B64WithType(B64TypeDigit), B64WithStore(store)).MustInit()
+	id, answer, _ := m.Generate()
+	assert.True(t, m.Verify(id, answer, true))
+}
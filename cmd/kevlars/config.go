@@ -0,0 +1,163 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: config
+ * @Date: 22/08/24 10.10
+ */
+
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/evorts/kevlars/config"
+	"github.com/evorts/kevlars/scaffold"
+	"github.com/spf13/viper"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+func runConfig(args []string, in io.Reader, out io.Writer) error {
+	if len(args) < 1 {
+		return errUsage
+	}
+	switch args[0] {
+	case "keygen":
+		return configKeygen(args[1:], out)
+	case "encrypt":
+		return configEncrypt(args[1:], in, out)
+	case "decrypt":
+		return configDecrypt(args[1:], in, out)
+	case "schema":
+		return configSchema(out)
+	case "validate":
+		return configValidate(args[1:], out)
+	default:
+		return errUsage
+	}
+}
+
+func configKeygen(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("config keygen", flag.ContinueOnError)
+	size := fs.Int("size", 32, "key size in bytes, 32 for XCHACHA20-POLY1305 and AES-GCM-SIV, 64 for AES-SIV")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	key := make([]byte, *size)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(out, base64.StdEncoding.EncodeToString(key))
+	return err
+}
+
+func configEncrypt(args []string, in io.Reader, out io.Writer) error {
+	value, err := argOrStdin(args, in)
+	if err != nil {
+		return err
+	}
+	c, err := config.NewValueCrypterFromEnv()
+	if err != nil {
+		return err
+	}
+	rs, err := config.EncryptValue(c, value)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, rs)
+	return err
+}
+
+func configDecrypt(args []string, in io.Reader, out io.Writer) error {
+	value, err := argOrStdin(args, in)
+	if err != nil {
+		return err
+	}
+	if !config.IsEncrypted(value) {
+		return fmt.Errorf("value should be prefixed by %s", config.EncryptedPrefix)
+	}
+	c, err := config.NewValueCrypterFromEnv()
+	if err != nil {
+		return err
+	}
+	rs, err := config.DecryptValue(c, value)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, rs)
+	return err
+}
+
+func configSchema(out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(scaffold.ConfigSchema())
+}
+
+func configValidate(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	configFile := fs.String("config", "config.yaml", "config file")
+	secretFile := fs.String("secret", "secret.yaml", "secret file, empty to validate config file only")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// merged the same way as on load, secret take precedence
+	v := viper.New()
+	for _, file := range []string{*configFile, *secretFile} {
+		if len(file) < 1 {
+			continue
+		}
+		data, err := readConfigFile(file)
+		if err != nil {
+			return err
+		}
+		if err = v.MergeConfigMap(data); err != nil {
+			return err
+		}
+	}
+	issues := scaffold.ConfigSchema().Validate(v.AllSettings())
+	if len(issues) < 1 {
+		_, err := fmt.Fprintln(out, "config is valid")
+		return err
+	}
+	for _, issue := range issues {
+		if _, err := fmt.Fprintln(out, issue.String()); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("config is invalid, found %d issues", len(issues))
+}
+
+// readConfigFile through the same local file provider used on load, type follow the file extension
+func readConfigFile(file string) (map[string]interface{}, error) {
+	ctype := config.TypeYaml
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		ctype = config.TypeJson
+	}
+	p := config.NewLocalFile(filepath.Base(file), ctype, filepath.Dir(file))
+	if err := p.Init(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", file, err)
+	}
+	return p.GetData(), nil
+}
+
+// argOrStdin read the value from stdin when not given, thus plain secret not kept on shell history
+func argOrStdin(args []string, in io.Reader) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) < 1 {
+		return "", errUsage
+	}
+	return line, nil
+}
@@ -0,0 +1,63 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: config_test
+ * @Date: 22/08/24 10.40
+ */
+
+package main
+
+import (
+	"bytes"
+	"github.com/evorts/kevlars/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigEncryptDecrypt(t *testing.T) {
+	var key bytes.Buffer
+	require.NoError(t, run([]string{"config", "keygen"}, nil, &key))
+	t.Setenv(config.EnvKeyEncryptionKey.String(), strings.TrimSpace(key.String()))
+
+	var encrypted bytes.Buffer
+	require.NoError(t, run([]string{"config", "encrypt"}, strings.NewReader("s3cret\n"), &encrypted))
+	assert.True(t, config.IsEncrypted(encrypted.String()))
+
+	var decrypted bytes.Buffer
+	require.NoError(t, run([]string{"config", "decrypt", strings.TrimSpace(encrypted.String())}, nil, &decrypted))
+	assert.Equal(t, "s3cret\n", decrypted.String())
+
+	assert.ErrorIs(t, run([]string{"config", "unknown"}, nil, &decrypted), errUsage)
+}
+
+func TestConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yaml")
+	secretFile := filepath.Join(dir, "secret.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("app:\n  name: sample\n  port:\n    rest: 99999\n"), 0o600))
+	require.NoError(t, os.WriteFile(secretFile, []byte("crypt:\n  default:\n    cipher: UNKNOWN\n"), 0o600))
+
+	var out bytes.Buffer
+	err := run([]string{"config", "validate", "-config", configFile, "-secret", secretFile}, nil, &out)
+	assert.Error(t, err)
+	assert.Equal(t, "app.port.rest is invalid on 'max=65535' rule\ncrypt.default.cipher is invalid on 'oneof' rule\n", out.String())
+
+	out.Reset()
+	require.NoError(t, os.WriteFile(configFile, []byte("app:\n  name: sample\n  port:\n    rest: 8080\n"), 0o600))
+	require.NoError(t, run([]string{"config", "validate", "-config", configFile, "-secret", ""}, nil, &out))
+	assert.Equal(t, "config is valid\n", out.String())
+}
+
+func TestConfigValidateSample(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, run([]string{
+		"config", "validate",
+		"-config", filepath.Join("..", "..", "scaffold", "sample_config.yaml"),
+		"-secret", filepath.Join("..", "..", "scaffold", "sample_secrets.yaml"),
+	}, nil, &out))
+	assert.Equal(t, "config is valid\n", out.String())
+}
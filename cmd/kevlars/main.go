@@ -0,0 +1,49 @@
+/**
+ * @Author: steven
+ * @Description: kevlars command line tools for developers
+ * @File: main
+ * @Date: 22/08/24 10.05
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `usage: kevlars <command> [arguments]
+
+commands:
+  config keygen            generate base64 key of config value encryption
+  config encrypt [value]   encrypt value into enc: prefixed ciphertext, read from stdin when value not given
+  config decrypt <value>   decrypt enc: prefixed ciphertext
+  config schema            print json schema of the configuration consumed by the scaffold
+  config validate          validate config and secret file pair against the schema
+    -config string         config file (default "config.yaml")
+    -secret string         secret file, empty to validate config file only (default "secret.yaml")
+
+key of encrypt and decrypt taken from CONFIG_ENC_KEY or CONFIG_ENC_KEY_FILE, cipher from CONFIG_ENC_CIPHER`
+
+var errUsage = errors.New(usage)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(args []string, in io.Reader, out io.Writer) error {
+	if len(args) < 1 {
+		return errUsage
+	}
+	switch args[0] {
+	case "config":
+		return runConfig(args[1:], in, out)
+	default:
+		return errUsage
+	}
+}
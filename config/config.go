@@ -8,8 +8,16 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"github.com/evorts/kevlars/common"
+	"github.com/evorts/kevlars/crypt"
 	"github.com/spf13/viper"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -40,6 +48,14 @@ type Manager interface {
 	IsSet(key string) bool
 	AllSettings() map[string]interface{}
 
+	// Watch providers which able to detect changes of its source until the context done,
+	// the merged configuration swapped on every change
+	Watch(ctx context.Context) error
+	// OnChange subscribe to the value changes of the key, including its nested keys when key is a section
+	OnChange(key string, fn ChangeFunc)
+	// Explain which provider supplied the effective value of the key, only leaf key has provenance
+	Explain(key string) (Provenance, bool)
+
 	common.Init[Manager]
 }
 
@@ -48,61 +64,83 @@ type Provider interface {
 	GetData() map[string]interface{}
 }
 
+// Watcher implemented by provider which able to detect changes of its source,
+// notify called once the provider data refreshed
+type Watcher interface {
+	Watch(ctx context.Context, notify func()) error
+}
+
+type ChangeFunc func(old, new interface{})
+
 type configManager struct {
 	providers  []Provider
-	v          *viper.Viper
+	v          atomic.Pointer[viper.Viper]
+	provenance atomic.Pointer[map[string]Provenance]
 	stringVars StringVars
+
+	reloadMu    sync.Mutex
+	subscribeMu sync.RWMutex
+	subscribers map[string][]ChangeFunc
+	onError     func(err error)
+
+	envPrefix       string
+	secretResolvers map[string]SecretResolver
+	decrypter       crypt.Decrypter
+}
+
+func (c *configManager) viper() *viper.Viper {
+	return c.v.Load()
 }
 
 func (c *configManager) UnmarshalTo(key string, to interface{}) error {
-	return c.v.UnmarshalKey(key, to)
+	return c.viper().UnmarshalKey(key, to)
 }
 
 func (c *configManager) GetBoolOrElse(key string, elseValue bool) bool {
-	if v := c.GetBool(key); v {
-		return v
+	if c.IsSet(key) {
+		return c.GetBool(key)
 	}
 	return elseValue
 }
 
 func (c *configManager) GetFloat64OrElse(key string, elseValue float64) float64 {
-	if v := c.GetFloat64(key); v > 0 {
-		return v
+	if c.IsSet(key) {
+		return c.GetFloat64(key)
 	}
 	return elseValue
 }
 
 func (c *configManager) GetIntOrElse(key string, elseValue int) int {
-	if v := c.GetInt(key); v > 0 {
-		return v
+	if c.IsSet(key) {
+		return c.GetInt(key)
 	}
 	return elseValue
 }
 
 func (c *configManager) GetIntSliceOrElse(key string, elseValue []int) []int {
-	if v := c.GetIntSlice(key); len(v) > 0 {
-		return v
+	if c.IsSet(key) {
+		return c.GetIntSlice(key)
 	}
 	return elseValue
 }
 
 func (c *configManager) GetStringOrElse(key string, elseValue string) string {
-	if v := c.GetString(key); len(v) > 0 {
-		return v
+	if c.IsSet(key) {
+		return c.GetString(key)
 	}
 	return elseValue
 }
 
 func (c *configManager) GetStringMapOrElse(key string, elseValue map[string]interface{}) map[string]interface{} {
-	if v := c.GetStringMap(key); v != nil {
-		return v
+	if c.IsSet(key) {
+		return c.GetStringMap(key)
 	}
 	return elseValue
 }
 
 func (c *configManager) GetStringMapStringOrElse(key string, elseValue map[string]string) map[string]string {
-	if v := c.GetStringMapString(key); v != nil {
-		return v
+	if c.IsSet(key) {
+		return c.GetStringMapString(key)
 	}
 	return elseValue
 }
@@ -112,6 +150,9 @@ func (c *configManager) GetMapArray(key string) []map[string]interface{} {
 }
 
 func (c *configManager) GetMapArrayOrElse(key string, elseValue []map[string]interface{}) []map[string]interface{} {
+	if !c.IsSet(key) {
+		return elseValue
+	}
 	var arrMap []map[string]interface{}
 	if err := c.UnmarshalTo(key, &arrMap); err != nil {
 		return elseValue
@@ -120,74 +161,83 @@ func (c *configManager) GetMapArrayOrElse(key string, elseValue []map[string]int
 }
 
 func (c *configManager) Get(key string) interface{} {
-	return c.v.Get(key)
+	return c.viper().Get(key)
 }
 
 func (c *configManager) GetBool(key string) bool {
-	return c.v.GetBool(key)
+	return c.viper().GetBool(key)
 }
 
 func (c *configManager) GetFloat64(key string) float64 {
-	return c.v.GetFloat64(key)
+	return c.viper().GetFloat64(key)
 }
 
 func (c *configManager) GetInt(key string) int {
-	return c.v.GetInt(key)
+	return c.viper().GetInt(key)
 }
 
 func (c *configManager) GetIntSlice(key string) []int {
-	return c.v.GetIntSlice(key)
+	return c.viper().GetIntSlice(key)
 }
 
 func (c *configManager) GetString(key string) string {
-	return c.v.GetString(key)
+	return c.viper().GetString(key)
 }
 
 func (c *configManager) GetStringMap(key string) map[string]interface{} {
-	return c.v.GetStringMap(key)
+	return c.viper().GetStringMap(key)
 }
 
 func (c *configManager) GetStringMapString(key string) map[string]string {
-	return c.v.GetStringMapString(key)
+	return c.viper().GetStringMapString(key)
 }
 
 func (c *configManager) GetStringSlice(key string) []string {
-	return c.v.GetStringSlice(key)
+	return c.viper().GetStringSlice(key)
 }
 
 func (c *configManager) GetStringSliceOrElse(key string, orElse []string) []string {
-	if v := c.v.GetStringSlice(key); v != nil {
-		return v
+	if c.IsSet(key) {
+		return c.GetStringSlice(key)
 	}
 	return orElse
 }
 
 func (c *configManager) GetTime(key string) time.Time {
-	return c.v.GetTime(key)
+	return c.viper().GetTime(key)
 }
 
 func (c *configManager) GetDuration(key string) time.Duration {
-	return c.v.GetDuration(key)
+	return c.viper().GetDuration(key)
 }
 
 func (c *configManager) GetDurationOrElse(key string, elseValue time.Duration) time.Duration {
-	d := c.v.GetDuration(key)
-	if d < 1 {
-		return elseValue
+	if c.IsSet(key) {
+		return c.GetDuration(key)
 	}
-	return d
+	return elseValue
 }
 
 func (c *configManager) IsSet(key string) bool {
-	return c.v.IsSet(key)
+	return c.viper().IsSet(key)
 }
 
 func (c *configManager) AllSettings() map[string]interface{} {
-	return c.v.AllSettings()
+	return c.viper().AllSettings()
 }
 
 func (c *configManager) Init() error {
 	c.loadEnv()
+	// decrypter of `enc:` values, not required until such value found
+	if c.decrypter == nil {
+		d, err := NewValueCrypterFromEnv()
+		switch {
+		case err == nil:
+			c.decrypter = d
+		case !errors.Is(err, ErrEncryptionKeyNotDefined):
+			return err
+		}
+	}
 	// container configuration in dev, staging or production should be first class citizen
 	ev := c.populateEnvVars()
 	switch true {
@@ -206,8 +256,9 @@ func (c *configManager) Init() error {
 				remoteProviderItem{
 					address: ev.remote.secretAddress,
 					providerItem: &providerItem{
-						name:  ev.remote.secretName,
-						ctype: ev.remote.secretType,
+						name:   ev.remote.secretName,
+						ctype:  ev.remote.secretType,
+						secret: true,
 					},
 				})...)
 	case ev.useConfig.value == UseConfigDynamic:
@@ -227,23 +278,138 @@ func (c *configManager) Init() error {
 				},
 				localProviderItem{
 					providerItem: &providerItem{
-						name:  ev.local.secretName,
-						ctype: ev.local.secretType,
+						name:   ev.local.secretName,
+						ctype:  ev.local.secretType,
+						secret: true,
 					},
 				})...)
 	}
-	c.v = viper.New()
 	for _, provider := range c.providers {
 		if err := provider.Init(); err != nil {
 			return err
 		}
-		if err := c.v.MergeConfigMap(provider.GetData()); err != nil {
+	}
+	return c.load()
+}
+
+// load merge the providers data and store it as the current configuration
+func (c *configManager) load() error {
+	v, provenance, err := c.merge()
+	if err != nil {
+		return err
+	}
+	c.v.Store(v)
+	c.provenance.Store(&provenance)
+	return nil
+}
+
+// merge providers data into new viper instance, the later provider take precedence.
+// environment variable overrides applied on top of them, then references and encrypted values resolved.
+// provenance of every key tracked along the way
+func (c *configManager) merge() (*viper.Viper, map[string]Provenance, error) {
+	v := viper.New()
+	for _, provider := range c.providers {
+		if err := v.MergeConfigMap(provider.GetData()); err != nil {
+			return nil, nil, err
+		}
+	}
+	settings := v.AllSettings()
+	overrides := applyEnvOverrides(settings, c.envPrefix, os.Environ())
+	r := &resolver{
+		ctx:       context.Background(),
+		secrets:   c.secretResolvers,
+		decrypter: c.decrypter,
+		cache:     make(map[string]string),
+	}
+	resolved, err := r.resolveValue(settings)
+	if err != nil {
+		return nil, nil, err
+	}
+	v = viper.New()
+	if err = v.MergeConfigMap(resolved.(map[string]interface{})); err != nil {
+		return nil, nil, err
+	}
+	return v, provenanceOf(c.providers, overrides, settings, v.AllSettings()), nil
+}
+
+// reload merge the latest providers data and notify subscribers of the changed keys,
+// current configuration kept when merge failed. subscribers called without holding any lock,
+// so they could subscribe or trigger another reload
+func (c *configManager) reload() {
+	notifications, err := c.swap()
+	if err != nil {
+		c.onError(err)
+		return
+	}
+	for _, n := range notifications {
+		for _, fn := range n.fns {
+			fn(n.old, n.new)
+		}
+	}
+}
+
+// changeNotification of subscribers of a changed key
+type changeNotification struct {
+	old, new interface{}
+	fns      []ChangeFunc
+}
+
+// swap the current configuration with the merged one, return notifications of the changed keys
+func (c *configManager) swap() ([]changeNotification, error) {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+	v, provenance, err := c.merge()
+	if err != nil {
+		return nil, err
+	}
+	old := c.v.Swap(v)
+	c.provenance.Store(&provenance)
+	c.subscribeMu.RLock()
+	defer c.subscribeMu.RUnlock()
+	notifications := make([]changeNotification, 0)
+	for key, fns := range c.subscribers {
+		ov, nv := old.Get(key), v.Get(key)
+		if reflect.DeepEqual(ov, nv) {
+			continue
+		}
+		notifications = append(notifications, changeNotification{
+			old: ov,
+			new: nv,
+			fns: append([]ChangeFunc{}, fns...),
+		})
+	}
+	return notifications, nil
+}
+
+func (c *configManager) Watch(ctx context.Context) error {
+	for _, provider := range c.providers {
+		w, ok := provider.(Watcher)
+		if !ok {
+			continue
+		}
+		if err := w.Watch(ctx, c.reload); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+func (c *configManager) OnChange(key string, fn ChangeFunc) {
+	c.subscribeMu.Lock()
+	defer c.subscribeMu.Unlock()
+	key = strings.ToLower(key)
+	c.subscribers[key] = append(c.subscribers[key], fn)
+}
+
+func (c *configManager) Explain(key string) (Provenance, bool) {
+	provenance := c.provenance.Load()
+	if provenance == nil {
+		return Provenance{}, false
+	}
+	p, ok := (*provenance)[strings.ToLower(key)]
+	return p, ok
+}
+
 func (c *configManager) MustInit() Manager {
 	if err := c.Init(); err != nil {
 		panic(err)
@@ -252,7 +418,14 @@ func (c *configManager) MustInit() Manager {
 }
 
 func New(opts ...common.Option[configManager]) Manager {
-	c := &configManager{providers: make([]Provider, 0)}
+	c := &configManager{
+		providers:   make([]Provider, 0),
+		subscribers: make(map[string][]ChangeFunc),
+		onError:     func(err error) {},
+		secretResolvers: map[string]SecretResolver{
+			RemoteProviderGSM.String(): NewGSMSecretResolver(),
+		},
+	}
 	for _, opt := range opts {
 		opt.Apply(c)
 	}
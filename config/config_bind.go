@@ -0,0 +1,191 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: config_bind
+ * @Date: 18/08/24 09.10
+ */
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/evorts/kevlars/common"
+	"github.com/evorts/kevlars/validation"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+type binder struct {
+	validator validation.Manager
+}
+
+// BindIssue of single config key which missing or invalid
+type BindIssue struct {
+	Key  string
+	Rule string
+}
+
+func (i BindIssue) String() string {
+	if i.Rule == "required" {
+		return i.Key + " is missing"
+	}
+	return fmt.Sprintf("%s is invalid on '%s' rule", i.Key, i.Rule)
+}
+
+// BindError report all missing and invalid keys at once, so the whole config could be fixed in one go
+type BindError struct {
+	Key    string
+	Issues []BindIssue
+}
+
+func (e *BindError) Error() string {
+	issues := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		issues = append(issues, issue.String())
+	}
+	return fmt.Sprintf("config %s: %s", e.Key, strings.Join(issues, "; "))
+}
+
+// Bind decode the config section of the key into T, fields follow the mapstructure naming.
+// value of `default` tag used when the key is not set, explicit zero value kept as is,
+// then validated by `validate` tag rules. `required` means the key is set on the config or has default,
+// thus explicit zero value, e.g. `enabled: false` or `port: 0`, satisfy it, e.g.
+//
+//	type Server struct {
+//		Port    int           `mapstructure:"port" default:"8080" validate:"min=1,max=65535"`
+//		Timeout time.Duration `mapstructure:"timeout" default:"10s"`
+//		Name    string        `mapstructure:"name" validate:"required"`
+//	}
+//	server, err := config.Bind[Server](cfg, "app.server")
+func Bind[T any](mgr Manager, key string, opts ...common.Option[binder]) (T, error) {
+	var out T
+	b := &binder{}
+	for _, opt := range opts {
+		opt.Apply(b)
+	}
+	if b.validator == nil {
+		b.validator = validation.NewValidator().MustInit()
+	}
+	defaults := make(map[string]string)
+	keys := make(map[string]string)
+	required := make(map[string]bool)
+	bindFields(reflect.TypeOf(out), "", "", defaults, keys, required)
+	v := viper.New()
+	for k, d := range defaults {
+		v.SetDefault(k, d)
+	}
+	section := mgr.AllSettings()
+	if len(key) > 0 {
+		section = mgr.GetStringMap(key)
+	}
+	if err := v.MergeConfigMap(section); err != nil {
+		return out, fmt.Errorf("config %s: %w", key, err)
+	}
+	if err := v.Unmarshal(&out); err != nil {
+		return out, fmt.Errorf("config %s: %w", key, err)
+	}
+	if reflect.ValueOf(out).Kind() != reflect.Struct {
+		return out, nil
+	}
+	bindErr := &BindError{Key: key, Issues: make([]BindIssue, 0)}
+	// validator take zero value as missing, required of the struct fields decided by the config keys instead
+	for ns := range required {
+		if !v.IsSet(keys[ns]) {
+			bindErr.Issues = append(bindErr.Issues, BindIssue{Key: joinKey(key, keys[ns]), Rule: "required"})
+		}
+	}
+	sort.Slice(bindErr.Issues, func(i, j int) bool {
+		return bindErr.Issues[i].Key < bindErr.Issues[j].Key
+	})
+	err := b.validator.ValidateStruct(&out)
+	var fieldErrors validator.ValidationErrors
+	if err != nil && !errors.As(err, &fieldErrors) {
+		return out, err
+	}
+	for _, fe := range fieldErrors {
+		// namespace prefixed by the struct name
+		_, ns, _ := strings.Cut(fe.StructNamespace(), ".")
+		if fe.Tag() == "required" && required[ns] {
+			continue
+		}
+		issueKey, ok := keys[ns]
+		if !ok {
+			issueKey = strings.ToLower(ns)
+		}
+		bindErr.Issues = append(bindErr.Issues, BindIssue{Key: joinKey(key, issueKey), Rule: fe.Tag()})
+	}
+	if len(bindErr.Issues) < 1 {
+		return out, nil
+	}
+	return out, bindErr
+}
+
+// MustBind panic when the config section failed to bind, meant for startup
+func MustBind[T any](mgr Manager, key string, opts ...common.Option[binder]) T {
+	out, err := Bind[T](mgr, key, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// BindWithValidator use the given validator instead of the default one, e.g. with custom rules registered
+func BindWithValidator(v validation.Manager) common.Option[binder] {
+	return common.OptionFunc[binder](func(b *binder) {
+		b.validator = v
+	})
+}
+
+// bindFields walk the struct fields, collect default value, config key and whether required of each field namespace
+func bindFields(t reflect.Type, keyPrefix, nsPrefix string, defaults, keys map[string]string, required map[string]bool) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, tagOpts, _ := strings.Cut(f.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+		ns := joinKey(nsPrefix, f.Name)
+		if f.Anonymous && strings.Contains(tagOpts, "squash") {
+			bindFields(f.Type, keyPrefix, ns, defaults, keys, required)
+			continue
+		}
+		if len(name) < 1 {
+			name = f.Name
+		}
+		key := joinKey(keyPrefix, strings.ToLower(name))
+		keys[ns] = key
+		if d, ok := f.Tag.Lookup("default"); ok {
+			defaults[key] = d
+		}
+		for _, rule := range strings.Split(f.Tag.Get("validate"), ",") {
+			if strings.TrimSpace(rule) == "required" {
+				required[ns] = true
+			}
+		}
+		bindFields(f.Type, key, ns, defaults, keys, required)
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if len(prefix) < 1 {
+		return key
+	}
+	if len(key) < 1 {
+		return prefix
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,97 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: config_bind_test
+ * @Date: 18/08/24 09.40
+ */
+
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type bindServer struct {
+	Name    string        `mapstructure:"name" validate:"required"`
+	Port    int           `mapstructure:"port" default:"8080" validate:"min=1,max=65535"`
+	Debug   bool          `mapstructure:"debug" default:"true"`
+	Retry   int           `mapstructure:"retry" default:"3"`
+	Timeout time.Duration `mapstructure:"timeout" default:"10s"`
+	Hosts   []string      `mapstructure:"hosts" default:"a,b"`
+	DB      struct {
+		Host string `mapstructure:"host" validate:"required"`
+	} `mapstructure:"db"`
+}
+
+func newBindTestManager(t *testing.T, yaml string) Manager {
+	return newTestManager(t, []Provider{NewLocalStringVar("bind", TypeYaml, yaml)})
+}
+
+func TestBind(t *testing.T) {
+	c := newBindTestManager(t, `
+app:
+  server:
+    name: api
+    debug: false
+    retry: 0
+    db:
+      host: localhost
+`)
+	server, err := Bind[bindServer](c, "app.server")
+	require.NoError(t, err)
+	assert.Equal(t, "api", server.Name)
+	assert.Equal(t, 8080, server.Port)
+	assert.False(t, server.Debug, "explicit false should not be replaced by default")
+	assert.Equal(t, 0, server.Retry, "explicit zero should not be replaced by default")
+	assert.Equal(t, 10*time.Second, server.Timeout)
+	assert.Equal(t, []string{"a", "b"}, server.Hosts)
+	assert.Equal(t, "localhost", server.DB.Host)
+
+	assert.False(t, c.GetBoolOrElse("app.server.debug", true))
+	assert.Equal(t, 0, c.GetIntOrElse("app.server.retry", 3))
+	assert.Equal(t, 3, c.GetIntOrElse("app.server.unknown", 3))
+}
+
+func TestBindReportAllIssues(t *testing.T) {
+	c := newBindTestManager(t, `
+app:
+  server:
+    port: 70000
+`)
+	_, err := Bind[bindServer](c, "app.server")
+	var bindErr *BindError
+	require.ErrorAs(t, err, &bindErr)
+	assert.ElementsMatch(t, []BindIssue{
+		{Key: "app.server.name", Rule: "required"},
+		{Key: "app.server.port", Rule: "max"},
+		{Key: "app.server.db.host", Rule: "required"},
+	}, bindErr.Issues)
+	assert.Panics(t, func() {
+		MustBind[bindServer](c, "app.server")
+	})
+}
+
+func TestBindRequiredExplicitZero(t *testing.T) {
+	type toggle struct {
+		Enabled bool   `mapstructure:"enabled" validate:"required"`
+		Port    int    `mapstructure:"port" validate:"required"`
+		Mode    string `mapstructure:"mode" default:"release" validate:"required"`
+	}
+	c := newBindTestManager(t, `feature: {enabled: false, port: 0}`)
+	rs, err := Bind[toggle](c, "feature")
+	require.NoError(t, err)
+	assert.False(t, rs.Enabled)
+	assert.Equal(t, 0, rs.Port)
+	assert.Equal(t, "release", rs.Mode)
+
+	_, err = Bind[toggle](c, "other")
+	var bindErr *BindError
+	require.ErrorAs(t, err, &bindErr)
+	assert.Equal(t, []BindIssue{
+		{Key: "other.enabled", Rule: "required"},
+		{Key: "other.port", Rule: "required"},
+	}, bindErr.Issues)
+}
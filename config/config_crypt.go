@@ -0,0 +1,82 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: config_crypt
+ * @Date: 22/08/24 09.20
+ */
+
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/evorts/kevlars/crypt"
+	"os"
+	"strings"
+)
+
+// EncryptedPrefix mark config value as ciphertext, e.g. `password: "enc:dGhpcyBpcyBjaXBoZXI..."`,
+// the rest of the value is base64 of the ciphertext produced by the value crypter
+const EncryptedPrefix = "enc:"
+
+// defaultValueCipher has random nonce, thus encrypting the same value twice produce different ciphertext
+const defaultValueCipher = crypt.CipherXChaCha20
+
+// IsEncrypted value of EncryptedPrefix
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, EncryptedPrefix)
+}
+
+// EncryptValue into `enc:<base64 ciphertext>` which safe to be committed along the config file
+func EncryptValue(e crypt.Encrypter, plain string) (string, error) {
+	rs, err := e.Encrypt([]byte(plain))
+	if err != nil {
+		return "", err
+	}
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(rs), nil
+}
+
+// DecryptValue of `enc:<base64 ciphertext>`, value without the prefix returned as is
+func DecryptValue(d crypt.Decrypter, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(strings.TrimPrefix(value, EncryptedPrefix)))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrEncryptedValueInvalid, err.Error())
+	}
+	rs, err := d.Decrypt(raw)
+	if err != nil {
+		return "", err
+	}
+	return rs.String(), nil
+}
+
+// NewValueCrypterFromEnv of base64 key on CONFIG_ENC_KEY, or on the file of CONFIG_ENC_KEY_FILE
+// which could be mounted by kms or secret manager. cipher follow CONFIG_ENC_CIPHER, default to XChaCha20-Poly1305
+func NewValueCrypterFromEnv() (crypt.Manager, error) {
+	encoded := os.Getenv(EnvKeyEncryptionKey.String())
+	if file := os.Getenv(EnvKeyEncryptionKeyFile.String()); len(encoded) < 1 && len(file) > 0 {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(raw)
+	}
+	if len(strings.TrimSpace(encoded)) < 1 {
+		return nil, ErrEncryptionKeyNotDefined
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrEncryptionKeyInvalid, err.Error())
+	}
+	cipher := defaultValueCipher
+	if v := os.Getenv(EnvKeyEncryptionCipher.String()); len(v) > 0 {
+		cipher = crypt.Cipher(strings.ToUpper(v))
+	}
+	m := crypt.New(crypt.WithCipher(cipher), crypt.WithKey(key))
+	if err = m.Init(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
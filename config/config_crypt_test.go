@@ -0,0 +1,43 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: config_crypt_test
+ * @Date: 22/08/24 10.25
+ */
+
+package config
+
+import (
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEncryptedValues(t *testing.T) {
+	t.Setenv(EnvKeyEncryptionKey.String(), base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")))
+	c, err := NewValueCrypterFromEnv()
+	require.NoError(t, err)
+	encrypted, err := EncryptValue(c, "p@ss")
+	require.NoError(t, err)
+	other, err := EncryptValue(c, "p@ss")
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted, other)
+
+	p := NewLocalStringVar("encrypted", TypeYaml, `db: {user: app, password: "`+encrypted+`"}`)
+	cm := newTestManager(t, []Provider{p}, WithDecrypter(c))
+	assert.Equal(t, "p@ss", cm.GetString("db.password"))
+	prov, ok := cm.Explain("db.password")
+	require.True(t, ok)
+	assert.True(t, prov.Encrypted)
+	redacted := Redact(cm)
+	assert.Equal(t, RedactedValue, redacted["db"].(map[string]interface{})["password"])
+	assert.Equal(t, "app", redacted["db"].(map[string]interface{})["user"])
+
+	cm.decrypter = nil
+	_, _, err = cm.merge()
+	assert.ErrorIs(t, err, ErrDecrypterNotDefined)
+
+	_, err = DecryptValue(c, EncryptedPrefix+"not base64!")
+	assert.ErrorIs(t, err, ErrEncryptedValueInvalid)
+}
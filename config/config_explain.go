@@ -0,0 +1,190 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: config_explain
+ * @Date: 21/08/24 10.15
+ */
+
+package config
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	// RedactedValue replace the value of secret or masked key on the redacted settings
+	RedactedValue = "********"
+
+	sourceEnv     = "env"
+	sourceUnknown = "unknown"
+)
+
+// defaultMaskedKeys always redacted regardless of the given masked fields, matched as part of any key segment,
+// e.g. `key` cover `api_keys`, `key_file` and `keys`
+var defaultMaskedKeys = []string{"pass", "pwd", "secret", "token", "key", "dsn", "private", "credential"}
+
+// Provenance of the effective value of a key, which provider supplied it and which ones it overrides
+type Provenance struct {
+	Key string `json:"key"`
+	// Source of the effective value, e.g. `file:/etc/app/config.yaml`, `vault:secret/app/config` or `env:APP__NAME`
+	Source string `json:"source"`
+	// Secret when the value supplied by secret provider
+	Secret bool `json:"secret"`
+	// Overrides sources of the same key which merged earlier, in merge order
+	Overrides []string `json:"overrides,omitempty"`
+	// Reference of the value before resolved, e.g. `${secret:gsm/projects/x/secrets/y}`
+	Reference string `json:"reference,omitempty"`
+	// Encrypted when the value is `enc:` prefixed ciphertext
+	Encrypted bool `json:"encrypted,omitempty"`
+}
+
+// sourcer implemented by provider to describe where its data come from
+type sourcer interface {
+	Source() string
+}
+
+// secretProvider mark data of the wrapped provider as secret, thus redacted when dumped
+type secretProvider struct {
+	Provider
+}
+
+func (s *secretProvider) Source() string {
+	return providerSource(s.Provider)
+}
+
+func (s *secretProvider) Watch(ctx context.Context, notify func()) error {
+	if w, ok := s.Provider.(Watcher); ok {
+		return w.Watch(ctx, notify)
+	}
+	return nil
+}
+
+func asSecret(p Provider) Provider {
+	return &secretProvider{Provider: p}
+}
+
+func providerSource(p Provider) string {
+	if s, ok := p.(sourcer); ok {
+		return s.Source()
+	}
+	return sourceUnknown
+}
+
+// flattenSettings into dotted leaf keys, slices taken as leaf
+func flattenSettings(prefix string, settings map[string]interface{}, out map[string]interface{}) {
+	for k, v := range settings {
+		key := joinKey(prefix, strings.ToLower(k))
+		if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+			flattenSettings(key, m, out)
+			continue
+		}
+		out[key] = v
+	}
+}
+
+// provenanceOf every leaf key of the effective settings, following the merge order of the providers
+// then the environment variable overrides. raw settings are the merged ones prior to references resolved
+func provenanceOf(providers []Provider, envOverrides map[string]string, raw, effective map[string]interface{}) map[string]Provenance {
+	track := make(map[string]Provenance)
+	record := func(key, source string, secret bool) {
+		p, ok := track[key]
+		if ok {
+			p.Overrides = append(p.Overrides, p.Source)
+		}
+		// secret is sticky, key supplied by secret provider stay redacted even when overridden later, e.g. by env
+		p.Key, p.Source, p.Secret = key, source, p.Secret || secret
+		track[key] = p
+	}
+	for _, provider := range providers {
+		_, secret := provider.(*secretProvider)
+		source := providerSource(provider)
+		leaves := make(map[string]interface{})
+		flattenSettings("", provider.GetData(), leaves)
+		for key := range leaves {
+			record(key, source, secret)
+		}
+	}
+	for key, name := range envOverrides {
+		record(key, sourceEnv+":"+name, false)
+	}
+	rawLeaves := make(map[string]interface{})
+	flattenSettings("", raw, rawLeaves)
+	leaves := make(map[string]interface{})
+	flattenSettings("", effective, leaves)
+	rs := make(map[string]Provenance, len(leaves))
+	for key := range leaves {
+		p, ok := track[key]
+		if !ok {
+			// leaf of section replaced by the later provider, e.g. `a.b` overridden by scalar `a`
+			p = Provenance{Key: key, Source: sourceUnknown}
+		}
+		if s, isString := rawLeaves[key].(string); isString {
+			p.Encrypted = IsEncrypted(s)
+			if !p.Encrypted && referencePattern.MatchString(s) {
+				p.Reference = s
+			}
+		}
+		rs[key] = p
+	}
+	return rs
+}
+
+// Redact the effective settings for display, value supplied by secret provider, encrypted or which key
+// matched the masked fields replaced by RedactedValue. resolved references displayed as its reference instead.
+// masked field is case-insensitive and matched as part of any segment of the key, while the dotted one
+// is key pattern redacting the whole subtree, `*` match any segment, e.g. `dbs.*.dsn` or Schema.SecretKeys
+func Redact(mgr Manager, maskedFields ...string) map[string]interface{} {
+	fields := make([]string, 0, len(defaultMaskedKeys)+len(maskedFields))
+	patterns := make([][]string, 0)
+	for _, f := range append(append([]string{}, defaultMaskedKeys...), maskedFields...) {
+		f = strings.ToLower(f)
+		if strings.Contains(f, ".") {
+			patterns = append(patterns, strings.Split(f, "."))
+			continue
+		}
+		fields = append(fields, f)
+	}
+	leaves := make(map[string]interface{})
+	flattenSettings("", mgr.AllSettings(), leaves)
+	rs := make(map[string]interface{})
+	for key, value := range leaves {
+		p, _ := mgr.Explain(key)
+		switch {
+		case p.Secret || p.Encrypted || isMaskedKey(key, fields, patterns):
+			value = RedactedValue
+		case len(p.Reference) > 0:
+			value = p.Reference
+		}
+		expandNestedKey(rs, key, value)
+	}
+	return rs
+}
+
+// isMaskedKey when any segment of the key contain one of the fields, or the key is under one of the patterns
+func isMaskedKey(key string, fields []string, patterns [][]string) bool {
+	segments := strings.Split(key, ".")
+	for _, segment := range segments {
+		for _, f := range fields {
+			if strings.Contains(segment, f) {
+				return true
+			}
+		}
+	}
+	for _, pattern := range patterns {
+		if len(pattern) > len(segments) {
+			continue
+		}
+		matched := true
+		for i, segment := range pattern {
+			if segment != "*" && segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
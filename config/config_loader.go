@@ -11,6 +11,7 @@ import (
 	"github.com/evorts/kevlars/rules/eval"
 	"github.com/joho/godotenv"
 	"os"
+	"strings"
 )
 
 func (c *configManager) loadEnv() {
@@ -116,79 +117,69 @@ func (c *configManager) populateEnvVars() envVars {
 			}(),
 		},
 		dynamic: dynamicVarItem{
-			localStringVars: localVarItem{
-				providerName: CProvider(LocalProviderStringVar),
-			},
-			localFile: localVarItem{
-				providerName: CProvider(LocalProviderFile),
-			},
-			remoteDB: remoteVarItem{
-				providerName: CProvider(RemoteProviderDB),
-			},
-			remoteGSM: remoteVarItem{
-				providerName: CProvider(RemoteProviderGSM),
-			},
-			remoteConsul: remoteVarItem{
-				providerName: CProvider(RemoteProviderConsul),
-			},
+			localStringVars: dynamicLocalVarItem(CProvider(LocalProviderStringVar)),
+			localFile:       dynamicLocalVarItem(CProvider(LocalProviderFile)),
+			localK8s:        dynamicLocalVarItem(CProvider(LocalProviderK8s)),
+			remoteDB:        dynamicRemoteVarItem(CProvider(RemoteProviderDB)),
+			remoteGSM:       dynamicRemoteVarItem(CProvider(RemoteProviderGSM)),
+			remoteConsul:    dynamicRemoteVarItem(CProvider(RemoteProviderConsul)),
+			remoteVault:     dynamicRemoteVarItem(CProvider(RemoteProviderVault)),
 		},
 	}
-	// fill out dynamic value
-	ev.dynamic.localStringVars = localVarItem{
-		providerName: ev.dynamic.localStringVars.providerName,
-		configName:   EnvKeyPatternConfigLocal.String(ev.dynamic.localStringVars.providerName.String(), EnvContextName),
-		configType:   Type(EnvKeyPatternConfigLocal.String(ev.dynamic.localStringVars.providerName.String(), EnvContextType)),
-		secretName:   EnvKeyPatternSecretLocal.String(ev.dynamic.localStringVars.providerName.String(), EnvContextName),
-		secretType:   Type(EnvKeyPatternSecretLocal.String(ev.dynamic.localStringVars.providerName.String(), EnvContextType)),
-	}
-	ev.dynamic.localFile = localVarItem{
-		providerName: ev.dynamic.localFile.providerName,
-		configName:   EnvKeyPatternConfigLocal.String(ev.dynamic.localFile.providerName.String(), EnvContextName),
-		configType:   Type(EnvKeyPatternConfigLocal.String(ev.dynamic.localFile.providerName.String(), EnvContextType)),
-		secretName:   EnvKeyPatternSecretLocal.String(ev.dynamic.localFile.providerName.String(), EnvContextName),
-		secretType:   Type(EnvKeyPatternSecretLocal.String(ev.dynamic.localFile.providerName.String(), EnvContextType)),
-	}
-	ev.dynamic.remoteDB = remoteVarItem{
-		providerName:  ev.dynamic.remoteDB.providerName,
-		configAddress: EnvKeyPatternConfigRemote.String(ev.dynamic.remoteDB.providerName.String(), EnvContextAddress),
-		configName:    EnvKeyPatternConfigRemote.String(ev.dynamic.remoteDB.providerName.String(), EnvContextName),
-		configType:    Type(EnvKeyPatternConfigRemote.String(ev.dynamic.remoteDB.providerName.String(), EnvContextType)),
-		secretAddress: EnvKeyPatternSecretRemote.String(ev.dynamic.remoteDB.providerName.String(), EnvContextAddress),
-		secretName:    EnvKeyPatternSecretRemote.String(ev.dynamic.remoteDB.providerName.String(), EnvContextName),
-		secretType:    Type(EnvKeyPatternSecretRemote.String(ev.dynamic.remoteDB.providerName.String(), EnvContextType)),
+	return ev
+}
+
+func envType(key string) Type {
+	if v := os.Getenv(key); len(v) > 0 {
+		return Type(v)
 	}
-	ev.dynamic.remoteGSM = remoteVarItem{
-		providerName:  ev.dynamic.remoteGSM.providerName,
-		configAddress: EnvKeyPatternConfigRemote.String(ev.dynamic.remoteGSM.providerName.String(), EnvContextAddress),
-		configName:    EnvKeyPatternConfigRemote.String(ev.dynamic.remoteGSM.providerName.String(), EnvContextName),
-		configType:    Type(EnvKeyPatternConfigRemote.String(ev.dynamic.remoteGSM.providerName.String(), EnvContextType)),
-		secretAddress: EnvKeyPatternSecretRemote.String(ev.dynamic.remoteGSM.providerName.String(), EnvContextAddress),
-		secretName:    EnvKeyPatternSecretRemote.String(ev.dynamic.remoteGSM.providerName.String(), EnvContextName),
-		secretType:    Type(EnvKeyPatternSecretRemote.String(ev.dynamic.remoteGSM.providerName.String(), EnvContextType)),
+	return TypeYaml
+}
+
+// dynamicLocalVarItem of the provider from env, e.g. CONFIG_LOCAL_FILE_NAME and SECRET_LOCAL_FILE_TYPE
+func dynamicLocalVarItem(provider CProvider) localVarItem {
+	p := strings.ToUpper(provider.String())
+	return localVarItem{
+		providerName: provider,
+		configName:   os.Getenv(EnvKeyPatternConfigLocal.String(p, EnvContextName)),
+		configType:   envType(EnvKeyPatternConfigLocal.String(p, EnvContextType)),
+		secretName:   os.Getenv(EnvKeyPatternSecretLocal.String(p, EnvContextName)),
+		secretType:   envType(EnvKeyPatternSecretLocal.String(p, EnvContextType)),
 	}
-	ev.dynamic.remoteConsul = remoteVarItem{
-		providerName:  ev.dynamic.remoteConsul.providerName,
-		configAddress: EnvKeyPatternConfigRemote.String(ev.dynamic.remoteConsul.providerName.String(), EnvContextAddress),
-		configName:    EnvKeyPatternConfigRemote.String(ev.dynamic.remoteConsul.providerName.String(), EnvContextName),
-		configType:    Type(EnvKeyPatternConfigRemote.String(ev.dynamic.remoteConsul.providerName.String(), EnvContextType)),
-		secretAddress: EnvKeyPatternSecretRemote.String(ev.dynamic.remoteConsul.providerName.String(), EnvContextAddress),
-		secretName:    EnvKeyPatternSecretRemote.String(ev.dynamic.remoteConsul.providerName.String(), EnvContextName),
-		secretType:    Type(EnvKeyPatternSecretRemote.String(ev.dynamic.remoteConsul.providerName.String(), EnvContextType)),
+}
+
+// dynamicRemoteVarItem of the provider from env, e.g. CONFIG_REMOTE_GSM_ADDR and SECRET_REMOTE_GSM_NAME
+func dynamicRemoteVarItem(provider CProvider) remoteVarItem {
+	p := strings.ToUpper(provider.String())
+	return remoteVarItem{
+		providerName:  provider,
+		configAddress: os.Getenv(EnvKeyPatternConfigRemote.String(p, EnvContextAddress)),
+		configName:    os.Getenv(EnvKeyPatternConfigRemote.String(p, EnvContextName)),
+		configType:    envType(EnvKeyPatternConfigRemote.String(p, EnvContextType)),
+		secretAddress: os.Getenv(EnvKeyPatternSecretRemote.String(p, EnvContextAddress)),
+		secretName:    os.Getenv(EnvKeyPatternSecretRemote.String(p, EnvContextName)),
+		secretType:    envType(EnvKeyPatternSecretRemote.String(p, EnvContextType)),
 	}
-	return ev
 }
 
 func (c *configManager) loadRemoteProvider(provider RemoteProvider, items ...remoteProviderItem) []Provider {
 	rs := make([]Provider, 0)
 	for _, item := range items {
+		var p Provider
 		switch true {
 		case provider == RemoteProviderGSM:
-			rs = append(rs, NewRemoteGSM(item.address, item.name, item.ctype))
+			p = NewRemoteGSM(item.address, item.name, item.ctype)
 		case provider == RemoteProviderDB:
-			rs = append(rs, NewRemoteDB([]string{item.name}))
+			p = NewRemoteDB(item.address, []string{item.name})
+		case provider == RemoteProviderVault:
+			p = NewRemoteVault(item.address, item.name)
 		default:
-			rs = append(rs, NewRemote(provider.String(), item.address, item.name, item.ctype))
+			p = NewRemote(provider.String(), item.address, item.name, item.ctype)
 		}
+		if item.secret {
+			p = asSecret(p)
+		}
+		rs = append(rs, p)
 	}
 	return rs
 }
@@ -196,14 +187,22 @@ func (c *configManager) loadRemoteProvider(provider RemoteProvider, items ...rem
 func (c *configManager) loadLocalProvider(provider LocalProvider, items ...localProviderItem) []Provider {
 	rs := make([]Provider, 0)
 	for _, item := range items {
+		var p Provider
 		switch true {
 		case provider == LocalProviderStringVar:
 			for _, sv := range item.stringVars {
 				rs = append(rs, NewLocalStringVar(sv.Name, item.ctype, sv.Value))
 			}
+			continue
+		case provider == LocalProviderK8s:
+			p = NewLocalMountedDir(item.name)
 		default:
-			rs = append(rs, NewLocalFile(item.name, item.ctype))
+			p = NewLocalFile(item.name, item.ctype)
+		}
+		if item.secret {
+			p = asSecret(p)
 		}
+		rs = append(rs, p)
 	}
 	return rs
 }
@@ -235,19 +234,23 @@ func (c *configManager) loadDynamicProviders(ev envVars) []Provider {
 			) {
 				secrets = append(secrets, NewLocalFile(ev.dynamic.localFile.secretName, ev.dynamic.localFile.secretType))
 			}
+		case CProvider(LocalProviderK8s):
+			if len(ev.dynamic.localK8s.configName) > 0 {
+				configs = append(configs, NewLocalMountedDir(ev.dynamic.localK8s.configName))
+			}
+			if len(ev.dynamic.localK8s.secretName) > 0 {
+				secrets = append(secrets, NewLocalMountedDir(ev.dynamic.localK8s.secretName))
+			}
 		case CProvider(RemoteProviderDB):
 			// evaluate config from db
-			if eval.AND(
-				len(ev.dynamic.remoteDB.configName) > 0,
-				len(ev.dynamic.remoteDB.configAddress) > 0,
-			) {
+			if address := ev.dynamic.remoteDB.configAddress; len(address) > 0 {
 				prefix := func() string {
 					if v := os.Getenv(EnvKeyConfigRemotePrefix.String()); len(v) > 0 {
 						return v
 					}
 					return ""
 				}()
-				configs = append(configs, NewRemoteDB([]string{prefix}))
+				configs = append(configs, NewRemoteDB(address, []string{prefix}))
 			}
 			// should never store secret in DB, thus will not be evaluated
 		case CProvider(RemoteProviderGSM):
@@ -294,7 +297,30 @@ func (c *configManager) loadDynamicProviders(ev envVars) []Provider {
 					ev.dynamic.remoteConsul.secretType,
 				))
 			}
+		case CProvider(RemoteProviderVault):
+			if eval.AND(
+				len(ev.dynamic.remoteVault.configName) > 0,
+				len(ev.dynamic.remoteVault.configAddress) > 0,
+			) {
+				configs = append(configs, NewRemoteVault(
+					ev.dynamic.remoteVault.configAddress,
+					ev.dynamic.remoteVault.configName,
+				))
+			}
+			if eval.AND(
+				len(ev.dynamic.remoteVault.secretName) > 0,
+				len(ev.dynamic.remoteVault.secretAddress) > 0,
+			) {
+				secrets = append(secrets, NewRemoteVault(
+					ev.dynamic.remoteVault.secretAddress,
+					ev.dynamic.remoteVault.secretName,
+				))
+			}
 		}
 	}
+	// secrets marked, thus redacted when the configuration dumped
+	for i, p := range secrets {
+		secrets[i] = asSecret(p)
+	}
 	return append(configs, secrets...)
 }
@@ -8,8 +8,9 @@
 package config
 
 type providerItem struct {
-	name  string
-	ctype Type
+	name   string
+	ctype  Type
+	secret bool
 }
 type remoteProviderItem struct {
 	address string
@@ -52,9 +53,11 @@ type localVarItem struct {
 type dynamicVarItem struct {
 	localStringVars localVarItem
 	localFile       localVarItem
+	localK8s        localVarItem
 	remoteDB        remoteVarItem
 	remoteGSM       remoteVarItem
 	remoteConsul    remoteVarItem
+	remoteVault     remoteVarItem
 }
 
 type envVars struct {
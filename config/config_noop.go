@@ -8,6 +8,7 @@
 package config
 
 import (
+	"context"
 	"time"
 )
 
@@ -85,6 +86,10 @@ func (n *noop) GetMapArray(key string) []map[string]interface{} {
 	return make([]map[string]interface{}, 0)
 }
 
+func (n *noop) GetMapArrayOrElse(key string, orElse []map[string]interface{}) []map[string]interface{} {
+	return orElse
+}
+
 func (n *noop) GetTime(key string) time.Time {
 	return time.Time{}
 }
@@ -109,6 +114,16 @@ func (n *noop) AllSettings() map[string]interface{} {
 	return map[string]interface{}{}
 }
 
+func (n *noop) Watch(ctx context.Context) error {
+	return nil
+}
+
+func (n *noop) OnChange(key string, fn ChangeFunc) {}
+
+func (n *noop) Explain(key string) (Provenance, bool) {
+	return Provenance{}, false
+}
+
 func (n *noop) Init() error {
 	return nil
 }
@@ -7,10 +7,43 @@
 
 package config
 
-import "github.com/evorts/kevlars/common"
+import (
+	"github.com/evorts/kevlars/common"
+	"github.com/evorts/kevlars/crypt"
+	"strings"
+)
 
 func WithStringVar(s StringVars) common.Option[configManager] {
 	return common.OptionFunc[configManager](func(c *configManager) {
 		c.stringVars = s
 	})
 }
+
+// WithOnError handle errors while reloading changes of the watched providers
+func WithOnError(fn func(err error)) common.Option[configManager] {
+	return common.OptionFunc[configManager](func(c *configManager) {
+		c.onError = fn
+	})
+}
+
+// WithEnvOverridePrefix only consider environment variables with the prefix as overrides,
+// e.g. prefix MYAPP take MYAPP__APP__LOG__LEVEL as override of `app.log.level`
+func WithEnvOverridePrefix(prefix string) common.Option[configManager] {
+	return common.OptionFunc[configManager](func(c *configManager) {
+		c.envPrefix = prefix
+	})
+}
+
+// WithDecrypter of `enc:` prefixed values, instead of the one built from CONFIG_ENC_KEY
+func WithDecrypter(d crypt.Decrypter) common.Option[configManager] {
+	return common.OptionFunc[configManager](func(c *configManager) {
+		c.decrypter = d
+	})
+}
+
+// WithSecretResolver of `${secret:<scheme>/<path>}` references, google secret manager resolver registered as `gsm`
+func WithSecretResolver(scheme string, r SecretResolver) common.Option[configManager] {
+	return common.OptionFunc[configManager](func(c *configManager) {
+		c.secretResolvers[strings.ToLower(scheme)] = r
+	})
+}
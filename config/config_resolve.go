@@ -0,0 +1,203 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: config_resolve
+ * @Date: 19/08/24 08.25
+ */
+
+package config
+
+import (
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"context"
+	"fmt"
+	"github.com/evorts/kevlars/crypt"
+	"google.golang.org/api/option"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SecretResolver resolve the secret reference of `${secret:<scheme>/<path>}` into its value
+type SecretResolver interface {
+	Resolve(ctx context.Context, path string) (string, error)
+}
+
+const (
+	referenceEnv    = "env"
+	referenceSecret = "secret"
+	referenceFile   = "file"
+
+	envOverrideSeparator = "__"
+)
+
+// referencePattern match `${ENV:NAME}`, `${secret:gsm/projects/x/secrets/y}` and `${file:/run/secrets/z}`
+var referencePattern = regexp.MustCompile(`\$\{(?i:(env|secret|file)):([^}]+)}`)
+
+// applyEnvOverrides put every environment variable with double underscore separator into the settings,
+// e.g. APP_SECTION__KEY override `app_section.key`. when prefix defined, only MYAPP__APP_SECTION__KEY considered.
+// name with empty segment or starting with underscore, e.g. __CF_USER_TEXT_ENCODING of the os, never taken as override.
+// return the overridden keys along with the environment variable name
+func applyEnvOverrides(settings map[string]interface{}, prefix string, environ []string) map[string]string {
+	overrides := make(map[string]string)
+	for _, env := range environ {
+		envName, value, found := strings.Cut(env, "=")
+		name := envName
+		if !found {
+			continue
+		}
+		if len(prefix) > 0 {
+			if !strings.HasPrefix(name, prefix+envOverrideSeparator) {
+				continue
+			}
+			name = strings.TrimPrefix(name, prefix+envOverrideSeparator)
+		}
+		if !isEnvOverrideName(name) {
+			continue
+		}
+		key := strings.ToLower(strings.ReplaceAll(name, envOverrideSeparator, "."))
+		expandNestedKey(settings, key, value)
+		overrides[key] = envName
+	}
+	return overrides
+}
+
+// isEnvOverrideName when it has at least two segments, each not empty nor starting or ending with underscore
+func isEnvOverrideName(name string) bool {
+	segments := strings.Split(name, envOverrideSeparator)
+	if len(segments) < 2 {
+		return false
+	}
+	for _, segment := range segments {
+		if len(segment) < 1 || strings.HasPrefix(segment, "_") || strings.HasSuffix(segment, "_") {
+			return false
+		}
+	}
+	return true
+}
+
+// resolver of references on config values, resolved secret cached for single pass only,
+// thus reload always pick up the latest value
+type resolver struct {
+	ctx       context.Context
+	secrets   map[string]SecretResolver
+	decrypter crypt.Decrypter
+	cache     map[string]string
+}
+
+// resolveValue into new copy, since collections could be shared with the providers data
+// which has to keep the references for the next reload
+func (r *resolver) resolveValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return r.resolveString(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			resolved, err := r.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolved
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := r.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	default:
+		return value, nil
+	}
+}
+
+func (r *resolver) resolveString(value string) (string, error) {
+	// ciphertext never contain reference, decrypted value taken as is
+	if IsEncrypted(value) {
+		if r.decrypter == nil {
+			return "", ErrDecrypterNotDefined
+		}
+		return DecryptValue(r.decrypter, value)
+	}
+	var resolveErr error
+	resolved := referencePattern.ReplaceAllStringFunc(value, func(ref string) string {
+		if resolveErr != nil {
+			return ref
+		}
+		if v, ok := r.cache[ref]; ok {
+			return v
+		}
+		m := referencePattern.FindStringSubmatch(ref)
+		v, err := r.resolveReference(strings.ToLower(m[1]), strings.TrimSpace(m[2]))
+		if err != nil {
+			resolveErr = fmt.Errorf("resolve %s: %w", ref, err)
+			return ref
+		}
+		r.cache[ref] = v
+		return v
+	})
+	return resolved, resolveErr
+}
+
+func (r *resolver) resolveReference(kind, path string) (string, error) {
+	switch kind {
+	case referenceEnv:
+		v, ok := os.LookupEnv(path)
+		if !ok {
+			return "", ErrReferenceNotFound
+		}
+		return v, nil
+	case referenceFile:
+		v, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(v), "\r\n"), nil
+	case referenceSecret:
+		scheme, secretPath, _ := strings.Cut(path, "/")
+		sr, ok := r.secrets[strings.ToLower(scheme)]
+		if !ok {
+			return "", ErrSecretResolverNotDefined
+		}
+		return sr.Resolve(r.ctx, secretPath)
+	default:
+		return "", ErrSecretResolverNotDefined
+	}
+}
+
+type gsmSecretResolver struct {
+	opts   []option.ClientOption
+	once   sync.Once
+	client *secretmanager.Client
+	err    error
+}
+
+// Resolve secret of `projects/*/secrets/*` path on its latest version, or `projects/*/secrets/*/versions/*`
+func (g *gsmSecretResolver) Resolve(ctx context.Context, path string) (string, error) {
+	g.once.Do(func() {
+		g.client, g.err = secretmanager.NewClient(context.Background(), g.opts...)
+	})
+	if g.err != nil {
+		return "", g.err
+	}
+	if !strings.Contains(path, "/versions/") {
+		path += "/versions/latest"
+	}
+	result, err := g.client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{Name: path})
+	if err != nil {
+		return "", err
+	}
+	return string(result.Payload.Data), nil
+}
+
+// NewGSMSecretResolver of google secret manager reference, e.g. `${secret:gsm/projects/x/secrets/y}`
+func NewGSMSecretResolver(opts ...option.ClientOption) SecretResolver {
+	return &gsmSecretResolver{opts: opts}
+}
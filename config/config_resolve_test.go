@@ -0,0 +1,77 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: config_resolve_test
+ * @Date: 19/08/24 09.05
+ */
+
+package config
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type staticSecretResolver map[string]string
+
+func (s staticSecretResolver) Resolve(ctx context.Context, path string) (string, error) {
+	return s[path], nil
+}
+
+func TestApplyEnvOverrides(t *testing.T) {
+	settings := map[string]interface{}{}
+	overrides := applyEnvOverrides(settings, "", []string{
+		"APP__LOG__LEVEL=5",
+		"__CF_USER_TEXT_ENCODING=0x1F5:0x0:0x0",
+		"_APP__NAME=hidden",
+		"APP____NAME=empty",
+		"APP___NAME=underscore",
+		"APP__=trailing",
+		"PATH=/usr/bin",
+	})
+	assert.Equal(t, map[string]string{"app.log.level": "APP__LOG__LEVEL"}, overrides)
+	assert.Equal(t, map[string]interface{}{"app": map[string]interface{}{"log": map[string]interface{}{"level": "5"}}}, settings)
+	// only prefixed one considered once prefix defined
+	settings = map[string]interface{}{}
+	overrides = applyEnvOverrides(settings, "MYAPP", []string{"APP__LOG__LEVEL=5", "MYAPP__APP__NAME=kevlars"})
+	assert.Equal(t, map[string]string{"app.name": "MYAPP__APP__NAME"}, overrides)
+}
+
+func TestResolveReferencesAndEnvOverrides(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "api_key"), []byte("file-secret\n"), 0o600))
+	t.Setenv("KEVLARS_TEST_DB_PASS", "p@ss")
+	t.Setenv("APP__LOG__LEVEL", "5")
+	t.Setenv("FEATURE_FLAG__LAZY_LOAD_DATA", "true")
+
+	p := NewLocalStringVar("resolve", TypeYaml, `
+app:
+  log:
+    level: 2
+db:
+  dsn: "postgres://app:${ENV:KEVLARS_TEST_DB_PASS}@127.0.0.1/app"
+  hosts: ["${env:KEVLARS_TEST_DB_PASS}"]
+vendor:
+  api_key: "${file:`+filepath.Join(dir, "api_key")+`}"
+  token: "${secret:vault/kv/vendor}"
+`)
+	c := newTestManager(t, []Provider{p}, WithSecretResolver("vault", staticSecretResolver{"kv/vendor": "vault-token"}))
+	assert.Equal(t, "postgres://app:p@ss@127.0.0.1/app", c.GetString("db.dsn"))
+	assert.Equal(t, []string{"p@ss"}, c.GetStringSlice("db.hosts"))
+	assert.Equal(t, "file-secret", c.GetString("vendor.api_key"))
+	assert.Equal(t, "vault-token", c.GetString("vendor.token"))
+	assert.Equal(t, 5, c.GetInt("app.log.level"))
+	assert.True(t, c.GetBool("feature_flag.lazy_load_data"))
+	// provider data keep the references for the next reload
+	assert.Equal(t, "${secret:vault/kv/vendor}", p.GetData()["vendor"].(map[string]interface{})["token"])
+
+	missing := NewLocalStringVar("missing", TypeYaml, `db: {pass: "${ENV:KEVLARS_TEST_NOT_EXISTS}"}`)
+	require.NoError(t, missing.Init())
+	c.providers = []Provider{missing}
+	_, _, err := c.merge()
+	assert.ErrorIs(t, err, ErrReferenceNotFound)
+}
@@ -0,0 +1,334 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: config_schema
+ * @Date: 23/08/24 09.35
+ */
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+	SchemaTypeObject  = "object"
+	SchemaTypeArray   = "array"
+	SchemaTypeString  = "string"
+	SchemaTypeInteger = "integer"
+	SchemaTypeNumber  = "number"
+	SchemaTypeBoolean = "boolean"
+
+	durationPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+)
+
+// Schema is subset of json schema, enough to describe the shape of the configuration
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	// WriteOnly of secret value, set by `secret:"true"` tag and redacted along with its nested keys
+	WriteOnly bool `json:"writeOnly,omitempty"`
+}
+
+// SchemaOf generate the schema from the struct shape, following the same tags as Bind, e.g.
+//
+//	type Server struct {
+//		Port    int           `mapstructure:"port" default:"8080" validate:"min=1,max=65535" desc:"rest port"`
+//		Timeout time.Duration `mapstructure:"timeout" default:"10s"`
+//		Mode    string        `mapstructure:"mode" validate:"required,oneof=debug release"`
+//		Level   string        `mapstructure:"level" validate:"omitempty,oneof=info debug"`
+//		Token   string        `mapstructure:"token" secret:"true"`
+//	}
+//
+// map become object of additional properties, e.g. named db instances, and slice become array
+func SchemaOf(v interface{}) *Schema {
+	s := schemaOfType(reflect.TypeOf(v))
+	s.Schema = SchemaDraft
+	return s
+}
+
+func schemaOfType(t reflect.Type) *Schema {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return &Schema{}
+	}
+	switch t {
+	case reflect.TypeOf(time.Duration(0)):
+		return &Schema{Type: SchemaTypeString, Pattern: durationPattern}
+	case reflect.TypeOf(time.Time{}):
+		return &Schema{Type: SchemaTypeString}
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		s := &Schema{Type: SchemaTypeObject, Properties: make(map[string]*Schema)}
+		schemaFields(t, s)
+		return s
+	case reflect.Map:
+		return &Schema{Type: SchemaTypeObject, AdditionalProperties: schemaOfType(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: SchemaTypeArray, Items: schemaOfType(t.Elem())}
+	case reflect.Bool:
+		return &Schema{Type: SchemaTypeBoolean}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: SchemaTypeInteger}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: SchemaTypeNumber}
+	case reflect.String:
+		return &Schema{Type: SchemaTypeString}
+	default:
+		// interface accept any value
+		return &Schema{}
+	}
+}
+
+// schemaFields put the struct fields as properties of the schema, squashed struct merged into the parent
+func schemaFields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, tagOpts, _ := strings.Cut(f.Tag.Get("mapstructure"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(tagOpts, "squash") {
+			schemaFields(f.Type, s)
+			continue
+		}
+		if len(name) < 1 {
+			name = f.Name
+		}
+		name = strings.ToLower(name)
+		p := schemaOfType(f.Type)
+		p.Description = f.Tag.Get("desc")
+		p.WriteOnly, _ = strconv.ParseBool(f.Tag.Get("secret"))
+		if d, ok := f.Tag.Lookup("default"); ok {
+			p.Default = scalarOf(p.Type, d)
+		}
+		omitEmpty := false
+		for _, rule := range strings.Split(f.Tag.Get("validate"), ",") {
+			rule, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+			switch rule {
+			case "omitempty":
+				omitEmpty = true
+			case "required":
+				s.Required = append(s.Required, name)
+			case "oneof":
+				for _, item := range strings.Fields(param) {
+					p.Enum = append(p.Enum, scalarOf(p.Type, item))
+				}
+			case "min", "max":
+				// min and max of string and collection are about length, not covered
+				if p.Type != SchemaTypeInteger && p.Type != SchemaTypeNumber {
+					continue
+				}
+				if n, err := strconv.ParseFloat(param, 64); err == nil {
+					if rule == "min" {
+						p.Minimum = &n
+					} else {
+						p.Maximum = &n
+					}
+				}
+			}
+		}
+		// empty string allowed along the enum, same as `omitempty,oneof=...` of the validator
+		if omitEmpty && len(p.Enum) > 0 && p.Type == SchemaTypeString {
+			p.Enum = append([]interface{}{""}, p.Enum...)
+		}
+		s.Properties[name] = p
+	}
+}
+
+// SecretKeys of the write only properties as dotted key patterns to be redacted, `*` match any name of map,
+// e.g. `dbs.*.dsn`. slice taken as single key when any of its item hold secret, the same as flattened settings
+func (s *Schema) SecretKeys() []string {
+	rs := make([]string, 0)
+	s.secretKeys("", &rs)
+	sort.Strings(rs)
+	return rs
+}
+
+func (s *Schema) secretKeys(key string, rs *[]string) {
+	if s.WriteOnly && len(key) > 0 {
+		// whole object redacted, e.g. `crypt.*`
+		if s.Type == SchemaTypeObject {
+			key = joinKey(key, "*")
+		}
+		*rs = append(*rs, key)
+		return
+	}
+	for name, p := range s.Properties {
+		p.secretKeys(joinKey(key, name), rs)
+	}
+	if s.AdditionalProperties != nil {
+		s.AdditionalProperties.secretKeys(joinKey(key, "*"), rs)
+	}
+	if s.Items != nil {
+		items := make([]string, 0)
+		s.Items.secretKeys(joinKey(key, "*"), &items)
+		if len(items) > 0 {
+			*rs = append(*rs, key)
+		}
+	}
+}
+
+// scalarOf the tag value on the schema type, kept as string when not parsable
+func scalarOf(schemaType, value string) interface{} {
+	switch schemaType {
+	case SchemaTypeInteger:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case SchemaTypeNumber:
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case SchemaTypeBoolean:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// Validate the settings against the schema and report all issues at once, ordered by key.
+// reference and encrypted values are skipped since those only resolved on load
+func (s *Schema) Validate(settings map[string]interface{}) []BindIssue {
+	issues := make([]BindIssue, 0)
+	s.validate("", settings, &issues)
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Key < issues[j].Key
+	})
+	return issues
+}
+
+func (s *Schema) validate(key string, value interface{}, issues *[]BindIssue) {
+	if value == nil {
+		return
+	}
+	if str, ok := value.(string); ok && (IsEncrypted(str) || referencePattern.MatchString(str)) {
+		return
+	}
+	issue := func(rule string) {
+		*issues = append(*issues, BindIssue{Key: key, Rule: rule})
+	}
+	switch s.Type {
+	case SchemaTypeObject:
+		m, ok := stringMapOf(value)
+		if !ok {
+			issue("type=" + s.Type)
+			return
+		}
+		for _, name := range s.Required {
+			if v, exist := m[name]; !exist || v == nil {
+				*issues = append(*issues, BindIssue{Key: joinKey(key, name), Rule: "required"})
+			}
+		}
+		for name, v := range m {
+			if p, exist := s.Properties[strings.ToLower(name)]; exist {
+				p.validate(joinKey(key, name), v, issues)
+			} else if s.AdditionalProperties != nil {
+				s.AdditionalProperties.validate(joinKey(key, name), v, issues)
+			}
+		}
+	case SchemaTypeArray:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			issue("type=" + s.Type)
+			return
+		}
+		if s.Items == nil {
+			return
+		}
+		for i := 0; i < rv.Len(); i++ {
+			s.Items.validate(fmt.Sprintf("%s.%d", key, i), rv.Index(i).Interface(), issues)
+		}
+	case SchemaTypeString:
+		str, ok := value.(string)
+		if !ok {
+			issue("type=" + s.Type)
+			return
+		}
+		if matched, err := regexp.MatchString(s.Pattern, str); len(s.Pattern) > 0 && (err != nil || !matched) {
+			issue("pattern")
+			return
+		}
+	case SchemaTypeBoolean:
+		if _, ok := value.(bool); !ok {
+			issue("type=" + s.Type)
+			return
+		}
+	case SchemaTypeInteger, SchemaTypeNumber:
+		n, ok := numberOf(value)
+		if !ok || (s.Type == SchemaTypeInteger && n != float64(int64(n))) {
+			issue("type=" + s.Type)
+			return
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			issue(fmt.Sprintf("min=%v", *s.Minimum))
+		}
+		if s.Maximum != nil && n > *s.Maximum {
+			issue(fmt.Sprintf("max=%v", *s.Maximum))
+		}
+	}
+	if len(s.Enum) > 0 {
+		for _, e := range s.Enum {
+			if fmt.Sprint(e) == fmt.Sprint(value) {
+				return
+			}
+		}
+		issue("oneof")
+	}
+}
+
+func stringMapOf(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		rs := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			rs[fmt.Sprint(k)] = v
+		}
+		return rs, true
+	default:
+		return nil, false
+	}
+}
+
+func numberOf(value interface{}) (float64, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
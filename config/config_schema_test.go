@@ -0,0 +1,79 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: config_schema_test
+ * @Date: 23/08/24 11.15
+ */
+
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type schemaTestCommon struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+type schemaTestServer struct {
+	Common  schemaTestCommon `mapstructure:",squash"`
+	Port    int              `mapstructure:"port" default:"8080" validate:"min=1,max=65535"`
+	Timeout time.Duration    `mapstructure:"timeout" default:"10s"`
+	Mode    string           `mapstructure:"mode" validate:"required,oneof=debug release"`
+	Level   string           `mapstructure:"level" validate:"omitempty,oneof=info debug"`
+	Hosts   []string         `mapstructure:"hosts"`
+	Token   string           `mapstructure:"token" secret:"true"`
+}
+
+type schemaTestConfig struct {
+	Server schemaTestServer            `mapstructure:"server" validate:"required"`
+	Dbs    map[string]schemaTestServer `mapstructure:"dbs"`
+	Vault  map[string]string           `mapstructure:"vault" secret:"true"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	s := SchemaOf(schemaTestConfig{})
+	assert.Equal(t, SchemaDraft, s.Schema)
+	assert.Equal(t, []string{"server"}, s.Required)
+	server := s.Properties["server"]
+	require.NotNil(t, server)
+	assert.Equal(t, SchemaTypeBoolean, server.Properties["enabled"].Type)
+	assert.Equal(t, int64(8080), server.Properties["port"].Default)
+	assert.Equal(t, 65535.0, *server.Properties["port"].Maximum)
+	assert.Equal(t, []interface{}{"debug", "release"}, server.Properties["mode"].Enum)
+	assert.Equal(t, []interface{}{"", "info", "debug"}, server.Properties["level"].Enum)
+	assert.Equal(t, SchemaTypeString, server.Properties["hosts"].Items.Type)
+	assert.Equal(t, SchemaTypeObject, s.Properties["dbs"].AdditionalProperties.Type)
+	assert.True(t, server.Properties["token"].WriteOnly)
+	assert.Equal(t, []string{"dbs.*.token", "server.token", "vault.*"}, s.SecretKeys())
+
+	issues := s.Validate(map[string]interface{}{
+		"server": map[string]interface{}{
+			"enabled": "yes",
+			"port":    70000,
+			"timeout": "ten seconds",
+			"mode":    "${env:SERVER_MODE}",
+			"level":   "",
+			"hosts":   []interface{}{"a", 1},
+		},
+		"dbs": map[string]interface{}{
+			"main": map[string]interface{}{"port": 5432.5, "mode": "test", "level": "trace"},
+		},
+		"app": map[string]interface{}{"name": "not restricted"},
+	})
+	assert.Equal(t, []BindIssue{
+		{Key: "dbs.main.level", Rule: "oneof"},
+		{Key: "dbs.main.mode", Rule: "oneof"},
+		{Key: "dbs.main.port", Rule: "type=integer"},
+		{Key: "server.enabled", Rule: "type=boolean"},
+		{Key: "server.hosts.1", Rule: "type=string"},
+		{Key: "server.port", Rule: "max=65535"},
+		{Key: "server.timeout", Rule: "pattern"},
+	}, issues)
+
+	issues = s.Validate(map[string]interface{}{})
+	assert.Equal(t, []BindIssue{{Key: "server", Rule: "required"}}, issues)
+}
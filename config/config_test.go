@@ -5,7 +5,7 @@
  * @Date: 21/07/24 06.17
  */
 
-package config
+package config_test
 
 import (
 	mockConfig "github.com/evorts/kevlars/mocks/config"
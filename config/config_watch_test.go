@@ -0,0 +1,85 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: config_watch_test
+ * @Date: 17/08/24 10.15
+ */
+
+package config
+
+import (
+	"context"
+	"github.com/evorts/kevlars/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestManager of the given providers, merged in order, without going through env based provider selection
+func newTestManager(t *testing.T, providers []Provider, opts ...common.Option[configManager]) *configManager {
+	c := New(opts...).(*configManager)
+	for _, p := range providers {
+		require.NoError(t, p.Init())
+	}
+	c.providers = providers
+	require.NoError(t, c.load())
+	return c
+}
+
+func TestWatchLocalFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("app:\n  name: watched\n  log:\n    level: 2\n"), 0o600))
+
+	c := newTestManager(t, []Provider{NewLocalFile("config", TypeYaml, dir)})
+
+	levels := make(chan interface{}, 1)
+	names := make(chan interface{}, 1)
+	c.OnChange("app.log.level", func(old, new interface{}) {
+		levels <- new
+	})
+	c.OnChange("app.name", func(old, new interface{}) {
+		names <- new
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, c.Watch(ctx))
+
+	require.NoError(t, os.WriteFile(file, []byte("app:\n  name: watched\n  log:\n    level: 5\n"), 0o600))
+	select {
+	case level := <-levels:
+		assert.Equal(t, 5, level)
+	case <-time.After(5 * time.Second):
+		t.Fatal("change of app.log.level not notified")
+	}
+	assert.Equal(t, 5, c.GetInt("app.log.level"))
+	assert.Len(t, names, 0)
+}
+
+func TestReloadSubscriberReentrant(t *testing.T) {
+	t.Setenv("APP__NAME", "first")
+	c := newTestManager(t, []Provider{NewLocalStringVar("reload", TypeYaml, "app:\n  name: initial\n")})
+	names := make([]interface{}, 0)
+	c.OnChange("app.name", func(old, new interface{}) {
+		names = append(names, new)
+		// subscribing and reloading within subscriber should not deadlock
+		c.OnChange("app.log.level", func(old, new interface{}) {})
+		c.reload()
+	})
+	t.Setenv("APP__NAME", "second")
+	done := make(chan struct{})
+	go func() {
+		c.reload()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reload deadlocked by its subscriber")
+	}
+	assert.Equal(t, []interface{}{"second"}, names)
+	assert.Equal(t, "second", c.GetString("app.name"))
+}
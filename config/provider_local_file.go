@@ -7,31 +7,112 @@
 
 package config
 
-import "github.com/spf13/viper"
+import (
+	"context"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"path/filepath"
+	"sync"
+)
 
 type localFileProvider struct {
 	name  string
 	cType Type
 	paths []string
 
-	v *viper.Viper
+	mu sync.RWMutex
+	v  *viper.Viper
 }
 
 func (c *localFileProvider) GetData() map[string]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.v.AllSettings()
 }
 
+func (c *localFileProvider) Source() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.v == nil {
+		return "file:" + c.name
+	}
+	return "file:" + c.v.ConfigFileUsed()
+}
+
 func (c *localFileProvider) Init() error {
-	c.v = viper.New()
-	c.v.SetConfigName(c.name)
-	c.v.SetConfigType(c.cType.String())
 	if len(c.paths) < 1 {
 		c.paths = []string{"."}
 	}
-	for _, v := range c.paths {
-		c.v.AddConfigPath(v)
+	v, err := c.read()
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.v = v
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *localFileProvider) read() (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigName(c.name)
+	v.SetConfigType(c.cType.String())
+	for _, p := range c.paths {
+		v.AddConfigPath(p)
+	}
+	return v, v.ReadInConfig()
+}
+
+// Watch the directory of the config file rather than the file itself, so replacement by editors
+// or symlink swap of kubernetes mounted config map also detected
+func (c *localFileProvider) Watch(ctx context.Context, notify func()) error {
+	c.mu.RLock()
+	file := filepath.Clean(c.v.ConfigFileUsed())
+	c.mu.RUnlock()
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err = watcher.Add(filepath.Dir(file)); err != nil {
+		_ = watcher.Close()
+		return err
 	}
-	return c.v.ReadInConfig()
+	go func() {
+		defer func() {
+			_ = watcher.Close()
+		}()
+		realFile, _ := filepath.EvalSymlinks(file)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				currentFile, _ := filepath.EvalSymlinks(file)
+				written := filepath.Clean(event.Name) == file && event.Has(fsnotify.Write|fsnotify.Create)
+				if !written && currentFile == realFile {
+					continue
+				}
+				realFile = currentFile
+				v, err := c.read()
+				// file could be written partially, the next write event will bring the complete one
+				if err != nil {
+					continue
+				}
+				c.mu.Lock()
+				c.v = v
+				c.mu.Unlock()
+				notify()
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return nil
 }
 
 func NewLocalFile(name string, ctype Type, paths ...string) Provider {
@@ -0,0 +1,122 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: provider_local_mounted_dir
+ * @Date: 20/08/24 08.40
+ */
+
+package config
+
+import (
+	"context"
+	"github.com/fsnotify/fsnotify"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// mountedDirProvider read kubernetes secret or config map mounted as directory, each file is a key
+// and its content is the value. dotted file name expanded into nested map, e.g. file `db.host`
+// accessible through GetString("db.host"). hidden files skipped, including `..data` of atomic writer
+type mountedDirProvider struct {
+	dir string
+
+	mu   sync.RWMutex
+	data map[string]interface{}
+}
+
+func (m *mountedDirProvider) GetData() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.data
+}
+
+func (m *mountedDirProvider) Source() string {
+	return LocalProviderK8s.String() + ":" + m.dir
+}
+
+func (m *mountedDirProvider) Init() error {
+	data, err := m.read()
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.data = data
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *mountedDirProvider) read() (map[string]interface{}, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{})
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(m.dir, entry.Name())
+		// entries are symlinks into the current data directory when mounted by kubernetes
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		expandNestedKey(data, entry.Name(), strings.TrimRight(string(value), "\r\n"))
+	}
+	return data, nil
+}
+
+// Watch the directory, kubernetes update the mounted volume by swapping `..data` symlink
+func (m *mountedDirProvider) Watch(ctx context.Context, notify func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err = watcher.Add(m.dir); err != nil {
+		_ = watcher.Close()
+		return err
+	}
+	go func() {
+		defer func() {
+			_ = watcher.Close()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				data, err := m.read()
+				if err != nil {
+					continue
+				}
+				m.mu.Lock()
+				changed := !reflect.DeepEqual(m.data, data)
+				m.data = data
+				m.mu.Unlock()
+				if changed {
+					notify()
+				}
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// NewLocalMountedDir of kubernetes secret or config map volume, e.g. /etc/config
+func NewLocalMountedDir(dir string) Provider {
+	return &mountedDirProvider{dir: dir, data: map[string]interface{}{}}
+}
@@ -0,0 +1,62 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: provider_local_mounted_dir_test
+ * @Date: 20/08/24 10.45
+ */
+
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mountAtomic lay out files the way kubernetes atomic writer does, files linked through `..data`
+func mountAtomic(t *testing.T, dir, version string, files map[string]string) {
+	versionDir := filepath.Join(dir, version)
+	require.NoError(t, os.MkdirAll(versionDir, 0o700))
+	for name, value := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(versionDir, name), []byte(value), 0o600))
+		_ = os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name))
+	}
+	_ = os.Remove(filepath.Join(dir, "..data"))
+	require.NoError(t, os.Symlink(version, filepath.Join(dir, "..data")))
+}
+
+func TestMountedDirProvider(t *testing.T) {
+	dir := t.TempDir()
+	mountAtomic(t, dir, "..2024_08_20_10_45_00.1", map[string]string{
+		"db.host":  "127.0.0.1\n",
+		"db.port":  "5432",
+		"app_name": "kevlars",
+	})
+	c := newTestManager(t, []Provider{NewLocalMountedDir(dir)})
+	assert.Equal(t, "127.0.0.1", c.GetString("db.host"))
+	assert.Equal(t, 5432, c.GetInt("db.port"))
+	assert.Equal(t, "kevlars", c.GetString("app_name"))
+	assert.False(t, c.IsSet("..data"))
+}
+
+func TestDynamicProviders(t *testing.T) {
+	t.Setenv(EnvKeyUseConfigDynamicValues.String(), "local.k8s,remote.vault,local.file")
+	t.Setenv("CONFIG_LOCAL_K8S_NAME", "/etc/config")
+	t.Setenv("SECRET_LOCAL_K8S_NAME", "/etc/secret")
+	t.Setenv("SECRET_REMOTE_VAULT_ADDR", "http://127.0.0.1:8200")
+	t.Setenv("SECRET_REMOTE_VAULT_NAME", "secret/app/config")
+	c := New().(*configManager)
+	ev := c.populateEnvVars()
+	items := ev.useConfig.combination.Parse().Sort()
+	require.Len(t, items, 3)
+	assert.Equal(t, CProvider(RemoteProviderVault), items[0].Provider)
+	assert.Equal(t, CProvider(LocalProviderK8s), items[1].Provider)
+	providers := c.loadDynamicProviders(ev)
+	require.Len(t, providers, 3)
+	// configs then secrets, each following the sorted items
+	assert.Equal(t, "/etc/config", providers[0].(*mountedDirProvider).dir)
+	assert.Equal(t, "secret/app/config", providers[1].(*secretProvider).Provider.(*vaultProvider).path)
+	assert.Equal(t, "/etc/secret", providers[2].(*secretProvider).Provider.(*mountedDirProvider).dir)
+}
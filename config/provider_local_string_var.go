@@ -32,6 +32,10 @@ func (c *localStringVarProvider) GetData() map[string]interface{} {
 	return c.v.AllSettings()
 }
 
+func (c *localStringVarProvider) Source() string {
+	return "string_var:" + c.name
+}
+
 func (c *localStringVarProvider) Init() error {
 	c.v = viper.New()
 	c.v.SetConfigName(c.name)
@@ -7,7 +7,25 @@
 
 package config
 
-import "github.com/spf13/viper"
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	consulWaitTime   = 5 * time.Minute
+	consulRetryDelay = 5 * time.Second
+)
 
 type remoteProvider struct {
 	provider string
@@ -15,22 +33,125 @@ type remoteProvider struct {
 	path     string
 	cType    Type
 
-	v *viper.Viper
+	mu          sync.RWMutex
+	v           *viper.Viper
+	consulIndex uint64
+	client      *http.Client
 }
 
 func (c *remoteProvider) Init() error {
-	c.v = viper.New()
-	if err := c.v.AddRemoteProvider(c.provider, c.address, c.path); err != nil {
+	if c.provider == RemoteProviderConsul.String() {
+		_, err := c.loadConsul(context.Background())
+		return err
+	}
+	v := viper.New()
+	if err := v.AddRemoteProvider(c.provider, c.address, c.path); err != nil {
 		return err
 	}
-	c.v.SetConfigType(c.cType.String())
-	return c.v.ReadRemoteConfig()
+	v.SetConfigType(c.cType.String())
+	if err := v.ReadRemoteConfig(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.v = v
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *remoteProvider) Source() string {
+	return c.provider + ":" + strings.TrimSuffix(c.address, "/") + "/" + strings.TrimPrefix(c.path, "/")
 }
 
 func (c *remoteProvider) GetData() map[string]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.v.AllSettings()
 }
 
+// loadConsul read the key through consul blocking query, which return once the key modified
+// after the last known index or the wait time elapsed
+func (c *remoteProvider) loadConsul(ctx context.Context) (bool, error) {
+	c.mu.RLock()
+	index := c.consulIndex
+	c.mu.RUnlock()
+	address := c.address
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	query := url.Values{"raw": {"true"}}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", consulWaitTime.String())
+	}
+	endpoint := fmt.Sprintf("%s/v1/kv/%s?%s", strings.TrimSuffix(address, "/"), strings.TrimPrefix(c.path, "/"), query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	if token := os.Getenv("CONSUL_HTTP_TOKEN"); len(token) > 0 {
+		req.Header.Set("X-Consul-Token", token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("consul key %s: %s", c.path, resp.Status)
+	}
+	newIndex, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	// same index means the wait time elapsed without changes, while index going backward
+	// means consul state reset which taken as changed and start over from the new index
+	if index > 0 && newIndex == index {
+		return false, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	v := viper.New()
+	v.SetConfigType(c.cType.String())
+	if err = v.ReadConfig(bytes.NewReader(body)); err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	c.v = v
+	c.consulIndex = newIndex
+	c.mu.Unlock()
+	return true, nil
+}
+
+// Watch consul key through blocking query, other remote provider not supported
+func (c *remoteProvider) Watch(ctx context.Context, notify func()) error {
+	if c.provider != RemoteProviderConsul.String() {
+		return nil
+	}
+	go func() {
+		for ctx.Err() == nil {
+			changed, err := c.loadConsul(ctx)
+			if err != nil {
+				select {
+				case <-ctx.Done():
+				case <-time.After(consulRetryDelay):
+				}
+				continue
+			}
+			if changed {
+				notify()
+			}
+		}
+	}()
+	return nil
+}
+
 func NewRemote(provider, address, path string, configType Type) Provider {
-	return &remoteProvider{provider: provider, address: address, path: path, cType: configType}
+	return &remoteProvider{
+		provider: provider,
+		address:  address,
+		path:     path,
+		cType:    configType,
+		client:   &http.Client{Timeout: consulWaitTime + time.Minute},
+	}
 }
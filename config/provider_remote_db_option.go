@@ -7,10 +7,35 @@
 
 package config
 
-import "github.com/evorts/kevlars/common"
+import (
+	"github.com/evorts/kevlars/common"
+	"github.com/evorts/kevlars/db"
+	"time"
+)
 
 func WithDBTableName(tableName string) common.Option[dbProvider] {
 	return common.OptionFunc[dbProvider](func(m *dbProvider) {
 		m.tableName = tableName
 	})
 }
+
+// WithDBManager use the given connected database manager instead of the one constructed from the address
+func WithDBManager(dbm db.Manager) common.Option[dbProvider] {
+	return common.OptionFunc[dbProvider](func(m *dbProvider) {
+		m.dbm = dbm
+	})
+}
+
+// WithDBSchemaBootstrap create the config table when not exists, enabled by default
+func WithDBSchemaBootstrap(enabled bool) common.Option[dbProvider] {
+	return common.OptionFunc[dbProvider](func(m *dbProvider) {
+		m.bootstrap = enabled
+	})
+}
+
+// WithDBPollInterval of reading the table while watched, default to 1 minute
+func WithDBPollInterval(interval time.Duration) common.Option[dbProvider] {
+	return common.OptionFunc[dbProvider](func(m *dbProvider) {
+		m.pollInterval = interval
+	})
+}
@@ -18,25 +18,35 @@ import (
 	"gopkg.in/yaml.v3"
 	"hash/crc32"
 	"strings"
+	"sync"
+	"time"
 )
 
 type gsmProvider struct {
 	projectId    string
 	resourceName string
 	configType   Type
-	data         map[string]interface{}
+	pollInterval time.Duration
+
+	mu      sync.RWMutex
+	data    map[string]interface{}
+	version string
 
 	jsonCredFile string
 	jsonCred     []byte
 }
 
 func (g *gsmProvider) GetData() map[string]interface{} {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
 	return g.data
 }
 
-// Init initialize google secret manager and populate into conf object
-func (g *gsmProvider) Init() error {
-	ctx := context.Background()
+func (g *gsmProvider) Source() string {
+	return fmt.Sprintf("%s:%s/versions/%s", RemoteProviderGSM.String(), g.projectId, g.resourceName)
+}
+
+func (g *gsmProvider) newClient(ctx context.Context) (*secretmanager.Client, error) {
 	var opts = make([]option.ClientOption, 0)
 	if len(g.jsonCredFile) > 0 {
 		opts = append(opts, option.WithCredentialsFile(g.jsonCredFile))
@@ -44,7 +54,13 @@ func (g *gsmProvider) Init() error {
 	if g.jsonCred != nil && len(g.jsonCred) > 0 {
 		opts = append(opts, option.WithCredentialsJSON(g.jsonCred))
 	}
-	client, err := secretmanager.NewClient(ctx, opts...)
+	return secretmanager.NewClient(ctx, opts...)
+}
+
+// Init initialize google secret manager and populate into conf object
+func (g *gsmProvider) Init() error {
+	ctx := context.Background()
+	client, err := g.newClient(ctx)
 	if err != nil {
 		return err
 	}
@@ -54,6 +70,12 @@ func (g *gsmProvider) Init() error {
 			fmt.Println(err.Error())
 		}
 	}(client)
+	_, err = g.load(ctx, client)
+	return err
+}
+
+// load secret version and replace the data when the version changed, e.g. new version added on `latest` alias
+func (g *gsmProvider) load(ctx context.Context, client *secretmanager.Client) (bool, error) {
 	secretPath := fmt.Sprintf("%s/versions/%s", g.projectId, g.resourceName)
 	if strings.HasSuffix(g.projectId, "/versions") {
 		secretPath = fmt.Sprintf("%s/%s", g.projectId, g.resourceName)
@@ -64,18 +86,61 @@ func (g *gsmProvider) Init() error {
 	// Call the API.
 	result, err := client.AccessSecretVersion(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to access secret version: %v", err)
+		return false, fmt.Errorf("failed to access secret version: %v", err)
 	}
 	// Verify the data checksum.
 	crc32c := crc32.MakeTable(crc32.Castagnoli)
 	checksum := int64(crc32.Checksum(result.Payload.Data, crc32c))
 	if checksum != *result.Payload.DataCrc32C {
-		return fmt.Errorf("data corruption detected")
+		return false, fmt.Errorf("data corruption detected")
+	}
+	version := fmt.Sprintf("%s#%d", result.Name, checksum)
+	g.mu.RLock()
+	unchanged := version == g.version
+	g.mu.RUnlock()
+	if unchanged {
+		return false, nil
 	}
+	data := make(map[string]interface{})
 	if g.configType == TypeJson {
-		return json.Unmarshal(result.Payload.Data, &g.data)
+		err = json.Unmarshal(result.Payload.Data, &data)
+	} else {
+		err = yaml.Unmarshal(result.Payload.Data, &data)
 	}
-	return yaml.Unmarshal(result.Payload.Data, &g.data)
+	if err != nil {
+		return false, err
+	}
+	g.mu.Lock()
+	g.data, g.version = data, version
+	g.mu.Unlock()
+	return true, nil
+}
+
+// Watch poll the secret version on interval, effective when resource name refer to `latest` alias
+func (g *gsmProvider) Watch(ctx context.Context, notify func()) error {
+	client, err := g.newClient(ctx)
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer func() {
+			_ = client.Close()
+		}()
+		ticker := time.NewTicker(g.pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// transient failure retried on the next tick
+				if changed, err := g.load(ctx, client); err == nil && changed {
+					notify()
+				}
+			}
+		}
+	}()
+	return nil
 }
 
 // NewRemoteGSM instantiate google secret manager with the given projectId and secret name
@@ -85,6 +150,7 @@ func NewRemoteGSM(projectId, resourceName string, configType Type, opts ...commo
 		projectId:    projectId,
 		resourceName: resourceName,
 		configType:   configType,
+		pollInterval: defaultPollInterval,
 	}
 	for _, opt := range opts {
 		opt.Apply(p)
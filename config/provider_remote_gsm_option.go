@@ -7,7 +7,10 @@
 
 package config
 
-import "github.com/evorts/kevlars/common"
+import (
+	"github.com/evorts/kevlars/common"
+	"time"
+)
 
 func WithGsmProjectId(projectId string) common.Option[gsmProvider] {
 	return common.OptionFunc[gsmProvider](func(m *gsmProvider) {
@@ -21,7 +24,7 @@ func WithGsmResourceName(resourceName string) common.Option[gsmProvider] {
 	})
 }
 
-func WithGsmConfigType(configType string) common.Option[gsmProvider] {
+func WithGsmConfigType(configType Type) common.Option[gsmProvider] {
 	return common.OptionFunc[gsmProvider](func(m *gsmProvider) {
 		m.configType = configType
 	})
@@ -38,3 +41,10 @@ func WithGsmUseJsonCreds(jsonCreds []byte) common.Option[gsmProvider] {
 		m.jsonCred = jsonCreds
 	})
 }
+
+// WithGsmPollInterval of checking new secret version while watched, default to 1 minute
+func WithGsmPollInterval(interval time.Duration) common.Option[gsmProvider] {
+	return common.OptionFunc[gsmProvider](func(m *gsmProvider) {
+		m.pollInterval = interval
+	})
+}
@@ -0,0 +1,293 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: provider_remote_vault
+ * @Date: 20/08/24 09.30
+ */
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/evorts/kevlars/common"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	vaultDefaultAppRoleMount = "approle"
+	vaultRequestTimeout      = 30 * time.Second
+)
+
+// vaultProvider read secret of hashicorp vault kv v2 engine, authenticated by token or app role.
+// path consist of the engine mount and the secret path, e.g. `secret/app/config`
+type vaultProvider struct {
+	address      string
+	path         string
+	token        string
+	roleId       string
+	secretId     string
+	appRoleMount string
+	namespace    string
+	pollInterval time.Duration
+	client       *http.Client
+
+	mu        sync.RWMutex
+	data      map[string]interface{}
+	version   int64
+	tokenTTL  time.Duration
+	renewable bool
+}
+
+type vaultAuth struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int64  `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+}
+
+type vaultTokenLookup struct {
+	Data struct {
+		TTL       int64 `json:"ttl"`
+		Renewable bool  `json:"renewable"`
+	} `json:"data"`
+}
+
+type vaultKVSecret struct {
+	Data struct {
+		Data     map[string]interface{} `json:"data"`
+		Metadata struct {
+			Version int64 `json:"version"`
+		} `json:"metadata"`
+	} `json:"data"`
+}
+
+type vaultError struct {
+	status int
+	errors []string
+}
+
+func (e *vaultError) Error() string {
+	return fmt.Sprintf("vault responded %d: %s", e.status, strings.Join(e.errors, "; "))
+}
+
+func (v *vaultProvider) GetData() map[string]interface{} {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.data
+}
+
+func (v *vaultProvider) Source() string {
+	return RemoteProviderVault.String() + ":" + v.path
+}
+
+func (v *vaultProvider) Init() error {
+	ctx := context.Background()
+	if len(v.token) < 1 {
+		if err := v.login(ctx); err != nil {
+			return err
+		}
+	} else {
+		// lease of the given token, no renewal when lookup not allowed by its policy
+		var lookup vaultTokenLookup
+		if err := v.do(ctx, http.MethodGet, "auth/token/lookup-self", nil, &lookup); err == nil {
+			v.mu.Lock()
+			v.tokenTTL = time.Duration(lookup.Data.TTL) * time.Second
+			v.renewable = lookup.Data.Renewable
+			v.mu.Unlock()
+		}
+	}
+	_, err := v.load(ctx)
+	return err
+}
+
+// dataPath of kv v2 read api, `secret/app/config` become `secret/data/app/config`
+func (v *vaultProvider) dataPath() string {
+	p := strings.Trim(v.path, "/")
+	if strings.Contains(p, "/data/") {
+		return p
+	}
+	mount, secretPath, _ := strings.Cut(p, "/")
+	return mount + "/data/" + secretPath
+}
+
+func (v *vaultProvider) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var reader *bytes.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(raw)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(v.address, "/")+"/v1/"+path, reader)
+	if err != nil {
+		return err
+	}
+	v.mu.RLock()
+	token := v.token
+	v.mu.RUnlock()
+	if len(token) > 0 {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if len(v.namespace) > 0 {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode >= http.StatusBadRequest {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return &vaultError{status: resp.StatusCode, errors: e.Errors}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// login through app role, the token lease renewed while watched
+func (v *vaultProvider) login(ctx context.Context) error {
+	if len(v.roleId) < 1 {
+		return ErrVaultAuthNotDefined
+	}
+	var auth vaultAuth
+	err := v.do(ctx, http.MethodPost, "auth/"+v.appRoleMount+"/login", map[string]string{
+		"role_id":   v.roleId,
+		"secret_id": v.secretId,
+	}, &auth)
+	if err != nil {
+		return err
+	}
+	v.setAuth(auth)
+	return nil
+}
+
+func (v *vaultProvider) renew(ctx context.Context) error {
+	var auth vaultAuth
+	if err := v.do(ctx, http.MethodPost, "auth/token/renew-self", map[string]string{}, &auth); err != nil {
+		return err
+	}
+	v.setAuth(auth)
+	return nil
+}
+
+func (v *vaultProvider) setAuth(auth vaultAuth) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(auth.Auth.ClientToken) > 0 {
+		v.token = auth.Auth.ClientToken
+	}
+	v.tokenTTL = time.Duration(auth.Auth.LeaseDuration) * time.Second
+	v.renewable = auth.Auth.Renewable
+}
+
+// load the secret and replace the data when the version changed
+func (v *vaultProvider) load(ctx context.Context) (bool, error) {
+	var secret vaultKVSecret
+	err := v.do(ctx, http.MethodGet, v.dataPath(), nil, &secret)
+	// token could be expired or revoked, login again when app role defined
+	var ve *vaultError
+	if errors.As(err, &ve) && ve.status == http.StatusForbidden && len(v.roleId) > 0 {
+		if err = v.login(ctx); err != nil {
+			return false, err
+		}
+		err = v.do(ctx, http.MethodGet, v.dataPath(), nil, &secret)
+	}
+	if err != nil {
+		return false, err
+	}
+	v.mu.RLock()
+	unchanged := v.data != nil && secret.Data.Metadata.Version == v.version
+	v.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	data := make(map[string]interface{})
+	for key, value := range secret.Data.Data {
+		expandNestedKey(data, key, value)
+	}
+	v.mu.Lock()
+	v.data, v.version = data, secret.Data.Metadata.Version
+	v.mu.Unlock()
+	return true, nil
+}
+
+// renewIn two third of the token lease, zero when the token not renewable or has no expiry
+func (v *vaultProvider) renewIn() time.Duration {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if !v.renewable || v.tokenTTL < 1 {
+		return 0
+	}
+	return v.tokenTTL * 2 / 3
+}
+
+// Watch poll the secret version on interval and keep the token lease renewed
+func (v *vaultProvider) Watch(ctx context.Context, notify func()) error {
+	go func() {
+		ticker := time.NewTicker(v.pollInterval)
+		defer ticker.Stop()
+		renewal := time.NewTimer(0)
+		if !renewal.Stop() {
+			<-renewal.C
+		}
+		if d := v.renewIn(); d > 0 {
+			renewal.Reset(d)
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				renewal.Stop()
+				return
+			case <-renewal.C:
+				if err := v.renew(ctx); err != nil && len(v.roleId) > 0 {
+					_ = v.login(ctx)
+				}
+				if d := v.renewIn(); d > 0 {
+					renewal.Reset(d)
+				}
+			case <-ticker.C:
+				// transient failure retried on the next tick
+				if changed, err := v.load(ctx); err == nil && changed {
+					notify()
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// NewRemoteVault of kv v2 secret on the address, e.g. http://127.0.0.1:8200 and path `secret/app/config`.
+// authenticated by VAULT_TOKEN, or app role of VAULT_ROLE_ID and VAULT_SECRET_ID when token not defined
+func NewRemoteVault(address, path string, opts ...common.Option[vaultProvider]) Provider {
+	p := &vaultProvider{
+		address:      address,
+		path:         path,
+		token:        os.Getenv("VAULT_TOKEN"),
+		roleId:       os.Getenv("VAULT_ROLE_ID"),
+		secretId:     os.Getenv("VAULT_SECRET_ID"),
+		namespace:    os.Getenv("VAULT_NAMESPACE"),
+		appRoleMount: vaultDefaultAppRoleMount,
+		pollInterval: defaultPollInterval,
+		client:       &http.Client{Timeout: vaultRequestTimeout},
+	}
+	for _, opt := range opts {
+		opt.Apply(p)
+	}
+	return p
+}
@@ -0,0 +1,54 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: provider_remote_vault_option
+ * @Date: 20/08/24 09.55
+ */
+
+package config
+
+import (
+	"github.com/evorts/kevlars/common"
+	"net/http"
+	"time"
+)
+
+func WithVaultToken(token string) common.Option[vaultProvider] {
+	return common.OptionFunc[vaultProvider](func(m *vaultProvider) {
+		m.token = token
+	})
+}
+
+// WithVaultAppRole authenticate through app role when token not defined
+func WithVaultAppRole(roleId, secretId string) common.Option[vaultProvider] {
+	return common.OptionFunc[vaultProvider](func(m *vaultProvider) {
+		m.roleId = roleId
+		m.secretId = secretId
+	})
+}
+
+// WithVaultAppRoleMount when app role auth method enabled on other than `approle` path
+func WithVaultAppRoleMount(mount string) common.Option[vaultProvider] {
+	return common.OptionFunc[vaultProvider](func(m *vaultProvider) {
+		m.appRoleMount = mount
+	})
+}
+
+func WithVaultNamespace(namespace string) common.Option[vaultProvider] {
+	return common.OptionFunc[vaultProvider](func(m *vaultProvider) {
+		m.namespace = namespace
+	})
+}
+
+// WithVaultPollInterval of checking new secret version while watched, default to 1 minute
+func WithVaultPollInterval(interval time.Duration) common.Option[vaultProvider] {
+	return common.OptionFunc[vaultProvider](func(m *vaultProvider) {
+		m.pollInterval = interval
+	})
+}
+
+func WithVaultHttpClient(client *http.Client) common.Option[vaultProvider] {
+	return common.OptionFunc[vaultProvider](func(m *vaultProvider) {
+		m.client = client
+	})
+}
@@ -0,0 +1,70 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: provider_remote_vault_test
+ * @Date: 20/08/24 10.20
+ */
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newVaultStandIn serve app role login, token renewal and kv v2 read of `secret/app/config`
+func newVaultStandIn(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/auth/approle/login", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["role_id"] != "role" || body["secret_id"] != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":["invalid role or secret id"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"auth":{"client_token":"s.issued","lease_duration":3600,"renewable":true}}`))
+	})
+	mux.HandleFunc("GET /v1/secret/data/app/config", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.issued" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"data":{"db.password":"p@ss","api":{"key":"k"}},"metadata":{"version":3}}}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVaultProvider(t *testing.T) {
+	t.Setenv("VAULT_TOKEN", "")
+	srv := newVaultStandIn(t)
+
+	p := NewRemoteVault(srv.URL, "secret/app/config", WithVaultAppRole("role", "secret"))
+	require.NoError(t, p.Init())
+	assert.Equal(t, map[string]interface{}{
+		"db":  map[string]interface{}{"password": "p@ss"},
+		"api": map[string]interface{}{"key": "k"},
+	}, p.GetData())
+	vp := p.(*vaultProvider)
+	assert.Equal(t, int64(3), vp.version)
+	assert.Equal(t, 40*time.Minute, vp.renewIn())
+
+	// revoked token login again through app role
+	vp.token = "s.revoked"
+	_, err := vp.load(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "s.issued", vp.token)
+
+	invalid := NewRemoteVault(srv.URL, "secret/app/config", WithVaultAppRole("role", "wrong"))
+	assert.Error(t, invalid.Init())
+	assert.ErrorIs(t, NewRemoteVault(srv.URL, "secret/app/config").Init(), ErrVaultAuthNotDefined)
+}
@@ -45,7 +45,7 @@ func newCipher3DES(key common.Bytes) (*cipher3DES, error) {
 	}
 	c.iv = key[:des.BlockSize]
 	c.block = v
-	c.encrypterBlockMode = cipher.NewCBCEncrypter(c.block, c.key)
-	c.decrypterBlockMode = cipher.NewCBCDecrypter(c.block, c.key)
+	c.encrypterBlockMode = cipher.NewCBCEncrypter(c.block, c.iv)
+	c.decrypterBlockMode = cipher.NewCBCDecrypter(c.block, c.iv)
 	return c, nil
 }
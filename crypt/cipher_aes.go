@@ -12,6 +12,7 @@ import (
 	"crypto/cipher"
 	"encoding/hex"
 	"github.com/evorts/kevlars/common"
+	"io"
 )
 
 type cipherAES struct {
@@ -49,6 +50,20 @@ func (c *cipherAES) Decrypt(value common.Bytes) (common.Bytes, error) {
 	return unPad, nil
 }
 
+func (c *cipherAES) EncryptStream(w io.Writer, chunkSize int) (io.WriteCloser, error) {
+	if c.use != aesGCM {
+		return nil, ErrCipherNotSupported
+	}
+	return newStreamWriter(w, c.key, 0, chunkSize)
+}
+
+func (c *cipherAES) DecryptStream(r io.Reader) (io.Reader, error) {
+	if c.use != aesGCM {
+		return nil, ErrCipherNotSupported
+	}
+	return NewStreamReader(r, c.key)
+}
+
 func (c *cipherAES) generateIV() error {
 	if len(c.iv) > aes.BlockSize {
 		return ErrIVTooLong
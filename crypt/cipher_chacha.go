@@ -0,0 +1,49 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: cipher_chacha
+ * @Date: 14/08/24 08.50
+ */
+
+package crypt
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"github.com/evorts/kevlars/common"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// cipherXChaCha20 is XChaCha20-Poly1305 with random nonce, the extended nonce is safe to be random for
+// practically unlimited number of values under the same key
+//
+//	nonce (24) | sealed value
+type cipherXChaCha20 struct {
+	aead cipher.AEAD
+}
+
+func (c *cipherXChaCha20) Encrypt(value common.Bytes) (common.Bytes, error) {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(value)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, value, nil), nil
+}
+
+func (c *cipherXChaCha20) Decrypt(value common.Bytes) (common.Bytes, error) {
+	if len(value) < c.aead.NonceSize()+c.aead.Overhead() {
+		return nil, ErrMalformedCipherText
+	}
+	return c.aead.Open(nil, value[:c.aead.NonceSize()], value[c.aead.NonceSize():], nil)
+}
+
+func newCipherXChaCha20(key common.Bytes) (*cipherXChaCha20, error) {
+	if len(key) != chacha20poly1305.KeySize {
+		return nil, ErrInvalidKeyDefined
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	return &cipherXChaCha20{aead: aead}, nil
+}
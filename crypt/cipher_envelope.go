@@ -0,0 +1,169 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: cipher_envelope
+ * @Date: 10/08/24 09.12
+ */
+
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"github.com/evorts/kevlars/common"
+	"io"
+)
+
+// envelope ciphertext layout, all integers are big endian:
+//
+//	format (1) | kek version (4) | wrapped dek length (2) | wrapped dek | nonce (12) | sealed value
+//
+// wrapped dek is the nonce followed by the dek sealed by kek, both sealed with the header as additional data
+const (
+	envelopeFormatV1    byte = 1
+	envelopeDEKSize          = 32
+	envelopeHeaderSize       = 1 + 4 + 2
+	envelopeMinimumSize      = envelopeHeaderSize + 12
+)
+
+type cipherEnvelope struct {
+	keks    map[uint32]cipher.AEAD
+	keys    map[uint32]common.Bytes // raw key encryption keys, stream key derived from it
+	current uint32
+}
+
+func (c *cipherEnvelope) Encrypt(value common.Bytes) (common.Bytes, error) {
+	kek := c.keks[c.current]
+	dek := make([]byte, envelopeDEKSize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, err
+	}
+	data, err := newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+	wrappedSize := kek.NonceSize() + envelopeDEKSize + kek.Overhead()
+	out := make([]byte, envelopeHeaderSize, envelopeHeaderSize+wrappedSize+data.NonceSize()+len(value)+data.Overhead())
+	out[0] = envelopeFormatV1
+	binary.BigEndian.PutUint32(out[1:5], c.current)
+	binary.BigEndian.PutUint16(out[5:7], uint16(wrappedSize))
+	header := out[:envelopeHeaderSize]
+	// wrap dek
+	kekNonce := make([]byte, kek.NonceSize())
+	if _, err = rand.Read(kekNonce); err != nil {
+		return nil, err
+	}
+	out = append(out, kekNonce...)
+	out = kek.Seal(out, kekNonce, dek, header)
+	// seal value with dek, bound to the header and wrapped dek
+	nonce := make([]byte, data.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	aad := out[:len(out):len(out)]
+	out = append(out, nonce...)
+	return data.Seal(out, nonce, value, aad), nil
+}
+
+func (c *cipherEnvelope) Decrypt(value common.Bytes) (common.Bytes, error) {
+	version, err := c.version(value)
+	if err != nil {
+		return nil, err
+	}
+	kek, ok := c.keks[version]
+	if !ok {
+		return nil, ErrKeyVersionNotFound
+	}
+	wrappedSize := int(binary.BigEndian.Uint16(value[5:7]))
+	if wrappedSize < kek.NonceSize() || len(value) < envelopeMinimumSize+wrappedSize {
+		return nil, ErrMalformedCipherText
+	}
+	header := value[:envelopeHeaderSize]
+	wrapped := value[envelopeHeaderSize : envelopeHeaderSize+wrappedSize]
+	dek, err := kek.Open(nil, wrapped[:kek.NonceSize()], wrapped[kek.NonceSize():], header)
+	if err != nil {
+		return nil, err
+	}
+	data, err := newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+	aad := value[:envelopeHeaderSize+wrappedSize]
+	rest := value[envelopeHeaderSize+wrappedSize:]
+	return data.Open(nil, rest[:data.NonceSize()], rest[data.NonceSize():], aad)
+}
+
+// version of kek used to encrypt the value
+func (c *cipherEnvelope) version(value common.Bytes) (uint32, error) {
+	if len(value) < envelopeMinimumSize || value[0] != envelopeFormatV1 {
+		return 0, ErrMalformedCipherText
+	}
+	return binary.BigEndian.Uint32(value[1:5]), nil
+}
+
+func (c *cipherEnvelope) ReEncrypt(value common.Bytes) (common.Bytes, bool, error) {
+	version, err := c.version(value)
+	if err != nil {
+		return nil, false, err
+	}
+	if version == c.current {
+		return value, false, nil
+	}
+	plain, err := c.Decrypt(value)
+	if err != nil {
+		return nil, false, err
+	}
+	rs, err := c.Encrypt(plain)
+	return rs, err == nil, err
+}
+
+// EncryptStream with key derived from the current key encryption key, the version carried in stream header
+func (c *cipherEnvelope) EncryptStream(w io.Writer, chunkSize int) (io.WriteCloser, error) {
+	return newStreamWriter(w, c.keys[c.current], c.current, chunkSize)
+}
+
+func (c *cipherEnvelope) DecryptStream(r io.Reader) (io.Reader, error) {
+	return newStreamReader(r, func(version uint32) (common.Bytes, error) {
+		key, ok := c.keys[version]
+		if !ok {
+			return nil, ErrKeyVersionNotFound
+		}
+		return key, nil
+	})
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newCipherEnvelope with key encryption keys by its version, current version used on encrypt.
+// zero current version means the highest version
+func newCipherEnvelope(keks map[uint32]common.Bytes, current uint32) (*cipherEnvelope, error) {
+	if len(keks) < 1 {
+		return nil, ErrNoKeyDefined
+	}
+	c := &cipherEnvelope{keks: make(map[uint32]cipher.AEAD, len(keks)), keys: keks, current: current}
+	for version, key := range keks {
+		if kl := len(key); kl != 16 && kl != 24 && kl != 32 {
+			return nil, ErrInvalidKeyDefined
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		c.keks[version] = aead
+		if current == 0 && version > c.current {
+			c.current = version
+		}
+	}
+	if _, ok := c.keks[c.current]; !ok {
+		return nil, ErrKeyVersionNotFound
+	}
+	return c, nil
+}
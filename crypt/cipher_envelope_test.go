@@ -0,0 +1,46 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: cipher_envelope_test
+ * @Date: 10/08/24 10.02
+ */
+
+package crypt
+
+import (
+	"bytes"
+	"github.com/evorts/kevlars/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEnvelopeRotation(t *testing.T) {
+	v1 := common.Bytes(bytes.Repeat([]byte{1}, 32))
+	v2 := common.Bytes(bytes.Repeat([]byte{2}, 32))
+	old := New(WithCipher(CipherEnvelope), WithKeyVersion(1, v1)).MustInit()
+	encrypted, err := old.Encrypt(common.Bytes("secret"))
+	require.NoError(t, err)
+	// random data key and nonce on every encryption
+	again, err := old.Encrypt(common.Bytes("secret"))
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted, again)
+
+	m := New(WithCipher(CipherEnvelope), WithKeyVersion(1, v1), WithKeyVersion(2, v2)).MustInit()
+	plain, err := m.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", plain.String())
+	migrated, changed, err := m.ReEncrypt(encrypted)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	_, changed, err = m.ReEncrypt(migrated)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	// retired version no longer able to read the migrated value
+	_, err = old.Decrypt(migrated)
+	assert.ErrorIs(t, err, ErrKeyVersionNotFound)
+	// tampering detected
+	migrated[len(migrated)-1] ^= 1
+	_, err = m.Decrypt(migrated)
+	assert.Error(t, err)
+}
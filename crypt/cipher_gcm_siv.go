@@ -0,0 +1,189 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: cipher_gcm_siv
+ * @Date: 14/08/24 09.30
+ */
+
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"github.com/evorts/kevlars/common"
+)
+
+const (
+	gcmSIVNonceSize = 12
+	gcmSIVTagSize   = 16
+)
+
+// cipherGCMSIV is AES-GCM-SIV of RFC 8452, nonce misuse resistant: repeated nonce only reveal
+// whether the same value encrypted, unlike AES-GCM which lose its confidentiality and integrity.
+// key is 16 or 32 bytes
+//
+//	nonce (12) | encrypted value | tag (16)
+type cipherGCMSIV struct {
+	block cipher.Block // key generating key
+	size  int
+}
+
+func (c *cipherGCMSIV) Encrypt(value common.Bytes) (common.Bytes, error) {
+	nonce := make([]byte, gcmSIVNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return append(nonce, c.seal(nonce, value, nil)...), nil
+}
+
+func (c *cipherGCMSIV) Decrypt(value common.Bytes) (common.Bytes, error) {
+	if len(value) < gcmSIVNonceSize+gcmSIVTagSize {
+		return nil, ErrMalformedCipherText
+	}
+	return c.open(value[:gcmSIVNonceSize], value[gcmSIVNonceSize:], nil)
+}
+
+func (c *cipherGCMSIV) seal(nonce, plain, ad []byte) []byte {
+	authKey, enc := c.deriveKeys(nonce)
+	tag := c.tag(authKey, enc, nonce, plain, ad)
+	out := make([]byte, len(plain), len(plain)+gcmSIVTagSize)
+	gcmSIVCTR(enc, tag, out, plain)
+	return append(out, tag...)
+}
+
+func (c *cipherGCMSIV) open(nonce, sealed, ad []byte) ([]byte, error) {
+	if len(sealed) < gcmSIVTagSize {
+		return nil, ErrMalformedCipherText
+	}
+	authKey, enc := c.deriveKeys(nonce)
+	tag := sealed[len(sealed)-gcmSIVTagSize:]
+	plain := make([]byte, len(sealed)-gcmSIVTagSize)
+	gcmSIVCTR(enc, tag, plain, sealed[:len(plain)])
+	if subtle.ConstantTimeCompare(c.tag(authKey, enc, nonce, plain, ad), tag) != 1 {
+		return nil, ErrMalformedCipherText
+	}
+	return plain, nil
+}
+
+// deriveKeys of message authentication and encryption from the key generating key and nonce
+func (c *cipherGCMSIV) deriveKeys(nonce []byte) (authKey []byte, enc cipher.Block) {
+	derived := make([]byte, 0, 16+c.size)
+	in := make([]byte, aes.BlockSize)
+	out := make([]byte, aes.BlockSize)
+	copy(in[4:], nonce)
+	for i := uint32(0); len(derived) < cap(derived); i++ {
+		binary.LittleEndian.PutUint32(in, i)
+		c.block.Encrypt(out, in)
+		derived = append(derived, out[:8]...)
+	}
+	enc, _ = aes.NewCipher(derived[16:])
+	return derived[:16], enc
+}
+
+func (c *cipherGCMSIV) tag(authKey []byte, enc cipher.Block, nonce, plain, ad []byte) []byte {
+	lengths := make([]byte, 16)
+	binary.LittleEndian.PutUint64(lengths, uint64(len(ad))*8)
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(plain))*8)
+	s := polyval(authKey, ad, plain, lengths)
+	subtle.XORBytes(s, s, nonce)
+	s[15] &= 0x7f
+	enc.Encrypt(s, s)
+	return s
+}
+
+// gcmSIVCTR with 32 bit little endian counter on the first 4 bytes of tag which most significant bit set
+func gcmSIVCTR(enc cipher.Block, tag, dst, src []byte) {
+	counter := make([]byte, aes.BlockSize)
+	copy(counter, tag)
+	counter[15] |= 0x80
+	stream := make([]byte, aes.BlockSize)
+	for i := 0; i < len(src); i += aes.BlockSize {
+		enc.Encrypt(stream, counter)
+		end := min(i+aes.BlockSize, len(src))
+		subtle.XORBytes(dst[i:end], src[i:end], stream)
+		binary.LittleEndian.PutUint32(counter, binary.LittleEndian.Uint32(counter)+1)
+	}
+}
+
+// polyval of the zero padded inputs, computed through GHASH of byte reversed values
+func polyval(key []byte, inputs ...[]byte) []byte {
+	h := mulXGHASH(reverseBlock(key))
+	var y [16]byte
+	block := make([]byte, 16)
+	for _, in := range inputs {
+		for i := 0; i < len(in); i += 16 {
+			clear(block)
+			copy(block, in[i:min(i+16, len(in))])
+			rb := reverseBlock(block)
+			subtle.XORBytes(y[:], y[:], rb)
+			y = gfMulGHASH(y, h)
+		}
+	}
+	return reverseBlock(y[:])
+}
+
+func reverseBlock(v []byte) []byte {
+	out := make([]byte, 16)
+	for i := range out {
+		out[i] = v[15-i]
+	}
+	return out
+}
+
+// mulXGHASH multiply by x in GHASH bit order
+func mulXGHASH(v []byte) [16]byte {
+	var out [16]byte
+	hi, lo := binary.BigEndian.Uint64(v), binary.BigEndian.Uint64(v[8:])
+	carry := lo & 1
+	lo = lo>>1 | hi<<63
+	hi >>= 1
+	if carry != 0 {
+		hi ^= 0xe1 << 56
+	}
+	binary.BigEndian.PutUint64(out[:], hi)
+	binary.BigEndian.PutUint64(out[8:], lo)
+	return out
+}
+
+// gfMulGHASH of NIST SP 800-38D
+func gfMulGHASH(x, y [16]byte) [16]byte {
+	var out [16]byte
+	zh, zl := uint64(0), uint64(0)
+	vh, vl := binary.BigEndian.Uint64(y[:]), binary.BigEndian.Uint64(y[8:])
+	xh, xl := binary.BigEndian.Uint64(x[:]), binary.BigEndian.Uint64(x[8:])
+	for i := 0; i < 128; i++ {
+		var bit uint64
+		if i < 64 {
+			bit = xh >> (63 - i) & 1
+		} else {
+			bit = xl >> (127 - i) & 1
+		}
+		if bit != 0 {
+			zh ^= vh
+			zl ^= vl
+		}
+		carry := vl & 1
+		vl = vl>>1 | vh<<63
+		vh >>= 1
+		if carry != 0 {
+			vh ^= 0xe1 << 56
+		}
+	}
+	binary.BigEndian.PutUint64(out[:], zh)
+	binary.BigEndian.PutUint64(out[8:], zl)
+	return out
+}
+
+func newCipherGCMSIV(key common.Bytes) (*cipherGCMSIV, error) {
+	if kl := len(key); kl != 16 && kl != 32 {
+		return nil, ErrInvalidKeyDefined
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &cipherGCMSIV{block: block, size: len(key)}, nil
+}
@@ -0,0 +1,102 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: cipher_hybrid
+ * @Date: 14/08/24 10.40
+ */
+
+package crypt
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"github.com/evorts/kevlars/common"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"io"
+)
+
+// hybrid ciphertext layout, each value encrypted with ChaCha20-Poly1305 key derived by HKDF-SHA256
+// from X25519 shared secret of fresh ephemeral key and the recipient public key:
+//
+//	format (1) | ephemeral public key (32) | sealed value
+//
+// key used once, so the nonce derived along with it. header bound to the value as additional data
+const (
+	hybridFormatV1   byte = 1
+	hybridHeaderSize      = 1 + 32
+)
+
+var hybridKeyInfo = []byte("kevlars hybrid x25519 v1")
+
+type cipherHybrid struct {
+	public  *ecdh.PublicKey
+	private *ecdh.PrivateKey // nil on public key only mode, which could only encrypt
+}
+
+func (c *cipherHybrid) Encrypt(value common.Bytes) (common.Bytes, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := ephemeral.ECDH(c.public)
+	if err != nil {
+		return nil, err
+	}
+	header := append([]byte{hybridFormatV1}, ephemeral.PublicKey().Bytes()...)
+	sealed, err := hybridSeal(shared, header, c.public.Bytes(), value, true)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, sealed...), nil
+}
+
+func (c *cipherHybrid) Decrypt(value common.Bytes) (common.Bytes, error) {
+	if c.private == nil {
+		return nil, ErrPrivateKeyNotDefined
+	}
+	if len(value) < hybridHeaderSize+chacha20poly1305.Overhead || value[0] != hybridFormatV1 {
+		return nil, ErrMalformedCipherText
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(value[1:hybridHeaderSize])
+	if err != nil {
+		return nil, ErrMalformedCipherText
+	}
+	shared, err := c.private.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	return hybridSeal(shared, value[:hybridHeaderSize], c.public.Bytes(), value[hybridHeaderSize:], false)
+}
+
+// hybridSeal seal or open value with key and nonce derived from shared secret, bound to both public keys
+func hybridSeal(shared, header, recipient, value []byte, seal bool) ([]byte, error) {
+	salt := append(append([]byte{}, header[1:]...), recipient...)
+	derived := make([]byte, chacha20poly1305.KeySize+chacha20poly1305.NonceSize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, hybridKeyInfo), derived); err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(derived[:chacha20poly1305.KeySize])
+	if err != nil {
+		return nil, err
+	}
+	nonce := derived[chacha20poly1305.KeySize:]
+	if seal {
+		return aead.Seal(nil, nonce, value, header), nil
+	}
+	return aead.Open(nil, nonce, value, header)
+}
+
+func newCipherHybrid(private *ecdh.PrivateKey, public *ecdh.PublicKey) (*cipherHybrid, error) {
+	if private != nil {
+		public = private.PublicKey()
+	}
+	if public == nil {
+		return nil, ErrPublicKeyNotDefined
+	}
+	if public.Curve() != ecdh.X25519() {
+		return nil, ErrUnsupportedKey
+	}
+	return &cipherHybrid{public: public, private: private}, nil
+}
@@ -12,26 +12,42 @@ import (
 	"crypto/rsa"
 	"github.com/evorts/kevlars/common"
 	"hash"
+	"sync"
 )
 
 type cipherRsa struct {
 	pubKey     *rsa.PublicKey
-	privateKey *rsa.PrivateKey
+	privateKey *rsa.PrivateKey // nil on public key only mode, which could only encrypt
 	hash       hash.Hash
+	mu         sync.Mutex // hash is stateful, shared between calls
 }
 
 func (c *cipherRsa) Encrypt(value common.Bytes) (common.Bytes, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return rsa.EncryptOAEP(c.hash, rand.Reader, c.pubKey, value, nil)
 }
 
 func (c *cipherRsa) Decrypt(value common.Bytes) (common.Bytes, error) {
+	if c.privateKey == nil {
+		return nil, ErrPrivateKeyNotDefined
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return rsa.DecryptOAEP(c.hash, rand.Reader, c.privateKey, value, nil)
 }
 
-func newCipherRsa(privateKey *rsa.PrivateKey, hash hash.Hash) (*cipherRsa, error) {
+// newCipherRsa with OAEP padding, public key ignored when private key given
+func newCipherRsa(privateKey *rsa.PrivateKey, publicKey *rsa.PublicKey, hash hash.Hash) (*cipherRsa, error) {
+	if privateKey != nil {
+		publicKey = &privateKey.PublicKey
+	}
+	if publicKey == nil {
+		return nil, ErrPrivateKeyNotDefined
+	}
 	return &cipherRsa{
 		privateKey: privateKey,
-		pubKey:     &privateKey.PublicKey,
+		pubKey:     publicKey,
 		hash:       hash,
 	}, nil
 }
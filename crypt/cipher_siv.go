@@ -0,0 +1,146 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: cipher_siv
+ * @Date: 13/08/24 09.10
+ */
+
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"github.com/evorts/kevlars/common"
+)
+
+// cipherSIV is deterministic authenticated encryption of RFC 5297 (AES-SIV), the same value always produce
+// the same ciphertext so it could be searched by equality, at the cost of revealing which values are equal.
+// key is 32, 48 or 64 bytes, first half used by S2V (AES-CMAC) and the second half by AES-CTR.
+//
+//	synthetic iv (16) | encrypted value
+type cipherSIV struct {
+	mac cipher.Block
+	ctr cipher.Block
+}
+
+func (c *cipherSIV) Encrypt(value common.Bytes) (common.Bytes, error) {
+	return c.seal(value), nil
+}
+
+func (c *cipherSIV) Decrypt(value common.Bytes) (common.Bytes, error) {
+	return c.open(value)
+}
+
+func (c *cipherSIV) seal(plain []byte, ad ...[]byte) []byte {
+	v := c.s2v(append(ad, plain)...)
+	out := make([]byte, aes.BlockSize+len(plain))
+	copy(out, v)
+	c.xorCTR(out[aes.BlockSize:], plain, v)
+	return out
+}
+
+func (c *cipherSIV) open(sealed []byte, ad ...[]byte) ([]byte, error) {
+	if len(sealed) < aes.BlockSize {
+		return nil, ErrMalformedCipherText
+	}
+	v := sealed[:aes.BlockSize]
+	plain := make([]byte, len(sealed)-aes.BlockSize)
+	c.xorCTR(plain, sealed[aes.BlockSize:], v)
+	if subtle.ConstantTimeCompare(c.s2v(append(ad, plain)...), v) != 1 {
+		return nil, ErrMalformedCipherText
+	}
+	return plain, nil
+}
+
+// xorCTR with counter of synthetic iv which 31st and 63rd bits cleared
+func (c *cipherSIV) xorCTR(dst, src, v []byte) {
+	q := make([]byte, aes.BlockSize)
+	copy(q, v)
+	q[8] &= 0x7f
+	q[12] &= 0x7f
+	cipher.NewCTR(c.ctr, q).XORKeyStream(dst, src)
+}
+
+// s2v of strings, the last one is the plain text
+func (c *cipherSIV) s2v(s ...[]byte) []byte {
+	d := c.cmac(make([]byte, aes.BlockSize))
+	for _, v := range s[:len(s)-1] {
+		d = dbl(d)
+		subtle.XORBytes(d, d, c.cmac(v))
+	}
+	last := s[len(s)-1]
+	var t []byte
+	if len(last) >= aes.BlockSize {
+		t = append([]byte{}, last...)
+		end := t[len(t)-aes.BlockSize:]
+		subtle.XORBytes(end, end, d)
+	} else {
+		t = dbl(d)
+		subtle.XORBytes(t, t, pad(last))
+	}
+	return c.cmac(t)
+}
+
+// cmac of RFC 4493
+func (c *cipherSIV) cmac(msg []byte) []byte {
+	k1 := make([]byte, aes.BlockSize)
+	c.mac.Encrypt(k1, k1)
+	k1 = dbl(k1)
+	var last []byte
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	if n > 0 && len(msg)%aes.BlockSize == 0 {
+		last = append([]byte{}, msg[(n-1)*aes.BlockSize:]...)
+		subtle.XORBytes(last, last, k1)
+	} else {
+		if n < 1 {
+			n = 1
+		}
+		last = pad(msg[(n-1)*aes.BlockSize:])
+		subtle.XORBytes(last, last, dbl(k1))
+	}
+	x := make([]byte, aes.BlockSize)
+	for i := 0; i < n-1; i++ {
+		subtle.XORBytes(x, x, msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		c.mac.Encrypt(x, x)
+	}
+	subtle.XORBytes(x, x, last)
+	c.mac.Encrypt(x, x)
+	return x
+}
+
+// dbl is multiplication by x in GF(2^128)
+func dbl(v []byte) []byte {
+	out := make([]byte, aes.BlockSize)
+	for i := 0; i < aes.BlockSize-1; i++ {
+		out[i] = v[i]<<1 | v[i+1]>>7
+	}
+	out[aes.BlockSize-1] = v[aes.BlockSize-1] << 1
+	if v[0]&0x80 != 0 {
+		out[aes.BlockSize-1] ^= 0x87
+	}
+	return out
+}
+
+// pad incomplete block with single 1 bit followed by zeros
+func pad(v []byte) []byte {
+	out := make([]byte, aes.BlockSize)
+	copy(out, v)
+	out[len(v)] = 0x80
+	return out
+}
+
+func newCipherSIV(key common.Bytes) (*cipherSIV, error) {
+	if kl := len(key); kl != 32 && kl != 48 && kl != 64 {
+		return nil, ErrInvalidKeyDefined
+	}
+	mac, err := aes.NewCipher(key[:len(key)/2])
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := aes.NewCipher(key[len(key)/2:])
+	if err != nil {
+		return nil, err
+	}
+	return &cipherSIV{mac: mac, ctr: ctr}, nil
+}
@@ -0,0 +1,125 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: cipher_test
+ * @Date: 14/08/24 11.30
+ */
+
+package crypt
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"github.com/evorts/kevlars/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCipherGCMSIV(t *testing.T) {
+	// RFC 8452 appendix C.1 and C.2
+	const (
+		key128 = "01000000000000000000000000000000"
+		key256 = "0100000000000000000000000000000000000000000000000000000000000000"
+		blocks = "02000000000000000000000000000000" + "03000000000000000000000000000000" +
+			"04000000000000000000000000000000" + "05000000000000000000000000000000"
+	)
+	nonce, _ := hex.DecodeString("030000000000000000000000")
+	for _, tc := range []struct {
+		key, plain, ad, want string
+	}{
+		{key128, "0100000000000000", "", "b5d839330ac7b786578782fff6013b815b287c22493a364c"},
+		{key128, blocks[:96], "01", "50c8303ea93925d64090d07bd109dfd9515a5a33431019c17d93465999a8b005" +
+			"3201d723120a8562b838cdff25bf9d1e6a8cc3865f76897c2e4b245cf31c51f2"},
+		{key128, blocks, "01", "2f5c64059db55ee0fb847ed513003746aca4e61c711b5de2e7a77ffd02da42fe" +
+			"ec601910d3467bb8b36ebbaebce5fba30d36c95f48a3e7980f0e7ac299332a80cdc46ae475563de037001ef84ae21744"},
+		{key256, "0100000000000000", "", "c2ef328e5c71c83b843122130f7364b761e0b97427e3df28"},
+		{key256, blocks[:96], "01", "c67a1f0f567a5198aa1fcc8e3f21314336f7f51ca8b1af61feac35a86416fa47" +
+			"fbca3b5f749cdf564527f2314f42fe2503332742b228c647173616cfd44c54eb"},
+		{key256, blocks, "01", "67fd45e126bfb9a79930c43aad2d36967d3f0e4d217c1e551f59727870beefc9" +
+			"8cb933a8fce9de887b1e40799988db1fc3f91880ed405b2dd298318858467c895bde0285037c5de81e5b570a049b62a0"},
+	} {
+		k, _ := hex.DecodeString(tc.key)
+		plain, _ := hex.DecodeString(tc.plain)
+		ad, _ := hex.DecodeString(tc.ad)
+		c, err := newCipherGCMSIV(k)
+		require.NoError(t, err)
+		sealed := c.seal(nonce, plain, ad)
+		assert.Equal(t, tc.want, hex.EncodeToString(sealed))
+		rs, err := c.open(nonce, sealed, ad)
+		require.NoError(t, err)
+		assert.Equal(t, plain, rs)
+		// additional data authenticated along with the cipher text
+		_, err = c.open(nonce, sealed, []byte{2})
+		assert.ErrorIs(t, err, ErrMalformedCipherText)
+	}
+}
+
+func pemOf(t *testing.T, typ string, der []byte, err error) string {
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}))
+}
+
+func TestCiphers(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	xKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaPrivate := pemOf(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), nil)
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	rsaPublic := pemOf(t, "PUBLIC KEY", der, err)
+	der, err = x509.MarshalPKCS8PrivateKey(xKey)
+	xPrivate := pemOf(t, "PRIVATE KEY", der, err)
+	der, err = x509.MarshalPKIXPublicKey(xKey.PublicKey())
+	xPublic := pemOf(t, "PUBLIC KEY", der, err)
+
+	tests := map[string]struct {
+		encrypter []common.Option[manager]
+		decrypter []common.Option[manager]
+	}{
+		"3des": {
+			encrypter: []common.Option[manager]{WithCipher(Cipher3DES), WithKey(bytes.Repeat([]byte{1}, 24))},
+		},
+		"xchacha20-poly1305": {
+			encrypter: []common.Option[manager]{WithCipher(CipherXChaCha20), WithKey(bytes.Repeat([]byte{1}, 32))},
+		},
+		"aes-gcm-siv": {
+			encrypter: []common.Option[manager]{WithCipher(CipherAESGCMSIV), WithKey(bytes.Repeat([]byte{1}, 32))},
+		},
+		"rsa-oaep public key only": {
+			encrypter: []common.Option[manager]{WithCipher(CipherRSAOAEP), WithPublicKey(rsaPublic)},
+			decrypter: []common.Option[manager]{WithCipher(CipherRSAOAEP), WithPrivateKey(rsaPrivate)},
+		},
+		"hybrid public key only": {
+			encrypter: []common.Option[manager]{WithCipher(CipherHybridX25519), WithPublicKey(xPublic)},
+			decrypter: []common.Option[manager]{WithCipher(CipherHybridX25519), WithPrivateKey(xPrivate)},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			enc := New(tc.encrypter...).MustInit()
+			dec := enc
+			if tc.decrypter != nil {
+				dec = New(tc.decrypter...).MustInit()
+				_, err = enc.Decrypt(common.Bytes("anything"))
+				assert.ErrorIs(t, err, ErrPrivateKeyNotDefined)
+			}
+			sealed, err := enc.Encrypt(common.Bytes("sensitive value"))
+			require.NoError(t, err)
+			rs, err := dec.Decrypt(sealed)
+			require.NoError(t, err)
+			assert.Equal(t, "sensitive value", rs.String())
+			if name == "3des" {
+				return
+			}
+			sealed[len(sealed)-1] ^= 1
+			_, err = dec.Decrypt(sealed)
+			assert.Error(t, err)
+		})
+	}
+}
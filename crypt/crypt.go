@@ -8,17 +8,28 @@
 package crypt
 
 import (
+	"crypto"
 	"crypto/cipher"
+	"crypto/ecdh"
 	"crypto/rsa"
 	"crypto/sha512"
 	"github.com/evorts/kevlars/common"
 	"github.com/evorts/kevlars/rules"
 	"hash"
+	"io"
 )
 
 type Manager interface {
 	Encrypter
 	Decrypter
+	// ReEncrypt value encrypted with older key version using the current one, for background migration after rotation.
+	// value already on the current version returned as is with changed false, only supported by envelope cipher
+	ReEncrypt(value common.Bytes) (rs common.Bytes, changed bool, err error)
+	// EncryptStream return writer encrypting everything written into w in chunks, Close it to finish the stream.
+	// only supported by AES-GCM and envelope cipher
+	EncryptStream(w io.Writer) (io.WriteCloser, error)
+	// DecryptStream of stream produced by EncryptStream, read fail when the stream tampered or truncated
+	DecryptStream(r io.Reader) (io.Reader, error)
 
 	common.Init[Manager]
 	AddOptions(opts ...common.Option[manager]) Manager
@@ -29,6 +40,15 @@ type crypter interface {
 	Decrypter
 }
 
+type reEncrypter interface {
+	ReEncrypt(value common.Bytes) (common.Bytes, bool, error)
+}
+
+type streamer interface {
+	EncryptStream(w io.Writer, chunkSize int) (io.WriteCloser, error)
+	DecryptStream(r io.Reader) (io.Reader, error)
+}
+
 type manager struct {
 	crypter crypter
 	hash    hash.Hash
@@ -37,17 +57,26 @@ type manager struct {
 	iv      common.Bytes
 	cb      cipher.Block
 	pk      *rsa.PrivateKey
+	xpk     *ecdh.PrivateKey        // x25519 private key of hybrid cipher
+	pub     crypto.PublicKey        // public key of asymmetric cipher when private key not given
+	keks    map[uint32]common.Bytes // key encryption keys of envelope cipher by version
+	version uint32                  // current version of key encryption key
+	chunk   int                     // chunk size of stream encryption
 }
 
 func (m *manager) Init() error {
 	if m.cipher == "" {
 		return ErrNoCipherDefined
 	}
-	if m.key == nil {
+	if m.key == nil && m.cipher != CipherEnvelope && !m.cipher.asymmetric() {
 		return ErrNoKeyDefined
 	}
 	var err error
 	switch m.cipher {
+	case CipherEnvelope:
+		m.crypter, err = newCipherEnvelope(m.keks, m.version)
+	case CipherAESSIV:
+		m.crypter, err = newCipherSIV(m.key)
 	case Cipher3DES:
 		m.crypter, err = newCipher3DES(m.key)
 	case CipherAESCBC, CipherAESGCM:
@@ -55,14 +84,19 @@ func (m *manager) Init() error {
 			return ErrNoIVDefined
 		}
 		m.crypter, err = newCipherAes(rules.Iif(m.cipher == CipherAESGCM, aesGCM, aesCBC), m.key, m.iv)
-	case CipherRSA:
-		if m.pk == nil {
-			return ErrPrivateKeyNotDefined
-		}
+	case CipherXChaCha20:
+		m.crypter, err = newCipherXChaCha20(m.key)
+	case CipherAESGCMSIV:
+		m.crypter, err = newCipherGCMSIV(m.key)
+	case CipherRSA, CipherRSAOAEP:
 		if m.hash == nil {
 			return ErrHasherNotDefined
 		}
-		m.crypter, err = newCipherRsa(m.pk, m.hash)
+		pub, _ := m.pub.(*rsa.PublicKey)
+		m.crypter, err = newCipherRsa(m.pk, pub, m.hash)
+	case CipherHybridX25519:
+		pub, _ := m.pub.(*ecdh.PublicKey)
+		m.crypter, err = newCipherHybrid(m.xpk, pub)
 	default:
 		err = ErrCipherNotSupported
 	}
@@ -87,6 +121,30 @@ func (m *manager) Decrypt(value common.Bytes) (common.Bytes, error) {
 	return m.crypter.Decrypt(value)
 }
 
+func (m *manager) ReEncrypt(value common.Bytes) (common.Bytes, bool, error) {
+	re, ok := m.crypter.(reEncrypter)
+	if !ok {
+		return nil, false, ErrCipherNotSupported
+	}
+	return re.ReEncrypt(value)
+}
+
+func (m *manager) EncryptStream(w io.Writer) (io.WriteCloser, error) {
+	st, ok := m.crypter.(streamer)
+	if !ok {
+		return nil, ErrCipherNotSupported
+	}
+	return st.EncryptStream(w, m.chunk)
+}
+
+func (m *manager) DecryptStream(r io.Reader) (io.Reader, error) {
+	st, ok := m.crypter.(streamer)
+	if !ok {
+		return nil, ErrCipherNotSupported
+	}
+	return st.DecryptStream(r)
+}
+
 func (m *manager) AddOptions(opts ...common.Option[manager]) Manager {
 	for _, opt := range opts {
 		opt.Apply(m)
@@ -96,7 +154,9 @@ func (m *manager) AddOptions(opts ...common.Option[manager]) Manager {
 
 func New(opts ...common.Option[manager]) Manager {
 	m := &manager{
-		hash: sha512.New(),
+		hash:  sha512.New(),
+		keks:  make(map[uint32]common.Bytes),
+		chunk: DefaultStreamChunkSize,
 	}
 	for _, opt := range opts {
 		opt.Apply(m)
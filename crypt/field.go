@@ -0,0 +1,216 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: field
+ * @Date: 13/08/24 10.25
+ */
+
+package crypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/evorts/kevlars/common"
+	"strings"
+	"sync"
+)
+
+// encryptedFieldPrefix mark stored value as cipher text, value without it is plain text written before
+// the column encrypted and read as is until rewritten, see UserManager.EncryptFields of auth for example
+const encryptedFieldPrefix = "enc:v1:"
+
+// fields hold managers of encrypted column types. sql.Scanner and driver.Valuer could not receive
+// dependency, so the managers registered once on start up. non-empty value could not be written nor
+// its cipher text read until registered, so sensitive value never silently stored as plain text
+var fields = struct {
+	sync.RWMutex
+	randomized    Manager
+	deterministic Manager
+	indexer       BlindIndexer
+}{}
+
+// RegisterFieldManager used by EncryptedString, e.g. envelope cipher
+func RegisterFieldManager(m Manager) {
+	fields.Lock()
+	defer fields.Unlock()
+	fields.randomized = m
+}
+
+// RegisterDeterministicFieldManager used by DeterministicString, should be deterministic cipher such as CipherAESSIV
+func RegisterDeterministicFieldManager(m Manager) {
+	fields.Lock()
+	defer fields.Unlock()
+	fields.deterministic = m
+}
+
+// RegisterBlindIndexer used by BlindIndex
+func RegisterBlindIndexer(v BlindIndexer) {
+	fields.Lock()
+	defer fields.Unlock()
+	fields.indexer = v
+}
+
+func fieldManager(deterministic bool) Manager {
+	fields.RLock()
+	defer fields.RUnlock()
+	if deterministic {
+		return fields.deterministic
+	}
+	return fields.randomized
+}
+
+// EncryptedString column encrypted by the registered field manager, stored as base64 text.
+// each write produce different ciphertext, so it could not be searched, pair it with BlindIndex column for lookup
+type EncryptedString string
+
+func (s *EncryptedString) Scan(value interface{}) error {
+	rs, err := scanField(fieldManager(false), value)
+	*s = EncryptedString(rs)
+	return err
+}
+
+func (s EncryptedString) Value() (driver.Value, error) {
+	return valueField(fieldManager(false), string(s))
+}
+
+func (s EncryptedString) String() string { return string(s) }
+
+// DeterministicString column encrypted by the registered deterministic field manager, stored as base64 text.
+// equal values produce equal ciphertext, so it could be used on equality condition and unique index
+type DeterministicString string
+
+func (s *DeterministicString) Scan(value interface{}) error {
+	rs, err := scanField(fieldManager(true), value)
+	*s = DeterministicString(rs)
+	return err
+}
+
+func (s DeterministicString) Value() (driver.Value, error) {
+	return valueField(fieldManager(true), string(s))
+}
+
+func (s DeterministicString) String() string { return string(s) }
+
+// scanField decrypt column value, empty value kept empty and legacy plain text returned as is
+func scanField(m Manager, value interface{}) (string, error) {
+	var raw string
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return "", fmt.Errorf("unsupported type %T of encrypted field", value)
+	}
+	if !strings.HasPrefix(raw, encryptedFieldPrefix) {
+		return raw, nil
+	}
+	if m == nil {
+		return "", ErrFieldManagerNotRegistered
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(raw, encryptedFieldPrefix))
+	if err != nil {
+		return "", ErrMalformedCipherText
+	}
+	plain, err := m.Decrypt(sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// IsEncryptedField tell whether stored value of EncryptedString column is cipher text of the registered field manager,
+// used to find rows written as plain text so they could be encrypted. empty value considered encrypted,
+// while cipher text which could not be decrypted is an error rather than plain text
+func IsEncryptedField(value string) (bool, error) {
+	m := fieldManager(false)
+	if m == nil {
+		return false, ErrFieldManagerNotRegistered
+	}
+	if len(value) < 1 {
+		return true, nil
+	}
+	if !strings.HasPrefix(value, encryptedFieldPrefix) {
+		return false, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedFieldPrefix))
+	if err != nil {
+		return false, ErrMalformedCipherText
+	}
+	if _, err = m.Decrypt(sealed); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// valueField encrypt column value, empty value kept empty so it still mean not set on query
+func valueField(m Manager, value string) (driver.Value, error) {
+	if len(value) < 1 {
+		return value, nil
+	}
+	if m == nil {
+		return nil, ErrFieldManagerNotRegistered
+	}
+	sealed, err := m.Encrypt(common.Bytes(value))
+	if err != nil {
+		return nil, err
+	}
+	return encryptedFieldPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// BlindIndexer produce searchable index of sensitive value to be stored on companion column,
+// value looked up by its index without decrypting the column
+type BlindIndexer interface {
+	Index(value string) string
+}
+
+type blindIndexer struct {
+	key       common.Bytes
+	size      int
+	normalize func(string) string
+}
+
+// Index is hex of truncated HMAC-SHA256 of normalized value, empty value has empty index
+func (b *blindIndexer) Index(value string) string {
+	value = b.normalize(value)
+	if len(value) < 1 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:b.size])
+}
+
+// BlindIndex of value with the registered blind indexer, empty value has empty index even when not registered
+func BlindIndex(value string) (string, error) {
+	fields.RLock()
+	indexer := fields.indexer
+	fields.RUnlock()
+	if indexer == nil {
+		if len(NormalizeIndex(value)) < 1 {
+			return "", nil
+		}
+		return "", ErrBlindIndexerNotRegistered
+	}
+	return indexer.Index(value), nil
+}
+
+// NormalizeIndex is the default normalization of blind index, so lookup is case and surrounding space insensitive
+func NormalizeIndex(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// NewBlindIndexer with dedicated key, should not be the key used to encrypt the column
+func NewBlindIndexer(key common.Bytes, opts ...common.Option[blindIndexer]) BlindIndexer {
+	b := &blindIndexer{key: key, size: sha256.Size, normalize: NormalizeIndex}
+	for _, opt := range opts {
+		opt.Apply(b)
+	}
+	return b
+}
@@ -0,0 +1,103 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: field_test
+ * @Date: 13/08/24 11.40
+ */
+
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"github.com/evorts/kevlars/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCipherSIV(t *testing.T) {
+	// RFC 5297 appendix A.1
+	key, _ := hex.DecodeString("fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+	ad, _ := hex.DecodeString("101112131415161718191a1b1c1d1e1f2021222324252627")
+	plain, _ := hex.DecodeString("112233445566778899aabbccddee")
+	c, err := newCipherSIV(key)
+	require.NoError(t, err)
+	sealed := c.seal(plain, ad)
+	assert.Equal(t, "85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c", hex.EncodeToString(sealed))
+	rs, err := c.open(sealed, ad)
+	require.NoError(t, err)
+	assert.Equal(t, plain, rs)
+	sealed[len(sealed)-1] ^= 1
+	_, err = c.open(sealed, ad)
+	assert.ErrorIs(t, err, ErrMalformedCipherText)
+}
+
+func TestEncryptedField(t *testing.T) {
+	defer func() {
+		RegisterFieldManager(nil)
+		RegisterDeterministicFieldManager(nil)
+		RegisterBlindIndexer(nil)
+	}()
+	// refused until registered, empty value still allowed
+	_, err := EncryptedString("john@example.com").Value()
+	assert.ErrorIs(t, err, ErrFieldManagerNotRegistered)
+	var es EncryptedString
+	assert.ErrorIs(t, es.Scan("enc:v1:AAAA"), ErrFieldManagerNotRegistered)
+	// plain text written before encryption enabled read as is
+	require.NoError(t, es.Scan("john@example.com"))
+	assert.Equal(t, EncryptedString("john@example.com"), es)
+	v, err := EncryptedString("").Value()
+	require.NoError(t, err)
+	assert.Equal(t, "", v)
+	_, err = BlindIndex(" John@Example.com")
+	assert.ErrorIs(t, err, ErrBlindIndexerNotRegistered)
+	_, err = IsEncryptedField("john@example.com")
+	assert.ErrorIs(t, err, ErrFieldManagerNotRegistered)
+
+	RegisterFieldManager(New(WithCipher(CipherEnvelope), WithKeyVersion(1, bytes.Repeat([]byte{1}, 32))).MustInit())
+	RegisterDeterministicFieldManager(New(WithCipher(CipherAESSIV), WithKey(bytes.Repeat([]byte{2}, 64))).MustInit())
+	RegisterBlindIndexer(NewBlindIndexer(common.Bytes("index key"), BlindIndexerWithSize(16)))
+
+	first, err := EncryptedString("john@example.com").Value()
+	require.NoError(t, err)
+	second, err := EncryptedString("john@example.com").Value()
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+	ok, err := IsEncryptedField(first.(string))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = IsEncryptedField("john@example.com")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, err = IsEncryptedField("enc:v1:AAAA")
+	assert.Error(t, err)
+	require.NoError(t, es.Scan([]byte(first.(string))))
+	assert.Equal(t, EncryptedString("john@example.com"), es)
+
+	first, err = DeterministicString("john@example.com").Value()
+	require.NoError(t, err)
+	second, err = DeterministicString("john@example.com").Value()
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	var ds DeterministicString
+	require.NoError(t, ds.Scan(first))
+	assert.Equal(t, DeterministicString("john@example.com"), ds)
+
+	// empty value kept empty
+	empty, err := DeterministicString("").Value()
+	require.NoError(t, err)
+	assert.Equal(t, "", empty)
+	require.NoError(t, ds.Scan(nil))
+	assert.Equal(t, DeterministicString(""), ds)
+
+	index, err := BlindIndex("john@example.com")
+	require.NoError(t, err)
+	assert.Len(t, index, 32)
+	other, err := BlindIndex(" JOHN@example.com ")
+	require.NoError(t, err)
+	assert.Equal(t, index, other)
+	empty, err = BlindIndex("")
+	require.NoError(t, err)
+	assert.Equal(t, "", empty)
+}
@@ -0,0 +1,313 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: hasher
+ * @Date: 12/08/24 10.05
+ */
+
+package crypt
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"github.com/evorts/kevlars/common"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+	"hash"
+	"math/bits"
+	"strings"
+)
+
+type Hasher interface {
+	SHA3(use SHA3, bytes common.Bytes) (common.Bytes, error)
+
+	// HashPassword with the configured algorithm, the result encode the algorithm, parameters and salt:
+	//
+	//	argon2id: $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+	//	scrypt:   $scrypt$ln=15,r=8,p=1$<salt>$<hash>
+	//	bcrypt:   $2a$10$<salt and hash>
+	HashPassword(password common.Bytes) (string, error)
+	// Verify password against the encoded hash of any supported algorithm, mismatch is not an error
+	Verify(password common.Bytes, encoded string) (bool, error)
+	// NeedsRehash when the encoded hash not produced by the configured algorithm and parameters,
+	// so it could be upgraded on the next successful verification
+	NeedsRehash(encoded string) bool
+
+	// Sign message with HMAC, e.g. signature of webhook payload
+	Sign(use HMAC, key, message common.Bytes) (common.Bytes, error)
+	// VerifySignature of message in constant time
+	VerifySignature(use HMAC, key, message, signature common.Bytes) bool
+
+	// BLAKE2b of value with the given size in bytes (1 - 64), keyed when key given (up to 64 bytes)
+	BLAKE2b(size int, key, value common.Bytes) (common.Bytes, error)
+}
+
+// Argon2idParams of password hashing, memory in KiB
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  int
+	KeyLength   int
+}
+
+// ScryptParams of password hashing, cost (N) should be power of 2
+type ScryptParams struct {
+	Cost            int
+	BlockSize       int
+	Parallelization int
+	SaltLength      int
+	KeyLength       int
+}
+
+var (
+	// DefaultArgon2idParams as recommended by OWASP
+	DefaultArgon2idParams = Argon2idParams{Memory: 19 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	DefaultScryptParams   = ScryptParams{Cost: 1 << 15, BlockSize: 8, Parallelization: 1, SaltLength: 16, KeyLength: 32}
+)
+
+var passwordEncoding = base64.RawStdEncoding
+
+type hasher struct {
+	password   PasswordAlgorithm
+	argon2id   Argon2idParams
+	scrypt     ScryptParams
+	bcryptCost int
+}
+
+func (h *hasher) SHA3(use SHA3, bytes common.Bytes) (common.Bytes, error) {
+	var hashing hash.Hash
+	switch use {
+	case Shake128:
+		hashing = sha3.NewShake128()
+	case Shake256:
+		hashing = sha3.NewShake256()
+	case Bit224:
+		hashing = sha3.New224()
+	case Bit256:
+		hashing = sha3.New256()
+	case Bit384:
+		hashing = sha3.New384()
+	case Bit512:
+		hashing = sha3.New512()
+	default:
+		return nil, ErrInvalidSHA3Instance
+	}
+	hashing.Write(bytes)
+	return hashing.Sum(nil), nil
+}
+
+func (h *hasher) HashPassword(password common.Bytes) (string, error) {
+	if len(password) < 1 {
+		return "", ErrEmptyPassword
+	}
+	switch h.password {
+	case PasswordArgon2id:
+		p := h.argon2id
+		salt, err := randomSalt(p.SaltLength)
+		if err != nil {
+			return "", err
+		}
+		key := argon2.IDKey(password, salt, p.Iterations, p.Memory, p.Parallelism, uint32(p.KeyLength))
+		return fmt.Sprintf(
+			"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+			passwordEncoding.EncodeToString(salt), passwordEncoding.EncodeToString(key),
+		), nil
+	case PasswordScrypt:
+		p := h.scrypt
+		salt, err := randomSalt(p.SaltLength)
+		if err != nil {
+			return "", err
+		}
+		key, err := scrypt.Key(password, salt, p.Cost, p.BlockSize, p.Parallelization, p.KeyLength)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(
+			"$scrypt$ln=%d,r=%d,p=%d$%s$%s", bits.Len(uint(p.Cost))-1, p.BlockSize, p.Parallelization,
+			passwordEncoding.EncodeToString(salt), passwordEncoding.EncodeToString(key),
+		), nil
+	case PasswordBcrypt:
+		b, err := bcrypt.GenerateFromPassword(password, h.bcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+	return "", ErrUnsupportedPassword
+}
+
+func (h *hasher) Verify(password common.Bytes, encoded string) (bool, error) {
+	algorithm, err := passwordAlgorithmOf(encoded)
+	if err != nil {
+		return false, err
+	}
+	switch algorithm {
+	case PasswordArgon2id:
+		p, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, err
+		}
+		rs := argon2.IDKey(password, salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(rs, key) == 1, nil
+	case PasswordScrypt:
+		p, salt, key, err := decodeScrypt(encoded)
+		if err != nil {
+			return false, err
+		}
+		rs, err := scrypt.Key(password, salt, p.Cost, p.BlockSize, p.Parallelization, len(key))
+		if err != nil {
+			return false, err
+		}
+		return subtle.ConstantTimeCompare(rs, key) == 1, nil
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(encoded), password)
+	if err == nil {
+		return true, nil
+	}
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return false, ErrMalformedPassword
+}
+
+func (h *hasher) NeedsRehash(encoded string) bool {
+	algorithm, err := passwordAlgorithmOf(encoded)
+	if err != nil || algorithm != h.password {
+		return true
+	}
+	switch algorithm {
+	case PasswordArgon2id:
+		p, salt, key, err := decodeArgon2id(encoded)
+		want := h.argon2id
+		return err != nil || p.Memory != want.Memory || p.Iterations != want.Iterations ||
+			p.Parallelism != want.Parallelism || len(salt) != want.SaltLength || len(key) != want.KeyLength
+	case PasswordScrypt:
+		p, salt, key, err := decodeScrypt(encoded)
+		want := h.scrypt
+		return err != nil || p.Cost != want.Cost || p.BlockSize != want.BlockSize ||
+			p.Parallelization != want.Parallelization || len(salt) != want.SaltLength || len(key) != want.KeyLength
+	}
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.bcryptCost
+}
+
+func (h *hasher) Sign(use HMAC, key, message common.Bytes) (common.Bytes, error) {
+	var fn func() hash.Hash
+	switch use {
+	case HMACSHA256:
+		fn = sha256.New
+	case HMACSHA512:
+		fn = sha512.New
+	default:
+		return nil, ErrInvalidHMACInstance
+	}
+	mac := hmac.New(fn, key)
+	mac.Write(message)
+	return mac.Sum(nil), nil
+}
+
+func (h *hasher) VerifySignature(use HMAC, key, message, signature common.Bytes) bool {
+	expected, err := h.Sign(use, key, message)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expected, signature)
+}
+
+func (h *hasher) BLAKE2b(size int, key, value common.Bytes) (common.Bytes, error) {
+	hashing, err := blake2b.New(size, key)
+	if err != nil {
+		return nil, err
+	}
+	hashing.Write(value)
+	return hashing.Sum(nil), nil
+}
+
+func passwordAlgorithmOf(encoded string) (PasswordAlgorithm, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return PasswordArgon2id, nil
+	case strings.HasPrefix(encoded, "$scrypt$"):
+		return PasswordScrypt, nil
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return PasswordBcrypt, nil
+	}
+	return "", ErrUnsupportedPassword
+}
+
+// decodePassword split encoded hash of $<algorithm>$[v=<version>$]<params>$<salt>$<hash>
+func decodePassword(encoded string, parts int) (fields []string, salt, key []byte, err error) {
+	fields = strings.Split(encoded, "$")
+	if len(fields) != parts {
+		return nil, nil, nil, ErrMalformedPassword
+	}
+	if salt, err = passwordEncoding.DecodeString(fields[parts-2]); err != nil {
+		return nil, nil, nil, ErrMalformedPassword
+	}
+	if key, err = passwordEncoding.DecodeString(fields[parts-1]); err != nil || len(key) < 1 {
+		return nil, nil, nil, ErrMalformedPassword
+	}
+	return fields, salt, key, nil
+}
+
+func decodeArgon2id(encoded string) (p Argon2idParams, salt, key []byte, err error) {
+	fields, salt, key, err := decodePassword(encoded, 6)
+	if err != nil {
+		return p, nil, nil, err
+	}
+	var version int
+	if _, err = fmt.Sscanf(fields[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrMalformedPassword
+	}
+	if _, err = fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, ErrMalformedPassword
+	}
+	if p.Iterations < 1 || p.Parallelism < 1 {
+		return p, nil, nil, ErrMalformedPassword
+	}
+	p.SaltLength, p.KeyLength = len(salt), len(key)
+	return p, salt, key, nil
+}
+
+func decodeScrypt(encoded string) (p ScryptParams, salt, key []byte, err error) {
+	fields, salt, key, err := decodePassword(encoded, 5)
+	if err != nil {
+		return p, nil, nil, err
+	}
+	var ln uint
+	if _, err = fmt.Sscanf(fields[2], "ln=%d,r=%d,p=%d", &ln, &p.BlockSize, &p.Parallelization); err != nil || ln < 1 || ln > 62 {
+		return p, nil, nil, ErrMalformedPassword
+	}
+	p.Cost = 1 << ln
+	p.SaltLength, p.KeyLength = len(salt), len(key)
+	return p, salt, key, nil
+}
+
+func randomSalt(size int) ([]byte, error) {
+	salt := make([]byte, size)
+	_, err := rand.Read(salt)
+	return salt, err
+}
+
+// NewHasher default to argon2id password hashing with DefaultArgon2idParams
+func NewHasher(opts ...common.Option[hasher]) Hasher {
+	h := &hasher{
+		password:   PasswordArgon2id,
+		argon2id:   DefaultArgon2idParams,
+		scrypt:     DefaultScryptParams,
+		bcryptCost: bcrypt.DefaultCost,
+	}
+	for _, opt := range opts {
+		opt.Apply(h)
+	}
+	return h
+}
@@ -0,0 +1,76 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: hasher_test
+ * @Date: 12/08/24 11.20
+ */
+
+package crypt
+
+import (
+	"encoding/hex"
+	"github.com/evorts/kevlars/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"testing"
+)
+
+func TestHasherPassword(t *testing.T) {
+	cheap := []common.Option[hasher]{
+		HasherWithArgon2id(Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}),
+		HasherWithScrypt(ScryptParams{Cost: 16, BlockSize: 8, Parallelization: 1, SaltLength: 16, KeyLength: 32}),
+		HasherWithBcryptCost(bcrypt.MinCost),
+	}
+	encoded := make(map[PasswordAlgorithm]string)
+	for _, algorithm := range []PasswordAlgorithm{PasswordArgon2id, PasswordScrypt, PasswordBcrypt} {
+		h := NewHasher(append(cheap, HasherWithPasswordAlgorithm(algorithm))...)
+		rs, err := h.HashPassword(common.Bytes("s3cret"))
+		require.NoError(t, err)
+		encoded[algorithm] = rs
+		ok, err := h.Verify(common.Bytes("s3cret"), rs)
+		require.NoError(t, err)
+		assert.True(t, ok, algorithm)
+		ok, err = h.Verify(common.Bytes("wrong"), rs)
+		require.NoError(t, err)
+		assert.False(t, ok, algorithm)
+		assert.False(t, h.NeedsRehash(rs), algorithm)
+	}
+	assert.True(t, strings.HasPrefix(encoded[PasswordArgon2id], "$argon2id$v=19$m=64,t=1,p=1$"))
+	assert.True(t, strings.HasPrefix(encoded[PasswordScrypt], "$scrypt$ln=4,r=8,p=1$"))
+	// hash of other algorithm or weaker parameters verifiable but need rehash
+	h := NewHasher(cheap[0])
+	ok, err := h.Verify(common.Bytes("s3cret"), encoded[PasswordBcrypt])
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, h.NeedsRehash(encoded[PasswordBcrypt]))
+	assert.True(t, NewHasher().NeedsRehash(encoded[PasswordArgon2id]))
+	_, err = h.Verify(common.Bytes("s3cret"), "$argon2id$v=19$m=64$broken")
+	assert.ErrorIs(t, err, ErrMalformedPassword)
+	_, err = h.Verify(common.Bytes("s3cret"), "plain")
+	assert.ErrorIs(t, err, ErrUnsupportedPassword)
+}
+
+func TestHasherSignature(t *testing.T) {
+	h := NewHasher()
+	// RFC 4231 test case 2
+	key, message := common.Bytes("Jefe"), common.Bytes("what do ya want for nothing?")
+	sig, err := h.Sign(HMACSHA256, key, message)
+	require.NoError(t, err)
+	assert.Equal(t, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843", hex.EncodeToString(sig))
+	assert.True(t, h.VerifySignature(HMACSHA256, key, message, sig))
+	assert.False(t, h.VerifySignature(HMACSHA512, key, message, sig))
+	assert.False(t, h.VerifySignature(HMACSHA256, key, common.Bytes("tampered"), sig))
+	_, err = h.Sign("md5", key, message)
+	assert.ErrorIs(t, err, ErrInvalidHMACInstance)
+
+	plain, err := h.BLAKE2b(32, nil, message)
+	require.NoError(t, err)
+	keyed, err := h.BLAKE2b(32, key, message)
+	require.NoError(t, err)
+	assert.Len(t, keyed, 32)
+	assert.NotEqual(t, plain, keyed)
+	_, err = h.BLAKE2b(65, nil, message)
+	assert.Error(t, err)
+}
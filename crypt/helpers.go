@@ -9,10 +9,13 @@ package crypt
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
 	"errors"
+	"strings"
 )
 
 func pkcs5Padding(ciphertext []byte, blockSize int) []byte {
@@ -56,3 +59,42 @@ func GenerateRsaPrivateKeyFromPemString(privatePem string) (*rsa.PrivateKey, err
 	}
 	return pri, nil
 }
+
+// decodePEM of key given as is or base64 encoded
+func decodePEM(value string) (*pem.Block, error) {
+	raw := []byte(value)
+	if !bytes.Contains(raw, []byte("-----BEGIN")) {
+		dec, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		raw = dec
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("failed to parse PEM block containing the key")
+	}
+	return block, nil
+}
+
+func parsePrivateKey(value string) (crypto.PrivateKey, error) {
+	block, err := decodePEM(value)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return x509.ParsePKCS8PrivateKey(block.Bytes)
+}
+
+func parsePublicKey(value string) (crypto.PublicKey, error) {
+	block, err := decodePEM(value)
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
@@ -8,7 +8,9 @@
 package crypt
 
 import (
-	"encoding/base64"
+	"crypto/ecdh"
+	"crypto/rsa"
+	"crypto/sha256"
 	"github.com/evorts/kevlars/common"
 	"hash"
 )
@@ -31,22 +33,101 @@ func WithIV(iv common.Bytes) common.Option[manager] {
 	})
 }
 
+// WithKeyVersion add key encryption key of envelope cipher, keep the retired versions to decrypt existing data
+func WithKeyVersion(version uint32, key common.Bytes) common.Option[manager] {
+	return common.OptionFunc[manager](func(m *manager) {
+		m.keks[version] = key
+	})
+}
+
+// WithCurrentKeyVersion used by envelope cipher to encrypt, default to the highest version
+func WithCurrentKeyVersion(version uint32) common.Option[manager] {
+	return common.OptionFunc[manager](func(m *manager) {
+		m.version = version
+	})
+}
+
+// WithStreamChunkSize of stream encryption, bigger chunk has less overhead but hold more memory
+func WithStreamChunkSize(v int) common.Option[manager] {
+	return common.OptionFunc[manager](func(m *manager) {
+		m.chunk = v
+	})
+}
+
 func WithHash(hash hash.Hash) common.Option[manager] {
 	return common.OptionFunc[manager](func(m *manager) {
 		m.hash = hash
 	})
 }
 
+// WithPrivateKey of asymmetric cipher in PEM (PKCS1 or PKCS8), plain or base64 encoded.
+// rsa key used by CipherRSA while x25519 key used by CipherHybridX25519
 func WithPrivateKey(b64PEMPrivateKey string) common.Option[manager] {
 	return common.OptionFunc[manager](func(m *manager) {
-		var dec64 common.Bytes
-		_, err := base64.StdEncoding.Decode(dec64, []byte(b64PEMPrivateKey))
+		key, err := parsePrivateKey(b64PEMPrivateKey)
 		if err != nil {
 			panic(err)
 		}
-		m.pk, err = GenerateRsaPrivateKeyFromPemString(dec64.String())
+		switch v := key.(type) {
+		case *rsa.PrivateKey:
+			m.pk = v
+		case *ecdh.PrivateKey:
+			m.xpk = v
+		default:
+			panic(ErrUnsupportedKey)
+		}
+	})
+}
+
+// WithPublicKey of asymmetric cipher in PEM (PKCS1 or PKIX), plain or base64 encoded.
+// manager only able to encrypt, so the service holding it could not read the data back
+func WithPublicKey(b64PEMPublicKey string) common.Option[manager] {
+	return common.OptionFunc[manager](func(m *manager) {
+		key, err := parsePublicKey(b64PEMPublicKey)
 		if err != nil {
 			panic(err)
 		}
+		m.pub = key
+	})
+}
+
+// HasherWithPasswordAlgorithm used to hash new password, hash of other algorithms still verifiable
+func HasherWithPasswordAlgorithm(v PasswordAlgorithm) common.Option[hasher] {
+	return common.OptionFunc[hasher](func(h *hasher) {
+		h.password = v
+	})
+}
+
+func HasherWithArgon2id(v Argon2idParams) common.Option[hasher] {
+	return common.OptionFunc[hasher](func(h *hasher) {
+		h.argon2id = v
+	})
+}
+
+func HasherWithScrypt(v ScryptParams) common.Option[hasher] {
+	return common.OptionFunc[hasher](func(h *hasher) {
+		h.scrypt = v
+	})
+}
+
+func HasherWithBcryptCost(v int) common.Option[hasher] {
+	return common.OptionFunc[hasher](func(h *hasher) {
+		h.bcryptCost = v
+	})
+}
+
+// BlindIndexerWithSize of index in bytes (up to 32), shorter index cause more collisions,
+// which trade lookup precision for less information revealed about the value
+func BlindIndexerWithSize(v int) common.Option[blindIndexer] {
+	return common.OptionFunc[blindIndexer](func(b *blindIndexer) {
+		if v > 0 && v < sha256.Size {
+			b.size = v
+		}
+	})
+}
+
+func BlindIndexerWithNormalizer(v func(string) string) common.Option[blindIndexer] {
+	return common.OptionFunc[blindIndexer](func(b *blindIndexer) {
+		b.normalize = v
 	})
 }
@@ -0,0 +1,227 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: stream
+ * @Date: 11/08/24 08.30
+ */
+
+package crypt
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"github.com/evorts/kevlars/common"
+	"golang.org/x/crypto/hkdf"
+	"io"
+)
+
+// stream layout following the STREAM construction (Hoang, Reyhanitabar, Rogaway, Vizár), integers are big endian:
+//
+//	format (1) | key version (4) | chunk size (4) | salt (32) | chunk 0 | chunk 1 | ... | final chunk
+//
+// each stream encrypted with its own AES-256-GCM key derived from the key and salt by HKDF-SHA256.
+// nonce of chunk is its counter followed by flag of the final chunk, so reordered, dropped
+// or truncated chunks fail the authentication. header bound to every chunk as additional data
+const (
+	streamFormatV1         byte = 1
+	streamSaltSize              = 32
+	streamHeaderSize            = 1 + 4 + 4 + streamSaltSize
+	DefaultStreamChunkSize      = 64 * 1024
+	streamMaxChunkSize          = 16 * 1024 * 1024
+)
+
+var streamKeyInfo = []byte("kevlars stream v1")
+
+type streamWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	buf     []byte
+	counter uint64
+	closed  bool
+}
+
+// Write buffer the value and seal every full chunk, the last chunk only sealed on Close
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if s.closed {
+		return 0, ErrStreamClosed
+	}
+	written := 0
+	for len(p) > 0 {
+		n := copy(s.buf[len(s.buf):cap(s.buf)], p)
+		s.buf = s.buf[:len(s.buf)+n]
+		p = p[n:]
+		written += n
+		// keep the full chunk when no more data, it might be the final one
+		if len(s.buf) == cap(s.buf) && len(p) > 0 {
+			if err := s.flush(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close seal the final chunk, without it reader treat the stream as truncated. underlying writer left open
+func (s *streamWriter) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.flush(true)
+}
+
+func (s *streamWriter) flush(final bool) error {
+	sealed := s.aead.Seal(nil, streamNonce(s.counter, final), s.buf, s.header)
+	if _, err := s.w.Write(sealed); err != nil {
+		return err
+	}
+	s.counter++
+	s.buf = s.buf[:0]
+	return nil
+}
+
+type streamReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	header  []byte
+	chunk   []byte // sealed chunk read ahead along with one extra byte to detect the final chunk
+	plain   []byte
+	counter uint64
+	final   bool
+	err     error
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	for len(s.plain) < 1 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		if s.final {
+			return 0, io.EOF
+		}
+		s.err = s.next()
+	}
+	n := copy(p, s.plain)
+	s.plain = s.plain[n:]
+	return n, nil
+}
+
+// next chunk, the chunk is final when no more data after it
+func (s *streamReader) next() error {
+	size := cap(s.chunk) - 1
+	carried := len(s.chunk)
+	s.chunk = s.chunk[:cap(s.chunk)]
+	n, err := io.ReadFull(s.r, s.chunk[carried:])
+	s.chunk = s.chunk[:carried+n]
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+	final := len(s.chunk) <= size
+	sealed := s.chunk
+	if !final {
+		sealed = s.chunk[:size]
+	}
+	plain, err := s.aead.Open(nil, streamNonce(s.counter, final), sealed, s.header)
+	if err != nil {
+		if final {
+			return ErrStreamTruncated
+		}
+		return ErrStreamTampered
+	}
+	s.plain = plain
+	s.counter++
+	s.final = final
+	// carry the extra byte into the next chunk
+	if !final {
+		s.chunk = append(s.chunk[:0], s.chunk[size])
+	}
+	return nil
+}
+
+func streamNonce(counter uint64, final bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if final {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+func streamAEAD(key common.Bytes, salt []byte) (cipher.AEAD, error) {
+	if len(key) < 16 {
+		return nil, ErrInvalidKeyDefined
+	}
+	derived := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, salt, streamKeyInfo), derived); err != nil {
+		return nil, err
+	}
+	return newGCM(derived)
+}
+
+func newStreamWriter(w io.Writer, key common.Bytes, version uint32, chunkSize int) (io.WriteCloser, error) {
+	if chunkSize < 1 || chunkSize > streamMaxChunkSize {
+		return nil, ErrInvalidChunkSize
+	}
+	header := make([]byte, streamHeaderSize)
+	header[0] = streamFormatV1
+	binary.BigEndian.PutUint32(header[1:5], version)
+	binary.BigEndian.PutUint32(header[5:9], uint32(chunkSize))
+	if _, err := rand.Read(header[9:]); err != nil {
+		return nil, err
+	}
+	aead, err := streamAEAD(key, header[9:])
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(header); err != nil {
+		return nil, err
+	}
+	return &streamWriter{w: w, aead: aead, header: header, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+// newStreamReader read the header and resolve key of its version
+func newStreamReader(r io.Reader, keyOf func(version uint32) (common.Bytes, error)) (io.Reader, error) {
+	header := make([]byte, streamHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrMalformedCipherText
+	}
+	chunkSize := int(binary.BigEndian.Uint32(header[5:9]))
+	if header[0] != streamFormatV1 || chunkSize < 1 || chunkSize > streamMaxChunkSize {
+		return nil, ErrMalformedCipherText
+	}
+	key, err := keyOf(binary.BigEndian.Uint32(header[1:5]))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := streamAEAD(key, header[9:])
+	if err != nil {
+		return nil, err
+	}
+	return &streamReader{
+		r:      r,
+		aead:   aead,
+		header: header,
+		chunk:  make([]byte, 0, chunkSize+aead.Overhead()+1),
+	}, nil
+}
+
+// NewStreamWriter encrypt everything written into w with the key (at least 16 bytes), caller should Close it
+// to seal the final chunk. chunk size default to DefaultStreamChunkSize when zero
+func NewStreamWriter(w io.Writer, key common.Bytes, chunkSize int) (io.WriteCloser, error) {
+	if chunkSize == 0 {
+		chunkSize = DefaultStreamChunkSize
+	}
+	return newStreamWriter(w, key, 0, chunkSize)
+}
+
+// NewStreamReader decrypt stream produced by NewStreamWriter, read fail once the stream found tampered or truncated.
+// plain text of chunk only returned after authenticated, but the stream as a whole is only authentic once EOF reached
+func NewStreamReader(r io.Reader, key common.Bytes) (io.Reader, error) {
+	return newStreamReader(r, func(uint32) (common.Bytes, error) {
+		return key, nil
+	})
+}
@@ -0,0 +1,81 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: stream_test
+ * @Date: 11/08/24 09.40
+ */
+
+package crypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"github.com/evorts/kevlars/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+func encryptStream(t *testing.T, key common.Bytes, chunkSize int, plain []byte) []byte {
+	var buf bytes.Buffer
+	w, err := NewStreamWriter(&buf, key, chunkSize)
+	require.NoError(t, err)
+	// write in odd pieces to cross the chunk boundaries
+	for i := 0; i < len(plain); i += 7 {
+		_, err = w.Write(plain[i:min(i+7, len(plain))])
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func decryptStream(key common.Bytes, sealed []byte) ([]byte, error) {
+	r, err := NewStreamReader(bytes.NewReader(sealed), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestStream(t *testing.T) {
+	key := common.Bytes(bytes.Repeat([]byte{7}, 32))
+	chunkSize := 64
+	overhead := chunkSize + 16
+	for name, size := range map[string]int{"empty": 0, "partial chunk": 10, "exact chunks": 3 * chunkSize, "many chunks": 1000} {
+		t.Run(name, func(t *testing.T) {
+			plain := make([]byte, size)
+			_, _ = rand.Read(plain)
+			sealed := encryptStream(t, key, chunkSize, plain)
+			rs, err := decryptStream(key, sealed)
+			require.NoError(t, err)
+			assert.Equal(t, plain, append([]byte{}, rs...))
+		})
+	}
+	plain := make([]byte, 3*chunkSize+10)
+	sealed := encryptStream(t, key, chunkSize, plain)
+	body := sealed[streamHeaderSize:]
+	// dropping the final chunk, even on chunk boundary, detected as truncation
+	_, err := decryptStream(key, sealed[:streamHeaderSize+3*overhead])
+	assert.ErrorIs(t, err, ErrStreamTruncated)
+	// swapping chunks detected
+	swapped := append([]byte{}, sealed[:streamHeaderSize]...)
+	swapped = append(swapped, body[overhead:2*overhead]...)
+	swapped = append(swapped, body[:overhead]...)
+	swapped = append(swapped, body[2*overhead:]...)
+	_, err = decryptStream(key, swapped)
+	assert.ErrorIs(t, err, ErrStreamTampered)
+	// manager with envelope cipher carry the key version along the stream
+	m := New(WithCipher(CipherEnvelope), WithKeyVersion(3, key), WithStreamChunkSize(chunkSize)).MustInit()
+	var buf bytes.Buffer
+	w, err := m.EncryptStream(&buf)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("large export"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	r, err := m.DecryptStream(&buf)
+	require.NoError(t, err)
+	rs, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "large export", string(rs))
+}
@@ -20,13 +20,44 @@ const (
 	Bit512   SHA3 = "sha3-bits-512"
 )
 
+type PasswordAlgorithm string
+
+const (
+	PasswordArgon2id PasswordAlgorithm = "argon2id"
+	PasswordBcrypt   PasswordAlgorithm = "bcrypt"
+	PasswordScrypt   PasswordAlgorithm = "scrypt"
+)
+
+type HMAC string
+
+const (
+	HMACSHA256 HMAC = "hmac-sha256"
+	HMACSHA512 HMAC = "hmac-sha512"
+)
+
 type Cipher string
 
 const (
+	// Deprecated: 3DES is obsolete (64 bit block), only kept to decrypt existing data
 	Cipher3DES   Cipher = "3DES"
 	CipherAESCBC Cipher = "AES-CBC"
-	CipherAESGCM Cipher = "AES-GCM"
-	CipherRSA    Cipher = "RSA"
+	CipherAESGCM Cipher = "AES-GCM" // static iv as configured, prefer CipherEnvelope for new data
+	// CipherRSA is RSA-OAEP, could only encrypt when only public key given
+	CipherRSA Cipher = "RSA"
+	// CipherRSAOAEP is alias of CipherRSA
+	CipherRSAOAEP Cipher = "RSA-OAEP"
+	// CipherXChaCha20 is XChaCha20-Poly1305 with random nonce, key is 32 bytes
+	CipherXChaCha20 Cipher = "XCHACHA20-POLY1305"
+	// CipherAESGCMSIV is nonce misuse resistant AES-GCM-SIV (RFC 8452), key is 16 or 32 bytes
+	CipherAESGCMSIV Cipher = "AES-GCM-SIV"
+	// CipherHybridX25519 encrypt with X25519 public key and ChaCha20-Poly1305,
+	// could only encrypt when only public key given
+	CipherHybridX25519 Cipher = "X25519-CHACHA20-POLY1305"
+	// CipherAESSIV is deterministic (RFC 5297), equal values produce equal ciphertext so it could be searched
+	CipherAESSIV Cipher = "AES-SIV"
+	// CipherEnvelope encrypt each value with random data key and nonce (AES-256-GCM),
+	// the data key wrapped by versioned key encryption key and carried along the ciphertext
+	CipherEnvelope Cipher = "ENVELOPE"
 )
 
 type cAes string
@@ -38,6 +69,16 @@ const (
 
 func (c Cipher) String() string { return string(c) }
 
+// Deprecated cipher should not be used to encrypt new data
+func (c Cipher) Deprecated() bool {
+	return c == Cipher3DES
+}
+
+// asymmetric cipher use key pair instead of key
+func (c Cipher) asymmetric() bool {
+	return c == CipherRSA || c == CipherRSAOAEP || c == CipherHybridX25519
+}
+
 var (
 	ErrNoKeyDefined         = errors.New("no key defined")
 	ErrInvalidKeyDefined    = errors.New("invalid key defined")
@@ -48,8 +89,23 @@ var (
 	ErrNoDecrypterDefined   = errors.New("no decrypter defined")
 	ErrInvalidSHA3Instance  = errors.New("invalid SHA3 instance")
 	ErrPrivateKeyNotDefined = errors.New("private key not defined")
+	ErrPublicKeyNotDefined  = errors.New("public key not defined")
+	ErrUnsupportedKey       = errors.New("unsupported key")
 	ErrHasherNotDefined     = errors.New("hasher not defined")
 	ErrCipherNotSupported   = errors.New("cipher not supported")
+	ErrKeyVersionNotFound   = errors.New("key version not found")
+	ErrMalformedCipherText  = errors.New("malformed cipher text")
+	ErrInvalidChunkSize     = errors.New("invalid chunk size")
+	ErrStreamClosed         = errors.New("stream already closed")
+	ErrStreamTruncated      = errors.New("stream truncated or tampered")
+	ErrStreamTampered       = errors.New("stream chunk tampered or reordered")
+	ErrInvalidHMACInstance  = errors.New("invalid HMAC instance")
+	ErrUnsupportedPassword  = errors.New("unsupported password algorithm")
+	ErrMalformedPassword    = errors.New("malformed password hash")
+	ErrEmptyPassword        = errors.New("empty password")
+
+	ErrFieldManagerNotRegistered = errors.New("field manager not registered")
+	ErrBlindIndexerNotRegistered = errors.New("blind indexer not registered")
 
 	ErrIVTooLong                  = errors.New("iv too long")
 	ErrCipherNotMultipleBlockSize = errors.New("cipherText is not a multiple of the block size")
@@ -8,6 +8,8 @@
 package db
 
 import (
+	"context"
+	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
@@ -103,6 +105,49 @@ func NewHelper(separator Separator, opts ...IHelperOption) IHelper {
 	return h
 }
 
+type scopedHelper struct {
+	IHelper
+	scope     string
+	scopeArgs []interface{}
+}
+
+func (h *scopedHelper) BuildSqlAndArgs() (string, []interface{}) {
+	q, args := h.BuildSqlAndArgsFilterOnly()
+	qs := []string{q}
+	if ordersBy := h.OrdersBy(); ordersBy != nil && len(ordersBy) > 0 {
+		qs = append(qs, ordersBy.Build())
+	}
+	if pagination := h.Pagination(); pagination != nil {
+		qs = append(qs, pagination.Build())
+	}
+	return fmt.Sprintf(" %s", strings.Join(qs, " ")), args
+}
+
+func (h *scopedHelper) BuildSqlAndArgsWithWherePrefix() (string, []interface{}) {
+	where, args := h.BuildSqlAndArgs()
+	return fmt.Sprintf(" WHERE%s", where), args
+}
+
+func (h *scopedHelper) BuildSqlAndArgsFilterOnly() (string, []interface{}) {
+	q, args := h.IHelper.BuildSqlAndArgsFilterOnly()
+	args = append(append(make([]interface{}, 0, len(h.scopeArgs)+len(args)), h.scopeArgs...), args...)
+	if len(strings.TrimSpace(q)) < 1 {
+		return fmt.Sprintf("(%s)", h.scope), args
+	}
+	return fmt.Sprintf("(%s) AND (%s)", h.scope, q), args
+}
+
+func (h *scopedHelper) BuildSqlAndArgsFilterOnlyWithWherePrefix() (string, []interface{}) {
+	where, args := h.BuildSqlAndArgsFilterOnly()
+	return fmt.Sprintf(" WHERE %s", where), args
+}
+
+// NewScopedHelper wrap filters of the given helper with mandatory condition, e.g. tenant isolation.
+// the condition always joined using AND regardless the separator used by the helper
+func NewScopedHelper(h IHelper, scope string, args ...interface{}) IHelper {
+	return &scopedHelper{IHelper: h, scope: scope, scopeArgs: args}
+}
+
 func ToJsonObjectFromMap(v map[string]interface{}) *JsonObject {
 	if v == nil {
 		return nil
@@ -164,3 +209,16 @@ func ToDriverValueFromStringArray(collection []string) []driver.Value {
 	}
 	return rs
 }
+
+// ExecDefinitions of the manager's driver within single transaction, e.g. bring tables created by earlier version
+// into the current shape. definitions should be idempotent as it is executed on every start up
+func ExecDefinitions(ctx context.Context, m Manager, definitions map[SupportedDriver][]string) error {
+	tx := m.MustBegin(ctx, &sql.TxOptions{})
+	for _, definition := range definitions[m.Driver()] {
+		if _, err := tx.Exec(definition); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -45,3 +45,20 @@ func TestHelper_Build(t *testing.T) {
 	assert.Equal(t, []interface{}{"KEY", "SAVED"}, args, "Arguments test")
 	assert.Equal(t, " (key = ? AND status = ?) ORDER BY Field ASC LIMIT 10 OFFSET 0", qf, "Query filter")
 }
+
+func TestScopedHelper_Build(t *testing.T) {
+	filters := Filters{
+		Ors: []FilterItem{
+			{Field: "key", Op: OpEq, Value: "KEY"},
+			{Field: "status", Op: OpEq, Value: "SAVED"},
+		},
+	}
+	h := NewScopedHelper(NewHelper(SeparatorOR, WithPagination(1, 10), WithFilters(filters)), "tenant_id = ?", "t1")
+	qf, args := h.BuildSqlAndArgsWithWherePrefix()
+	assert.Equal(t, []interface{}{"t1", "KEY", "SAVED"}, args, "Arguments test")
+	assert.Equal(t, " WHERE (tenant_id = ?) AND ((key = ? OR status = ?)) LIMIT 10 OFFSET 0", qf, "Query filter")
+	// scope still applied when no filters defined
+	qf, args = NewScopedHelper(NewHelper(SeparatorAND), "tenant_id = ?", "t1").BuildSqlAndArgsWithWherePrefix()
+	assert.Equal(t, []interface{}{"t1"}, args, "Arguments test")
+	assert.Equal(t, " WHERE (tenant_id = ?)", qf, "Query filter")
+}
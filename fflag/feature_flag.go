@@ -16,6 +16,7 @@ import (
 	"github.com/evorts/kevlars/common"
 	"github.com/evorts/kevlars/db"
 	"github.com/evorts/kevlars/logger"
+	"github.com/evorts/kevlars/requests"
 	"github.com/evorts/kevlars/rules"
 	"github.com/evorts/kevlars/utils"
 	"github.com/huandu/go-sqlbuilder"
@@ -25,6 +26,7 @@ import (
 
 type Record struct {
 	Id            int64      `db:"id"`
+	TenantId      string     `db:"tenant_id"` // populated from context on write
 	Feature       string     `db:"feature"`
 	Enabled       bool       `db:"enabled"`
 	LastChangedBy string     `db:"last_changed_by"`
@@ -60,7 +62,7 @@ type manager struct {
 
 	dataLoaded   bool
 	lazyLoadData bool
-	mapFeature   map[string]bool
+	mapFeature   map[string]bool // map[tenant/feature]enabled
 }
 
 const (
@@ -69,7 +71,7 @@ const (
 
 //goland:noinspection SqlResolve
 var (
-	columns                  = []string{"feature", "enabled", "last_changed_by"}
+	columns                  = []string{"tenant_id", "feature", "enabled", "last_changed_by"}
 	tableExistenceCheckQuery = map[db.SupportedDriver][]string{
 		db.DriverPostgreSQL: {
 			fmt.Sprintf(`select count(table_name) as tableCount from information_schema.tables ist
@@ -79,6 +81,7 @@ var (
 	tableColumnDefinitions = map[db.SupportedDriver][][]string{
 		db.DriverPostgreSQL: {
 			{"id", "serial", "primary key"},
+			{"tenant_id", "varchar(50)", "not null", "default ''"},
 			{"feature", "varchar(50)", "not null"},
 			{"enabled", "boolean", "default false"},
 			{"last_changed_by", "varchar(30)"},
@@ -88,10 +91,18 @@ var (
 	}
 	tableIndexDefinitions = map[db.SupportedDriver][]string{
 		db.DriverPostgreSQL: {
-			fmt.Sprintf("create unique index if not exists %s_feature_idx on public.%s(feature)", table, table),
+			fmt.Sprintf("create unique index if not exists %s_tenant_id_feature_idx on public.%s(tenant_id, feature)", table, table),
 			fmt.Sprintf("create index if not exists %s_enabled_idx on public.%s(enabled)", table, table),
 		},
 	}
+	// tableTenancyDefinitions bring table created before tenant awareness into the current shape
+	tableTenancyDefinitions = map[db.SupportedDriver][]string{
+		db.DriverPostgreSQL: {
+			fmt.Sprintf("alter table %s add column if not exists tenant_id varchar(50) not null default ''", table),
+			fmt.Sprintf("drop index if exists %s_feature_idx", table),
+			fmt.Sprintf("create unique index if not exists %s_tenant_id_feature_idx on public.%s(tenant_id, feature)", table, table),
+		},
+	}
 )
 
 func (m *manager) ExecWhenEnabled(ctx context.Context, feature string, f func()) {
@@ -100,18 +111,23 @@ func (m *manager) ExecWhenEnabled(ctx context.Context, feature string, f func())
 	}
 }
 
+// IsEnabled check feature within tenant of context, flag defined for the tenant take precedence over the global one
 func (m *manager) IsEnabled(ctx context.Context, feature string) bool {
 	rules.WhenTrue(m.lazyLoadData, func() {
 		m.log.Info(m.loadData())
 	})
+	tenantId := requests.TenantId(ctx)
 	if m.dataLoaded {
-		if v, ok := m.mapFeature[feature]; ok {
+		if v, ok := m.mapFeature[featureKey(tenantId, feature)]; ok {
+			return v
+		}
+		if v, ok := m.mapFeature[featureKey("", feature)]; ok {
 			return v
 		}
 	}
-	q := m.dbr.Rebind(`select enabled from ` + table + ` where feature = ?`)
+	q := m.dbr.Rebind(`select enabled from ` + table + ` where feature = ? and tenant_id in (?, '') order by tenant_id desc limit 1`)
 	var value sql.NullBool
-	if err := m.dbr.QueryRow(ctx, q, feature).Scan(&value); err != nil {
+	if err := m.dbr.QueryRow(ctx, q, feature, tenantId).Scan(&value); err != nil {
 		return false
 	}
 	return value.Bool
@@ -121,8 +137,9 @@ func (m *manager) Add(ctx context.Context, records ...Record) error {
 	builder := sqlbuilder.NewInsertBuilder().
 		InsertInto(table).
 		Cols(columns...)
+	tenantId := requests.TenantId(ctx)
 	for _, record := range records {
-		builder.Values(record.Feature, record.Enabled, record.LastChangedBy)
+		builder.Values(tenantId, record.Feature, record.Enabled, record.LastChangedBy)
 	}
 	q, args := builder.BuildWithFlavor(m.dbw.Driver().ToSqlBuilderFlavor())
 	_, err := m.dbw.Exec(ctx, q, args...)
@@ -138,7 +155,7 @@ func (m *manager) AddOptions(opts ...common.Option[manager]) Manager {
 
 func (m *manager) getFeaturesBy(ctx context.Context, by db.IHelper) (Records, error) {
 	qf, args := by.BuildSqlAndArgsWithWherePrefix()
-	q := fmt.Sprintf(`select id, feature, enabled, last_changed_by, created_at, updated_at from %s %s`, table, qf)
+	q := fmt.Sprintf(`select id, tenant_id, feature, enabled, last_changed_by, created_at, updated_at from %s %s`, table, qf)
 	rs := make(Records, 0)
 	rows, err := m.dbr.Query(ctx, m.dbr.Rebind(q), args...)
 	defer func() {
@@ -159,7 +176,7 @@ func (m *manager) getFeaturesBy(ctx context.Context, by db.IHelper) (Records, er
 			updatedAt     sql.NullTime
 		)
 		if err = rows.Scan(
-			&record.Id, &record.Feature, &record.Enabled,
+			&record.Id, &record.TenantId, &record.Feature, &record.Enabled,
 			&lastChangedBy, &record.CreatedAt, &updatedAt,
 		); err != nil {
 			return rs, err
@@ -215,7 +232,7 @@ func (m *manager) loadData() error {
 		}
 		// map items into map client authorization
 		for _, item := range items {
-			m.mapFeature[item.Feature] = item.Enabled
+			m.mapFeature[featureKey(item.TenantId, item.Feature)] = item.Enabled
 		}
 		if len(items) < limit {
 			break
@@ -298,7 +315,7 @@ func (m *manager) initSchema(ctx context.Context) error {
 		return err
 	}
 	if total >= 1 {
-		return nil
+		return db.ExecDefinitions(ctx, m.dbw, tableTenancyDefinitions)
 	}
 	columnDefinitions := utils.GetValueOnMap(tableColumnDefinitions, driver, [][]string{})
 	if len(columnDefinitions) < 1 {
@@ -330,6 +347,10 @@ func (m *manager) initSchema(ctx context.Context) error {
 	return tx.Commit()
 }
 
+func featureKey(tenantId, feature string) string {
+	return tenantId + "/" + feature
+}
+
 func New(db db.Manager, opts ...common.Option[manager]) Manager {
 	m := &manager{
 		dbw: db, dbr: db, log: logger.NewNoop(),
@@ -11,6 +11,7 @@ require (
 	github.com/amacneil/dbmate/v2 v2.19.0
 	github.com/avast/retry-go/v4 v4.6.0
 	github.com/denisenkom/go-mssqldb v0.12.3
+	github.com/fsnotify/fsnotify v1.7.0
 	github.com/go-co-op/gocron v1.37.0
 	github.com/go-jose/go-jose/v4 v4.0.3
 	github.com/go-playground/validator/v10 v10.22.0
@@ -84,7 +85,6 @@ require (
 	github.com/docker/go-connections v0.5.0 // indirect
 	github.com/docker/go-units v0.5.0 // indirect
 	github.com/felixge/httpsnoop v1.0.4 // indirect
-	github.com/fsnotify/fsnotify v1.7.0 // indirect
 	github.com/gabriel-vasile/mimetype v1.4.3 // indirect
 	github.com/go-logr/logr v1.4.2 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
@@ -24,7 +24,7 @@ func injectPrefixIntoKeysWhenDefined(prefix string, keys ...string) []string {
 	if len(prefix) < 1 {
 		return keys
 	}
-	rs := make([]string, len(keys))
+	rs := make([]string, 0, len(keys))
 	for _, key := range keys {
 		rs = append(rs, prefix+"_"+key)
 	}
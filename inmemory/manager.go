@@ -24,12 +24,18 @@ type Manager interface {
 	Get(ctx context.Context, key string, bindTo interface{}) error
 	GetString(ctx context.Context, key string) string
 	Del(ctx context.Context, keys ...string) error
+	// Incr value of key atomically, expiry only set when the key created by this increment
+	Incr(ctx context.Context, key string, expire time.Duration) (int64, error)
+	// CompareAndSwap value of key atomically, only when the current value equal to the old one.
+	// false returned when the key changed or gone in the meantime
+	CompareAndSwap(ctx context.Context, key, old, new string, expire time.Duration) (bool, error)
 
 	HSet(ctx context.Context, key string, value ...interface{}) error
 	HSetWhenNotExist(ctx context.Context, key, field string, value interface{}) error
 	HGet(ctx context.Context, key, field string, bindTo interface{}) error
 	HDel(ctx context.Context, key string, fields ...string) error
 	HGetAll(ctx context.Context, key string, bindTo interface{}) error
+	HKeys(ctx context.Context, key string) ([]string, error)
 
 	HMSet(ctx context.Context, key string, values ...interface{}) error
 	HMGet(ctx context.Context, key, field string, bindTo interface{}) error
@@ -44,6 +44,14 @@ func (m *managerNoop) SetWithExpireWhenNotExist(ctx context.Context, key string,
 	return nil
 }
 
+func (m *managerNoop) Incr(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (m *managerNoop) CompareAndSwap(ctx context.Context, key, old, new string, expire time.Duration) (bool, error) {
+	return false, nil
+}
+
 func (m *managerNoop) SetString(ctx context.Context, key, value string, expire time.Duration) error {
 	return nil
 }
@@ -76,6 +84,10 @@ func (m *managerNoop) HGetAll(ctx context.Context, key string, bindTo interface{
 	return nil
 }
 
+func (m *managerNoop) HKeys(ctx context.Context, key string) ([]string, error) {
+	return nil, nil
+}
+
 func (m *managerNoop) HMSet(ctx context.Context, key string, values ...interface{}) error {
 	return nil
 }
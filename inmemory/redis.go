@@ -20,7 +20,9 @@ import (
 )
 
 type redisManager struct {
-	c *redis.Client
+	c          *redis.Client
+	casScript  *redis.Script
+	incrScript *redis.Script
 
 	addr string
 	pwd  string
@@ -94,6 +96,16 @@ func (m *redisManager) HGetAll(ctx context.Context, key string, bindTo interface
 	})
 }
 
+func (m *redisManager) HKeys(ctx context.Context, key string) (rs []string, err error) {
+	err = wrapTelemetryTuple1(ctx, m.tm.Tracer(), m.spanName("h_keys"), []trace.SpanStartOption{
+		trace.WithAttributes(attribute.String("key", key)),
+	}, func(newCtx context.Context) error {
+		rs, err = m.c.HKeys(newCtx, injectPrefixWhenDefined(m.prefix, key)).Result()
+		return err
+	})
+	return rs, err
+}
+
 func (m *redisManager) HMSet(ctx context.Context, key string, values ...interface{}) error {
 	return wrapTelemetryTuple1(ctx, m.tm.Tracer(), m.spanName("hm_set"), []trace.SpanStartOption{
 		trace.WithAttributes(attribute.String("key", key)),
@@ -157,6 +169,31 @@ func (m *redisManager) Del(ctx context.Context, keys ...string) error {
 	})
 }
 
+func (m *redisManager) Incr(ctx context.Context, key string, expire time.Duration) (rs int64, err error) {
+	err = wrapTelemetryTuple1(ctx, m.tm.Tracer(), m.spanName("incr"), []trace.SpanStartOption{
+		trace.WithAttributes(attribute.String("key", key)),
+	}, func(newCtx context.Context) error {
+		rs, err = m.incrScript.Run(
+			newCtx, m.c, []string{injectPrefixWhenDefined(m.prefix, key)}, expire.Milliseconds(),
+		).Int64()
+		return err
+	})
+	return rs, err
+}
+
+func (m *redisManager) CompareAndSwap(ctx context.Context, key, old, new string, expire time.Duration) (swapped bool, err error) {
+	err = wrapTelemetryTuple1(ctx, m.tm.Tracer(), m.spanName("cas"), []trace.SpanStartOption{
+		trace.WithAttributes(attribute.String("key", key)),
+	}, func(newCtx context.Context) error {
+		rs, errS := m.casScript.Run(
+			newCtx, m.c, []string{injectPrefixWhenDefined(m.prefix, key)}, old, new, expire.Milliseconds(),
+		).Int64()
+		swapped = rs == 1
+		return errS
+	})
+	return swapped, err
+}
+
 func (m *redisManager) SetString(ctx context.Context, key, value string, expire time.Duration) error {
 	return wrapTelemetryTuple1(ctx, m.tm.Tracer(), m.spanName("set_str"), []trace.SpanStartOption{
 		trace.WithAttributes(attribute.String("key", key)),
@@ -224,7 +261,12 @@ func (m *redisManager) Connect(ctx context.Context) error {
 }
 
 func NewRedis(addr string, opts ...Option[redisManager]) Manager {
-	m := &redisManager{addr: addr}
+	m := &redisManager{
+		addr:       addr,
+		tm:         telemetry.NewNoop(),
+		casScript:  redis.NewScript(compareAndSwapScript),
+		incrScript: redis.NewScript(incrScript),
+	}
 	for _, opt := range opts {
 		opt.apply(m)
 	}
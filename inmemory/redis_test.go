@@ -20,6 +20,7 @@ import (
 )
 
 var (
+	mr  *miniredis.Miniredis
 	rm  Manager
 	rme Manager
 )
@@ -43,10 +44,7 @@ func assertNotPanic(t *testing.T, f func(ctx context.Context) Manager) {
 }
 
 func TestMain(m *testing.M) {
-	var (
-		mr  *miniredis.Miniredis
-		err error
-	)
+	var err error
 	if mr, err = miniredis.Run(); err != nil {
 		log.Fatalf("error '%s' wasn't expected when initiated minimal redis for test", err.Error())
 	}
@@ -82,3 +80,46 @@ func TestManagerSetString(t *testing.T) {
 func TestManagerGetString(t *testing.T) {
 	assert.Empty(t, rm.GetString(context.Background(), "key_not_exist"))
 }
+
+func TestManagerCompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	swapped, err := rm.CompareAndSwap(ctx, "cas_key", "", "v1", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, swapped, "missing key should not be swapped")
+	assert.NoError(t, rm.SetString(ctx, "cas_key", "v1", time.Minute))
+	swapped, err = rm.CompareAndSwap(ctx, "cas_key", "v1", "v2", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, swapped)
+	swapped, err = rm.CompareAndSwap(ctx, "cas_key", "v1", "v3", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, swapped, "stale value should not be swapped")
+	assert.Equal(t, "v2", rm.GetString(ctx, "cas_key"))
+}
+
+func TestManagerIncr(t *testing.T) {
+	ctx := context.Background()
+	count, err := rm.Incr(ctx, "incr_key", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, time.Minute, mr.TTL("incr_key"))
+	// expiry not extended by the next increment
+	mr.FastForward(30 * time.Second)
+	count, err = rm.Incr(ctx, "incr_key", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, 30*time.Second, mr.TTL("incr_key"))
+	// counter left without expiry get one on the next increment
+	mr.Set("incr_no_ttl", "3")
+	count, err = rm.Incr(ctx, "incr_no_ttl", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+	assert.Equal(t, time.Minute, mr.TTL("incr_no_ttl"))
+}
+
+func TestManagerHKeys(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, rm.HSet(ctx, "hkeys_key", "a", "1", "b", "2"))
+	keys, err := rm.HKeys(ctx, "hkeys_key")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b"}, keys)
+}
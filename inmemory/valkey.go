@@ -18,11 +18,14 @@ import (
 	"github.com/valkey-io/valkey-go"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"strconv"
 	"time"
 )
 
 type valkeyManager struct {
-	c valkey.Client
+	c          valkey.Client
+	casScript  *valkey.Lua
+	incrScript *valkey.Lua
 
 	addr string
 	pwd  string
@@ -159,13 +162,41 @@ func (m *valkeyManager) SetString(ctx context.Context, key, value string, expire
 	return wrapTelemetryTuple1(ctx, m.tm.Tracer(), m.spanName("set_str"), []trace.SpanStartOption{
 		trace.WithAttributes(attribute.String("key", key)),
 	}, func(newCtx context.Context) error {
-		return m.c.Do(
-			newCtx,
-			m.c.B().Set().
-				Key(injectPrefixWhenDefined(m.prefix, key)).
-				Value(value).Build(),
-		).Error()
+		cmd := m.c.B().Set().
+			Key(injectPrefixWhenDefined(m.prefix, key)).
+			Value(value)
+		if expire > 0 {
+			return m.c.Do(newCtx, cmd.Px(expire).Build()).Error()
+		}
+		return m.c.Do(newCtx, cmd.Build()).Error()
+	})
+}
+
+func (m *valkeyManager) Incr(ctx context.Context, key string, expire time.Duration) (rs int64, err error) {
+	err = wrapTelemetryTuple1(ctx, m.tm.Tracer(), m.spanName("incr"), []trace.SpanStartOption{
+		trace.WithAttributes(attribute.String("key", key)),
+	}, func(newCtx context.Context) error {
+		rs, err = m.incrScript.Exec(
+			newCtx, m.c, []string{injectPrefixWhenDefined(m.prefix, key)},
+			[]string{strconv.FormatInt(expire.Milliseconds(), 10)},
+		).AsInt64()
+		return err
 	})
+	return rs, err
+}
+
+func (m *valkeyManager) CompareAndSwap(ctx context.Context, key, old, new string, expire time.Duration) (swapped bool, err error) {
+	err = wrapTelemetryTuple1(ctx, m.tm.Tracer(), m.spanName("cas"), []trace.SpanStartOption{
+		trace.WithAttributes(attribute.String("key", key)),
+	}, func(newCtx context.Context) error {
+		rs, errS := m.casScript.Exec(
+			newCtx, m.c, []string{injectPrefixWhenDefined(m.prefix, key)},
+			[]string{old, new, strconv.FormatInt(expire.Milliseconds(), 10)},
+		).AsInt64()
+		swapped = rs == 1
+		return errS
+	})
+	return swapped, err
 }
 
 func (m *valkeyManager) Get(ctx context.Context, key string, bindTo interface{}) error {
@@ -290,6 +321,18 @@ func (m *valkeyManager) HGetAll(ctx context.Context, key string, bindTo interfac
 	})
 }
 
+func (m *valkeyManager) HKeys(ctx context.Context, key string) (rs []string, err error) {
+	err = wrapTelemetryTuple1(ctx, m.tm.Tracer(), m.spanName("h_keys"), []trace.SpanStartOption{
+		trace.WithAttributes(attribute.String("key", key)),
+	}, func(newCtx context.Context) error {
+		rs, err = m.c.Do(
+			newCtx, m.c.B().Hkeys().Key(injectPrefixWhenDefined(m.prefix, key)).Build(),
+		).AsStrSlice()
+		return err
+	})
+	return rs, err
+}
+
 func (m *valkeyManager) HMSet(ctx context.Context, key string, values ...interface{}) error {
 	return wrapTelemetryTuple1(ctx, m.tm.Tracer(), m.spanName("hm_set"), []trace.SpanStartOption{
 		trace.WithAttributes(attribute.String("key", key)),
@@ -329,7 +372,12 @@ func (m *valkeyManager) Ping() error {
 }
 
 func NewValKey(addr string, opts ...Option[valkeyManager]) Manager {
-	m := &valkeyManager{addr: addr}
+	m := &valkeyManager{
+		addr:       addr,
+		tm:         telemetry.NewNoop(),
+		casScript:  valkey.NewLuaScript(compareAndSwapScript),
+		incrScript: valkey.NewLuaScript(incrScript),
+	}
 	for _, opt := range opts {
 		opt.apply(m)
 	}
@@ -15,3 +15,22 @@ const (
 )
 
 func (p Provider) String() string { return string(p) }
+
+// compareAndSwapScript set KEYS[1] to ARGV[2] when its current value is ARGV[1], expire in ARGV[3] milliseconds when positive
+const compareAndSwapScript = `if redis.call('GET', KEYS[1]) ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[2])
+end
+return 1`
+
+// incrScript increment KEYS[1] and expire it in ARGV[1] milliseconds when positive and the key has no expiry yet,
+// in single step so the counter never left without expiry
+const incrScript = `local count = redis.call('INCR', KEYS[1])
+if tonumber(ARGV[1]) > 0 and redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return count`
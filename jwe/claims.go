@@ -0,0 +1,143 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: claims
+ * @Date: 08/08/24 10.11
+ */
+
+package jwe
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Holder of registered claims, satisfied by any struct embedding RegisteredClaims
+type Holder interface {
+	Registered() *RegisteredClaims
+}
+
+// Audience of token, serialized as single string when only one audience as allowed by RFC 7519 section 4.1.3
+type Audience []string
+
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var multi []string
+	if err := json.Unmarshal(b, &multi); err != nil {
+		return err
+	}
+	*a = multi
+	return nil
+}
+
+// Contains any of the given audiences
+func (a Audience) Contains(audiences ...string) bool {
+	for _, v := range a {
+		for _, aud := range audiences {
+			if v == aud {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RegisteredClaims as defined by RFC 7519 section 4.1, times are unix timestamp
+type RegisteredClaims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  Audience `json:"aud,omitempty"`
+	ExpiredAt int64    `json:"exp,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	TokenID   string   `json:"jti,omitempty"`
+}
+
+func (r *RegisteredClaims) Registered() *RegisteredClaims {
+	return r
+}
+
+// Expired when expiration defined and already passed the given time
+func (r RegisteredClaims) Expired(now time.Time) bool {
+	return r.ExpiredAt > 0 && now.Unix() >= r.ExpiredAt
+}
+
+// validate time based claims with the tolerance of leeway, along with issuer and audience when expected
+func (r RegisteredClaims) validate(now time.Time, leeway time.Duration, issuer string, audiences []string, requireExp bool) error {
+	if r.ExpiredAt < 1 && requireExp {
+		return ErrMissingExpiration
+	}
+	if r.ExpiredAt > 0 && !now.Before(time.Unix(r.ExpiredAt, 0).Add(leeway)) {
+		return ErrTokenExpired
+	}
+	if r.NotBefore > 0 && now.Add(leeway).Before(time.Unix(r.NotBefore, 0)) {
+		return ErrTokenNotValidYet
+	}
+	if r.IssuedAt > 0 && now.Add(leeway).Before(time.Unix(r.IssuedAt, 0)) {
+		return ErrTokenUsedBeforeIssued
+	}
+	if len(issuer) > 0 && r.Issuer != issuer {
+		return ErrInvalidIssuer
+	}
+	if len(audiences) > 0 && !r.Audience.Contains(audiences...) {
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+// Claims of registered claims along with custom claims of type T,
+// both flattened into the same payload so custom claim should not reuse registered claim names
+type Claims[T any] struct {
+	RegisteredClaims
+	Custom T
+}
+
+func (c Claims[T]) MarshalJSON() ([]byte, error) {
+	custom, err := json.Marshal(c.Custom)
+	if err != nil {
+		return nil, err
+	}
+	payload := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(custom, &payload); err != nil {
+		return nil, err
+	}
+	registered, err := json.Marshal(c.RegisteredClaims)
+	if err != nil {
+		return nil, err
+	}
+	// registered claims take precedence over custom claims of the same name
+	if err = json.Unmarshal(registered, &payload); err != nil {
+		return nil, err
+	}
+	return json.Marshal(payload)
+}
+
+func (c *Claims[T]) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &c.RegisteredClaims); err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &c.Custom)
+}
+
+// EncodeClaims with custom claims of type T
+func EncodeClaims[T any](m Manager, v Claims[T]) (string, error) {
+	return m.EncodeHolder(&v)
+}
+
+// DecodeClaims with custom claims of type T, claims returned along with validation error
+func DecodeClaims[T any](m Manager, token string) (Claims[T], error) {
+	var v Claims[T]
+	err := m.DecodeHolder(token, &v)
+	return v, err
+}
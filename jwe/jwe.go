@@ -10,14 +10,24 @@ package jwe
 import (
 	"crypto/rsa"
 	"encoding/json"
+	"fmt"
 	"github.com/evorts/kevlars/common"
+	"github.com/evorts/kevlars/ctime"
 	"github.com/go-jose/go-jose/v4"
 	"time"
 )
 
 type Manager interface {
 	Encode(v Claim) (token string, err error)
+	// Decode token and validate its registered claims, claim returned along with validation error
 	Decode(token string) (v Claim, err error)
+	// EncodeHolder of any claims embedding RegisteredClaims, see EncodeClaims for custom claims
+	EncodeHolder(v Holder) (token string, err error)
+	// DecodeHolder of any claims embedding RegisteredClaims, see DecodeClaims for custom claims
+	DecodeHolder(token string, bindTo Holder) error
+	// JWKS of public keys to be published for token consumer, empty when token not signed
+	// as public key of encryption only mode let anyone issue valid token
+	JWKS() jose.JSONWebKeySet
 
 	Init() error
 	MustInit() Manager
@@ -25,26 +35,43 @@ type Manager interface {
 
 type jwe struct {
 	key               *rsa.PrivateKey
-	keyAlgorithm      jose.KeyAlgorithm
-	expire            time.Duration //expiration
-	signerAlgorithm   jose.SignatureAlgorithm
-	signer            jose.Signer
+	ring              KeyRing
+	keyAlgorithm      jose.KeyAlgorithm // of rsa key, ec key always use ECDH-ES
+	expire            time.Duration     //expiration
+	leeway            time.Duration     // tolerated clock skew on validating time based claims
+	issuer            string
+	audience          []string
+	signerAlgorithm   jose.SignatureAlgorithm // of rsa key, other key type determine its own algorithm
 	contentEncryption jose.ContentEncryption
-	encrypter         jose.Encrypter
+	mode              Mode
 }
 
 func (j *jwe) Init() error {
-	var err error
-	j.signer, err = jose.NewSigner(jose.SigningKey{Algorithm: j.signerAlgorithm, Key: j.key}, nil)
+	if j.ring == nil {
+		ring, err := NewKeyRing()
+		if err != nil {
+			return err
+		}
+		j.ring = ring
+	}
+	if j.key != nil {
+		if err := j.ring.Add(Key{Private: j.key}); err != nil {
+			return err
+		}
+	}
+	// ensure the primary key usable by the mode
+	primary, err := j.ring.Primary()
 	if err != nil {
 		return err
 	}
-	j.encrypter, err = jose.NewEncrypter(
-		j.contentEncryption,
-		jose.Recipient{
-			Algorithm: j.keyAlgorithm,
-			Key:       j.key.PublicKey,
-		}, nil)
+	if j.mode != ModeEncrypted {
+		if _, err = j.newSigner(primary); err != nil {
+			return err
+		}
+	}
+	if j.mode != ModeSigned {
+		_, err = j.newEncrypter(primary)
+	}
 	return err
 }
 
@@ -55,12 +82,21 @@ func (j *jwe) MustInit() Manager {
 	return j
 }
 
+func (j *jwe) JWKS() jose.JSONWebKeySet {
+	if j.mode == ModeEncrypted || j.ring == nil {
+		return jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	}
+	return j.ring.JWKS()
+}
+
 func NewJWE(key *rsa.PrivateKey, opts ...common.Option[jwe]) Manager {
 	m := &jwe{
 		key:               key,
 		signerAlgorithm:   jose.PS512,
 		keyAlgorithm:      jose.RSA_OAEP,
 		contentEncryption: jose.A128GCM,
+		expire:            DefaultExpiration,
+		issuer:            ISSUER,
 	}
 	for _, opt := range opts {
 		opt.Apply(m)
@@ -69,29 +105,184 @@ func NewJWE(key *rsa.PrivateKey, opts ...common.Option[jwe]) Manager {
 	return m
 }
 
+// NewWithKeyRing issue token with the primary key of ring, while accepting token of any active key
+func NewWithKeyRing(ring KeyRing, opts ...common.Option[jwe]) Manager {
+	return NewJWE(nil, append([]common.Option[jwe]{WithKeyRing(ring)}, opts...)...)
+}
+
 func (j *jwe) Encode(v Claim) (token string, err error) {
+	return j.EncodeHolder(&v)
+}
+
+func (j *jwe) Decode(token string) (v Claim, err error) {
+	err = j.DecodeHolder(token, &v)
+	return
+}
+
+// EncodeHolder fill the unset issuer, audience, issued at and expiration from configuration
+func (j *jwe) EncodeHolder(v Holder) (token string, err error) {
+	now := ctime.Now()
+	r := v.Registered()
+	if len(r.Issuer) < 1 {
+		r.Issuer = j.issuer
+	}
+	if len(r.Audience) < 1 && len(j.audience) > 0 {
+		r.Audience = j.audience
+	}
+	if r.IssuedAt < 1 {
+		r.IssuedAt = now.Unix()
+	}
+	if r.ExpiredAt < 1 && j.expire > 0 {
+		r.ExpiredAt = time.Unix(r.IssuedAt, 0).Add(j.expire).Unix()
+	}
 	// encode to string first
 	vb, err := json.Marshal(v)
 	if err != nil {
 		return "", err
 	}
-	// encrypt it
-	enc, errEnc := j.encrypter.Encrypt(vb)
-	if errEnc != nil {
-		return "", errEnc
+	switch j.mode {
+	case ModeSigned:
+		return j.sign(vb)
+	case ModeEncrypted:
+		return j.encrypt(vb)
+	}
+	// sign then encrypt the signed token
+	signed, err := j.sign(vb)
+	if err != nil {
+		return "", err
+	}
+	return j.encrypt([]byte(signed))
+}
+
+func (j *jwe) DecodeHolder(token string, bindTo Holder) error {
+	var (
+		payload []byte
+		err     error
+	)
+	switch j.mode {
+	case ModeSigned:
+		payload, err = j.verify(token)
+	case ModeEncrypted:
+		payload, err = j.decrypt(token)
+	default:
+		if payload, err = j.decrypt(token); err == nil {
+			// inner token should be signed, otherwise anyone holding the public key could forge it
+			payload, err = j.verify(string(payload))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(payload, bindTo); err != nil {
+		return fmt.Errorf("%w: %s", ErrMalformedToken, err.Error())
+	}
+	// expiration required once the manager configured with one
+	return bindTo.Registered().validate(ctime.Now(), j.leeway, j.issuer, j.audience, j.expire > 0)
+}
+
+func (j *jwe) sign(payload []byte) (string, error) {
+	key, err := j.ring.Primary()
+	if err != nil {
+		return "", err
+	}
+	signer, err := j.newSigner(key)
+	if err != nil {
+		return "", err
+	}
+	sig, err := signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+	return sig.CompactSerialize()
+}
+
+func (j *jwe) verify(token string) ([]byte, error) {
+	sig, err := jose.ParseSigned(token, []jose.SignatureAlgorithm{
+		j.signerAlgorithm, jose.ES256, jose.ES384, jose.ES512, jose.EdDSA,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMalformedToken, err.Error())
+	}
+	header := sig.Signatures[0].Header
+	for _, key := range j.candidates(header.KeyID) {
+		// algorithm of token should be the one of key, to avoid algorithm confusion
+		if alg, errA := signatureAlgorithm(key, j.signerAlgorithm); errA != nil || string(alg) != header.Algorithm {
+			continue
+		}
+		if payload, errV := sig.Verify(key.public()); errV == nil {
+			return payload, nil
+		}
+	}
+	return nil, ErrInvalidSignature
+}
+
+func (j *jwe) encrypt(payload []byte) (string, error) {
+	key, err := j.ring.Primary()
+	if err != nil {
+		return "", err
+	}
+	encrypter, err := j.newEncrypter(key)
+	if err != nil {
+		return "", err
+	}
+	enc, err := encrypter.Encrypt(payload)
+	if err != nil {
+		return "", err
 	}
 	return enc.CompactSerialize()
 }
 
-func (j *jwe) Decode(token string) (v Claim, err error) {
-	jws, err := jose.ParseEncrypted(token, []jose.KeyAlgorithm{j.keyAlgorithm}, []jose.ContentEncryption{j.contentEncryption})
+func (j *jwe) decrypt(token string) ([]byte, error) {
+	enc, err := jose.ParseEncrypted(
+		token,
+		[]jose.KeyAlgorithm{j.keyAlgorithm, jose.ECDH_ES},
+		[]jose.ContentEncryption{j.contentEncryption},
+	)
 	if err != nil {
-		return Claim{}, err
+		return nil, fmt.Errorf("%w: %s", ErrMalformedToken, err.Error())
 	}
-	rs, errD := jws.Decrypt(j.key)
-	if errD != nil {
-		return Claim{}, errD
+	for _, key := range j.candidates(enc.Header.KeyID) {
+		if alg, errA := keyAlgorithm(key, j.keyAlgorithm); errA != nil || string(alg) != enc.Header.Algorithm {
+			continue
+		}
+		if payload, errD := enc.Decrypt(key.Private); errD == nil {
+			return payload, nil
+		}
 	}
-	err = json.Unmarshal(rs, &v)
-	return
+	return nil, fmt.Errorf("%w: no key able to decrypt", ErrMalformedToken)
+}
+
+// candidates of key to decode token, token without key id (issued before key ring) tried against every active key
+func (j *jwe) candidates(kid string) []Key {
+	if len(kid) < 1 {
+		return j.ring.Active()
+	}
+	if key, ok := j.ring.Get(kid); ok {
+		return []Key{key}
+	}
+	return nil
+}
+
+func (j *jwe) newSigner(key Key) (jose.Signer, error) {
+	alg, err := signatureAlgorithm(key, j.signerAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	return jose.NewSigner(
+		jose.SigningKey{Algorithm: alg, Key: jose.JSONWebKey{Key: key.Private, KeyID: key.ID}},
+		(&jose.SignerOptions{}).WithType(tokenType),
+	)
+}
+
+func (j *jwe) newEncrypter(key Key) (jose.Encrypter, error) {
+	alg, err := keyAlgorithm(key, j.keyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	opts := &jose.EncrypterOptions{}
+	if j.mode == ModeNested {
+		// content type of nested token as required by RFC 7519 section 5.2
+		opts = opts.WithType(tokenType).WithContentType(tokenType)
+	}
+	return jose.NewEncrypter(j.contentEncryption, jose.Recipient{Algorithm: alg, Key: key.public(), KeyID: key.ID}, opts)
 }
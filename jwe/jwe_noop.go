@@ -7,6 +7,8 @@
 
 package jwe
 
+import "github.com/go-jose/go-jose/v4"
+
 type jweNoop struct{}
 
 func (j *jweNoop) Encode(v Claim) (token string, err error) {
@@ -17,6 +19,18 @@ func (j *jweNoop) Decode(token string) (v Claim, err error) {
 	return Claim{}, nil
 }
 
+func (j *jweNoop) EncodeHolder(v Holder) (token string, err error) {
+	return "", nil
+}
+
+func (j *jweNoop) DecodeHolder(token string, bindTo Holder) error {
+	return nil
+}
+
+func (j *jweNoop) JWKS() jose.JSONWebKeySet {
+	return jose.JSONWebKeySet{}
+}
+
 func (j *jweNoop) Init() error {
 	return nil
 }
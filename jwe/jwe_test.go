@@ -0,0 +1,198 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: jwe_test
+ * @Date: 08/08/24 11.20
+ */
+
+package jwe
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeValidation(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	m := NewJWE(key, WithAudience("orders"), WithLeeway(30*time.Second)).MustInit()
+	now := time.Now()
+	tests := map[string]struct {
+		claim   RegisteredClaims
+		wantErr error
+	}{
+		"defaults filled on encode, should pass": {},
+		"expired within leeway, should pass": {
+			claim: RegisteredClaims{ExpiredAt: now.Add(-10 * time.Second).Unix()},
+		},
+		"expired beyond leeway, should fail": {
+			claim:   RegisteredClaims{ExpiredAt: now.Add(-time.Minute).Unix()},
+			wantErr: ErrTokenExpired,
+		},
+		"not valid yet, should fail": {
+			claim:   RegisteredClaims{NotBefore: now.Add(time.Minute).Unix()},
+			wantErr: ErrTokenNotValidYet,
+		},
+		"issued in the future, should fail": {
+			claim:   RegisteredClaims{IssuedAt: now.Add(time.Minute).Unix()},
+			wantErr: ErrTokenUsedBeforeIssued,
+		},
+		"foreign issuer, should fail": {
+			claim:   RegisteredClaims{Issuer: "example.com"},
+			wantErr: ErrInvalidIssuer,
+		},
+		"other audience, should fail": {
+			claim:   RegisteredClaims{Audience: Audience{"payments"}},
+			wantErr: ErrInvalidAudience,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			token, errE := m.Encode(Claim{RegisteredClaims: tc.claim, ID: 1})
+			require.NoError(t, errE)
+			claim, errD := m.Decode(token)
+			assert.ErrorIs(t, errD, tc.wantErr)
+			assert.Equal(t, int64(1), claim.ID)
+		})
+	}
+	_, err = m.Decode("not a token")
+	assert.ErrorIs(t, err, ErrMalformedToken)
+}
+
+func TestCustomClaims(t *testing.T) {
+	type profile struct {
+		Name  string   `json:"name"`
+		Roles []string `json:"roles"`
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	m := NewJWE(key).MustInit()
+	token, err := EncodeClaims(m, Claims[profile]{
+		RegisteredClaims: RegisteredClaims{Subject: "42"},
+		Custom:           profile{Name: "steven", Roles: []string{"admin"}},
+	})
+	require.NoError(t, err)
+	v, err := DecodeClaims[profile](m, token)
+	require.NoError(t, err)
+	assert.Equal(t, "42", v.Subject)
+	assert.Equal(t, ISSUER, v.Issuer)
+	assert.Equal(t, v.IssuedAt+int64(DefaultExpiration.Seconds()), v.ExpiredAt)
+	assert.Equal(t, profile{Name: "steven", Roles: []string{"admin"}}, v.Custom)
+}
+
+func TestModes(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	for name, mode := range map[string]Mode{"nested": ModeNested, "signed": ModeSigned, "encrypted": ModeEncrypted} {
+		t.Run(name, func(t *testing.T) {
+			m := NewJWE(key, WithMode(mode)).MustInit()
+			token, errE := m.Encode(Claim{ID: 1})
+			require.NoError(t, errE)
+			// compact jws has 3 parts while jwe has 5 parts
+			assert.Equal(t, map[Mode]int{ModeNested: 4, ModeSigned: 2, ModeEncrypted: 4}[mode], strings.Count(token, "."))
+			claim, errD := m.Decode(token)
+			require.NoError(t, errD)
+			assert.Equal(t, int64(1), claim.ID)
+		})
+	}
+	// token encrypted with the public key without being signed should not be accepted as nested token
+	forged, err := NewJWE(key, WithMode(ModeEncrypted)).MustInit().Encode(Claim{ID: 1})
+	require.NoError(t, err)
+	_, err = NewJWE(key).MustInit().Decode(forged)
+	assert.Error(t, err)
+	// public key of encryption only mode never published, anyone holding it could issue token
+	assert.Empty(t, NewJWE(key, WithMode(ModeEncrypted)).MustInit().JWKS().Keys)
+	assert.Len(t, NewJWE(key, WithMode(ModeSigned)).MustInit().JWKS().Keys, 1)
+	// signature of another key rejected
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signed, err := NewJWE(other, WithMode(ModeSigned)).MustInit().Encode(Claim{ID: 1})
+	require.NoError(t, err)
+	_, err = NewJWE(key, WithMode(ModeSigned)).MustInit().Decode(signed)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestKeyRing(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ring, err := NewKeyRing(Key{ID: "rsa", Private: rsaKey})
+	require.NoError(t, err)
+	m := NewWithKeyRing(ring).MustInit()
+	before, err := m.Encode(Claim{ID: 1})
+	require.NoError(t, err)
+	// token of the previous key remain accepted within grace period
+	require.NoError(t, ring.Rotate(Key{ID: "ec", Private: ecKey}, time.Hour))
+	after, err := m.Encode(Claim{ID: 2})
+	require.NoError(t, err)
+	for _, token := range []string{before, after} {
+		_, err = m.Decode(token)
+		assert.NoError(t, err)
+	}
+	primary, err := ring.Primary()
+	require.NoError(t, err)
+	assert.Equal(t, "ec", primary.ID)
+	// retired key no longer accepted
+	require.NoError(t, ring.Rotate(Key{ID: "ec-2", Private: ecKey}, 0))
+	_, err = m.Decode(after)
+	assert.ErrorIs(t, err, ErrMalformedToken)
+	assert.Len(t, m.JWKS().Keys, 2)
+	// scheduled key published ahead, current primary key kept until then
+	require.NoError(t, ring.Rotate(Key{ID: "rsa-2", Private: rsaKey, NotBefore: time.Now().Add(time.Hour)}, time.Minute))
+	primary, err = ring.Primary()
+	require.NoError(t, err)
+	assert.Equal(t, "ec-2", primary.ID)
+	jwks := m.JWKS()
+	assert.Len(t, jwks.Keys, 3)
+	for _, k := range jwks.Keys {
+		assert.Empty(t, k.Use)
+	}
+	// ed25519 could only sign
+	edRing, err := NewKeyRing(Key{Private: edKey})
+	require.NoError(t, err)
+	assert.ErrorIs(t, NewWithKeyRing(edRing).Init(), ErrUnsupportedKey)
+	signed := NewWithKeyRing(edRing, WithMode(ModeSigned)).MustInit()
+	token, err := signed.Encode(Claim{ID: 3})
+	require.NoError(t, err)
+	claim, err := signed.Decode(token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), claim.ID)
+}
+
+type configReader map[string]interface{}
+
+func (c configReader) UnmarshalTo(key string, to interface{}) error {
+	return mapstructure.Decode(c[key], to)
+}
+
+func TestKeysFromConfig(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	encoded := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	keys, err := KeysFromConfig(configReader{"jwe.keys": []map[string]interface{}{
+		{"kid": "plain", "pem": string(encoded), "not_before": "2024-08-01T00:00:00Z"},
+		{"kid": "b64", "pem": base64.StdEncoding.EncodeToString(encoded)},
+	}}, "jwe.keys")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.True(t, key.Equal(keys[0].Private))
+	assert.True(t, key.Equal(keys[1].Private))
+	assert.Equal(t, time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), keys[0].NotBefore)
+}
@@ -8,8 +8,18 @@
 package jwe
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
 	"github.com/evorts/kevlars/crypt"
+	"strings"
+	"time"
 )
 
 type (
@@ -17,6 +27,24 @@ type (
 	KeyStorage interface {
 		GetPrivate() *rsa.PrivateKey
 	}
+	// ConfigReader satisfied by config.Manager
+	ConfigReader interface {
+		UnmarshalTo(key string, to interface{}) error
+	}
+	// KeyConfig of key ring entry as defined in configuration, e.g.
+	//
+	//	jwe:
+	//	  keys:
+	//	    - kid: "2024-08"
+	//	      pem: "<pem or base64 encoded pem, preferably kept in secrets>"
+	//	      not_before: "2024-08-01T00:00:00Z"
+	//	      not_after: "2024-10-01T00:00:00Z"
+	KeyConfig struct {
+		ID        string `mapstructure:"kid"`
+		PEM       string `mapstructure:"pem"`
+		NotBefore string `mapstructure:"not_before"` // RFC 3339
+		NotAfter  string `mapstructure:"not_after"`  // RFC 3339
+	}
 )
 
 func (k PrivateKey) String() string {
@@ -25,3 +53,65 @@ func (k PrivateKey) String() string {
 func (k PrivateKey) GetKey() (*rsa.PrivateKey, error) {
 	return crypt.GenerateRsaPrivateKeyFromPemString(k.String())
 }
+
+// ParsePrivateKeyPEM of rsa, ec or ed25519 key in PKCS#1, PKCS#8 or SEC 1 form, the pem could be base64 encoded
+func ParsePrivateKeyPEM(v string) (crypto.PrivateKey, error) {
+	v = strings.TrimSpace(v)
+	if !strings.HasPrefix(v, "-----BEGIN") {
+		dec, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, err
+		}
+		v = string(dec)
+	}
+	block, _ := pem.Decode([]byte(v))
+	if block == nil {
+		return nil, errors.New("failed to parse PEM block containing the key")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey, ed25519.PrivateKey:
+		return key, nil
+	}
+	return nil, ErrUnsupportedKey
+}
+
+// KeysFromConfig of the given config key, holding list of KeyConfig
+func KeysFromConfig(cfg ConfigReader, key string) ([]Key, error) {
+	var items []KeyConfig
+	if err := cfg.UnmarshalTo(key, &items); err != nil {
+		return nil, err
+	}
+	keys := make([]Key, 0, len(items))
+	for i, item := range items {
+		private, err := ParsePrivateKeyPEM(item.PEM)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %w", key, i, err)
+		}
+		k := Key{ID: item.ID, Private: private}
+		if k.NotBefore, err = parseKeyTime(item.NotBefore); err != nil {
+			return nil, fmt.Errorf("%s[%d].not_before: %w", key, i, err)
+		}
+		if k.NotAfter, err = parseKeyTime(item.NotAfter); err != nil {
+			return nil, fmt.Errorf("%s[%d].not_after: %w", key, i, err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func parseKeyTime(v string) (time.Time, error) {
+	if len(v) < 1 {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,210 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: keyring
+ * @Date: 09/08/24 08.45
+ */
+
+package jwe
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"github.com/evorts/kevlars/ctime"
+	"github.com/go-jose/go-jose/v4"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Key of key ring, private key could be *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey.
+// ed25519 key could only sign, so it is only usable as primary key on ModeSigned
+type Key struct {
+	ID        string // kid, default to the RFC 7638 thumbprint of the public key
+	Private   crypto.PrivateKey
+	NotBefore time.Time // key become primary since this time, used to schedule rotation ahead
+	NotAfter  time.Time // key no longer accepted after this time, zero means never retired
+}
+
+func (k Key) active(now time.Time) bool {
+	return !now.Before(k.NotBefore) && !k.retired(now)
+}
+
+func (k Key) retired(now time.Time) bool {
+	return !k.NotAfter.IsZero() && !now.Before(k.NotAfter)
+}
+
+func (k Key) public() crypto.PublicKey {
+	switch v := k.Private.(type) {
+	case *rsa.PrivateKey:
+		return &v.PublicKey
+	case *ecdsa.PrivateKey:
+		return &v.PublicKey
+	case ed25519.PrivateKey:
+		return v.Public()
+	}
+	return nil
+}
+
+// KeyRing of keys identified by its id, the newest active key is the primary one used to issue token,
+// while the other active keys remain accepted on decode until retired
+type KeyRing interface {
+	Add(keys ...Key) error
+	Remove(kid string)
+	// Rotate make the given key primary from its not before, default to now, and retire the current primary key
+	// after the grace period since then.
+	// grace period should be longer than lifetime of token issued by the retired key
+	Rotate(key Key, gracePeriod time.Duration) error
+	Primary() (Key, error)
+	Get(kid string) (Key, bool)
+	// Active keys ordered from the newest
+	Active() []Key
+	// JWKS of public keys not retired yet, including the scheduled ones so consumer could fetch it ahead
+	JWKS() jose.JSONWebKeySet
+}
+
+type keyRing struct {
+	mu   sync.RWMutex
+	keys map[string]Key
+}
+
+func (r *keyRing) Add(keys ...Key) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range keys {
+		pub := key.public()
+		if pub == nil {
+			return ErrUnsupportedKey
+		}
+		if len(key.ID) < 1 {
+			thumbprint, err := (&jose.JSONWebKey{Key: pub}).Thumbprint(crypto.SHA256)
+			if err != nil {
+				return err
+			}
+			key.ID = base64.RawURLEncoding.EncodeToString(thumbprint)
+		}
+		r.keys[key.ID] = key
+	}
+	return nil
+}
+
+func (r *keyRing) Remove(kid string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, kid)
+}
+
+func (r *keyRing) Rotate(key Key, gracePeriod time.Duration) error {
+	now := ctime.Now()
+	// no primary key yet when ring still empty
+	current, _ := r.Primary()
+	// scheduled key kept as is, current primary key remain until it is active
+	if key.NotBefore.IsZero() || key.NotBefore.Before(now) {
+		key.NotBefore = now
+	}
+	if err := r.Add(key); err != nil {
+		return err
+	}
+	if current.Private == nil {
+		return nil
+	}
+	current.NotAfter = key.NotBefore.Add(gracePeriod)
+	return r.Add(current)
+}
+
+func (r *keyRing) Primary() (Key, error) {
+	keys := r.Active()
+	if len(keys) < 1 {
+		return Key{}, ErrNoActiveKey
+	}
+	return keys[0], nil
+}
+
+func (r *keyRing) Get(kid string) (Key, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[kid]
+	if !ok || !key.active(ctime.Now()) {
+		return Key{}, false
+	}
+	return key, true
+}
+
+func (r *keyRing) Active() []Key {
+	now := ctime.Now()
+	r.mu.RLock()
+	keys := make([]Key, 0, len(r.keys))
+	for _, key := range r.keys {
+		if key.active(now) {
+			keys = append(keys, key)
+		}
+	}
+	r.mu.RUnlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].NotBefore.Equal(keys[j].NotBefore) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].NotBefore.After(keys[j].NotBefore)
+	})
+	return keys
+}
+
+func (r *keyRing) JWKS() jose.JSONWebKeySet {
+	now := ctime.Now()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	set := jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, 0, len(r.keys))}
+	for _, key := range r.keys {
+		if key.retired(now) {
+			continue
+		}
+		// the same key sign or encrypt depend on the manager mode, thus use and algorithm left out
+		set.Keys = append(set.Keys, jose.JSONWebKey{
+			Key:   key.public(),
+			KeyID: key.ID,
+		})
+	}
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].KeyID < set.Keys[j].KeyID
+	})
+	return set
+}
+
+// signatureAlgorithm of key, rsa key use the given algorithm while the others determined by its type
+func signatureAlgorithm(key Key, rsaAlgorithm jose.SignatureAlgorithm) (jose.SignatureAlgorithm, error) {
+	switch v := key.Private.(type) {
+	case *rsa.PrivateKey:
+		return rsaAlgorithm, nil
+	case *ecdsa.PrivateKey:
+		switch v.Curve.Params().BitSize {
+		case 256:
+			return jose.ES256, nil
+		case 384:
+			return jose.ES384, nil
+		case 521:
+			return jose.ES512, nil
+		}
+	case ed25519.PrivateKey:
+		return jose.EdDSA, nil
+	}
+	return "", ErrUnsupportedKey
+}
+
+// keyAlgorithm of key management on encryption, ed25519 key could not be used for encryption
+func keyAlgorithm(key Key, rsaAlgorithm jose.KeyAlgorithm) (jose.KeyAlgorithm, error) {
+	switch key.Private.(type) {
+	case *rsa.PrivateKey:
+		return rsaAlgorithm, nil
+	case *ecdsa.PrivateKey:
+		return jose.ECDH_ES, nil
+	}
+	return "", ErrUnsupportedKey
+}
+
+func NewKeyRing(keys ...Key) (KeyRing, error) {
+	r := &keyRing{keys: make(map[string]Key)}
+	return r, r.Add(keys...)
+}
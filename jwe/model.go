@@ -13,28 +13,66 @@ import (
 )
 
 const (
+	// ISSUER default issuer of token, override through WithIssuer
 	ISSUER            = "evorts.com"
 	DefaultExpiration = 1 * time.Hour //in hour
+
+	// authentication method references as defined by RFC 8176
+	AMRPassword        = "pwd"
+	AMROneTimePassword = "otp"
+)
+
+// Mode of token produced and accepted by manager
+type Mode uint8
+
+const (
+	// ModeNested sign then encrypt the signed token as described by RFC 7519 section 5.2
+	ModeNested Mode = iota
+	// ModeSigned only sign the token (JWS), the claims readable by any party holding the token
+	ModeSigned
+	// ModeEncrypted only encrypt the token, as issued before nested token supported.
+	// encryption use the public key, so prefer ModeNested unless the public key kept secret
+	ModeEncrypted
 )
 
+const tokenType = "JWT"
+
+// Metadata of claim
+//
+// Deprecated: untyped, use Claims with custom claims struct instead
 type Metadata map[string]any
 
 func (m Metadata) ToStruct(bindTo any) error {
 	return mapstructure.Decode(m, bindTo)
 }
 
+// Claim of token issued by auth package, registered claims flattened along with the private claims
 type Claim struct {
-	Issuer   string   `json:"issuer"`
-	ClientID string   `json:"client_id"`
-	ID       int64    `json:"id"`
-	Meta     Metadata `json:"meta"`
+	RegisteredClaims
+	ClientID  string   `json:"client_id,omitempty"`
+	ID        int64    `json:"id,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	SessionID string   `json:"sid,omitempty"`
+	TenantID  string   `json:"tid,omitempty"`
+	Scope     string   `json:"scope,omitempty"` // space separated as defined by RFC 8693 section 4.2
+	AMR       []string `json:"amr,omitempty"`
+	StepUpAt  int64    `json:"step_up_at,omitempty"` // unix timestamp of the latest second factor verification
+	Meta      Metadata `json:"meta,omitempty"`
+}
+
+// SteppedUp when second factor verified within the max age, zero max age means no limit
+func (c Claim) SteppedUp(now time.Time, maxAge time.Duration) bool {
+	if c.StepUpAt < 1 {
+		return false
+	}
+	return maxAge <= 0 || now.Sub(time.Unix(c.StepUpAt, 0)) <= maxAge
 }
 
 func NewClaim(clientID string, id int64, meta Metadata) *Claim {
 	return &Claim{
-		Issuer:   ISSUER,
-		ClientID: clientID,
-		ID:       id,
-		Meta:     meta,
+		RegisteredClaims: RegisteredClaims{Issuer: ISSUER},
+		ClientID:         clientID,
+		ID:               id,
+		Meta:             meta,
 	}
 }
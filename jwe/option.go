@@ -20,12 +20,41 @@ func WithPrivateKey(v *rsa.PrivateKey) common.Option[jwe] {
 	})
 }
 
+// WithKeyRing of keys, private key set through NewJWE or WithPrivateKey added into the ring on init
+func WithKeyRing(v KeyRing) common.Option[jwe] {
+	return common.OptionFunc[jwe](func(j *jwe) {
+		j.ring = v
+	})
+}
+
+// WithExpiration of token encoded without expiration, zero make expiration optional on decode
 func WithExpiration(v time.Duration) common.Option[jwe] {
 	return common.OptionFunc[jwe](func(j *jwe) {
 		j.expire = v
 	})
 }
 
+// WithIssuer set into token on encode and expected on decode, empty issuer skip the check
+func WithIssuer(v string) common.Option[jwe] {
+	return common.OptionFunc[jwe](func(j *jwe) {
+		j.issuer = v
+	})
+}
+
+// WithAudience set into token without audience on encode, token should be intended for any of them on decode
+func WithAudience(v ...string) common.Option[jwe] {
+	return common.OptionFunc[jwe](func(j *jwe) {
+		j.audience = v
+	})
+}
+
+// WithLeeway of clock skew between issuer and consumer on validating exp, nbf and iat
+func WithLeeway(v time.Duration) common.Option[jwe] {
+	return common.OptionFunc[jwe](func(j *jwe) {
+		j.leeway = v
+	})
+}
+
 func WithKeyAlgorithm(v jose.KeyAlgorithm) common.Option[jwe] {
 	return common.OptionFunc[jwe](func(j *jwe) {
 		j.keyAlgorithm = v
@@ -43,3 +72,10 @@ func WithContentEncryption(v jose.ContentEncryption) common.Option[jwe] {
 		j.contentEncryption = v
 	})
 }
+
+// WithMode of token, default to ModeNested
+func WithMode(v Mode) common.Option[jwe] {
+	return common.OptionFunc[jwe](func(j *jwe) {
+		j.mode = v
+	})
+}
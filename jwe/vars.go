@@ -0,0 +1,23 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: vars
+ * @Date: 08/08/24 10.05
+ */
+
+package jwe
+
+import "errors"
+
+var (
+	ErrMalformedToken        = errors.New("malformed token")
+	ErrInvalidSignature      = errors.New("invalid token signature")
+	ErrTokenExpired          = errors.New("token expired")
+	ErrTokenNotValidYet      = errors.New("token not valid yet")
+	ErrTokenUsedBeforeIssued = errors.New("token used before issued")
+	ErrMissingExpiration     = errors.New("token has no expiration")
+	ErrInvalidIssuer         = errors.New("invalid token issuer")
+	ErrInvalidAudience       = errors.New("invalid token audience")
+	ErrUnsupportedKey        = errors.New("unsupported key type")
+	ErrNoActiveKey           = errors.New("no active key")
+)
@@ -94,6 +94,9 @@ type Manager interface {
 
 	Panic(messages ...interface{})
 	PanicWithProps(props map[string]interface{}, messages ...interface{})
+
+	// SetLevel change the level on the fly, e.g. following the config changes
+	SetLevel(level LogLevel)
 }
 
 type manager struct {
@@ -102,6 +105,14 @@ type manager struct {
 	name string
 }
 
+func (m *manager) SetLevel(level LogLevel) {
+	logrusLogLevel, err := logrus.ParseLevel(level.LogRushString())
+	if err != nil {
+		logrusLogLevel = logrus.ErrorLevel
+	}
+	m.l.SetLevel(logrusLogLevel)
+}
+
 func (m *manager) Trace(messages ...interface{}) {
 	m.l.Traceln(messages...)
 }
@@ -101,6 +101,10 @@ func (m *noop) PanicWithProps(props map[string]interface{}, messages ...interfac
 	// do nothing
 }
 
+func (m *noop) SetLevel(level LogLevel) {
+	// do nothing
+}
+
 func NewNoop() Manager {
 	return &noop{}
 }
@@ -4,11 +4,13 @@ import (
 	"context"
 	"github.com/evorts/kevlars/auth"
 	"github.com/evorts/kevlars/contracts"
+	"github.com/evorts/kevlars/ctime"
 	"github.com/evorts/kevlars/jwe"
 	"github.com/evorts/kevlars/logger"
 	"github.com/evorts/kevlars/requests"
 	"github.com/evorts/kevlars/utils"
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
@@ -26,7 +28,7 @@ func EchoWithClientAuthorization(ac auth.ClientManager, log logger.Manager) echo
 			method := req.Method
 			key := apiKeyFromHeader(req)
 			scope := auth.Scope("").FromHttpMethod(req.Method)
-			cm, allowed := ac.IsAllowed(key, resource, scope)
+			cm, allowed := ac.IsAllowed(req.Context(), key, resource, scope)
 			if !allowed {
 				log.ErrorWithProps(map[string]interface{}{
 					"cid":    cm,
@@ -78,6 +80,33 @@ func EchoWithUserAuthorization(aum auth.UserManager) echo.MiddlewareFunc {
 	}
 }
 
+// EchoWithMFA require user token carrying second factor verified within max age, zero max age means no limit.
+// place it on routes of sensitive operation, user without it should step up through auth.UserManager.StepUp
+func EchoWithMFA(aum auth.UserManager, maxAge time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			token := tokenFromHeader(req)
+			if len(token) < 1 {
+				return c.JSON(contracts.NewResponseFail(http.StatusUnauthorized, "not eligible to access this resource", contracts.ErrorDetail{}))
+			}
+			var claim jwe.Claim
+			if err := aum.Introspect(req.Context(), token, &claim); err != nil {
+				return c.JSON(contracts.NewResponseFail(http.StatusUnauthorized, "invalid token", contracts.ErrorDetail{}))
+			}
+			if !claim.SteppedUp(ctime.Now(), maxAge) {
+				return c.JSON(contracts.NewResponseFail(http.StatusUnauthorized, "second factor verification required", contracts.ErrorDetail{
+					Code: "ERR:NOK:MFA",
+					Errors: map[string]string{
+						"err": "step up required",
+					},
+				}))
+			}
+			return next(c)
+		}
+	}
+}
+
 func echoWithAuthApiKeySecretsEligibleClients(maps []ApiKeySecretMap, compareFunc func(items []string, item string) bool, clientIds ...string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
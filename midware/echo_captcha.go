@@ -0,0 +1,51 @@
+package midware
+
+import (
+	"github.com/evorts/kevlars/captcha"
+	"github.com/evorts/kevlars/contracts"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type CaptchaChallenge struct {
+	ID      string `json:"id"`
+	Captcha string `json:"captcha"` // base64 data uri of image or audio
+}
+
+type CaptchaAnswer struct {
+	ID     string `json:"id" form:"id"`
+	Answer string `json:"answer" form:"answer"`
+}
+
+// EchoCaptchaGenerateHandler issue new captcha challenge, e.g. e.POST("/captcha", midware.EchoCaptchaGenerateHandler(m))
+func EchoCaptchaGenerateHandler(m captcha.Manager[string]) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id, _, result := m.Generate()
+		if len(id) < 1 {
+			return c.JSON(contracts.NewResponseInternalServerError("failed to generate captcha"))
+		}
+		c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+		return c.JSON(contracts.NewResponseOK("captcha generated", CaptchaChallenge{ID: id, Captcha: result}))
+	}
+}
+
+// EchoCaptchaVerifyHandler verify answer of the challenge, the challenge could not be answered again afterward
+func EchoCaptchaVerifyHandler(m captcha.Manager[string]) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req CaptchaAnswer
+		if err := c.Bind(&req); err != nil {
+			return c.JSON(contracts.NewResponseBadRequest("invalid captcha answer"))
+		}
+		if !m.Verify(req.ID, strings.TrimSpace(req.Answer), true) {
+			return c.JSON(contracts.NewResponseFail(http.StatusUnprocessableEntity, "captcha not valid", contracts.ErrorDetail{
+				Code: "ERR:NOK:CAPTCHA",
+				Errors: map[string]string{
+					"answer": "incorrect or expired",
+				},
+			}))
+		}
+		return c.JSON(contracts.NewResponseOK("captcha valid", map[string]bool{"valid": true}))
+	}
+}
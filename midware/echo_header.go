@@ -69,3 +69,14 @@ func EchoResponseWithProperRequestId(log logger.Manager) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// EchoWithRemoteIp put real ip of requester into native context, as used by brute-force protection of user auth
+func EchoWithRemoteIp() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			c.SetRequest(req.WithContext(requests.WithRemoteIp(req.Context(), c.RealIP())))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package midware
+
+import (
+	"github.com/evorts/kevlars/jwe"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// JWKSPath well known location of the published key set
+const JWKSPath = "/.well-known/jwks.json"
+
+// EchoJWKSHandler publish public keys of jwe manager, e.g. e.GET(midware.JWKSPath, midware.EchoJWKSHandler(m)).
+// manager publishing no key, e.g. of encryption only mode, respond with not found
+func EchoJWKSHandler(m jwe.Manager) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// keep it short so consumer pick up rotated keys soon enough
+		c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+		set := m.JWKS()
+		if len(set.Keys) < 1 {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		return c.JSON(http.StatusOK, set)
+	}
+}
@@ -0,0 +1,105 @@
+package midware
+
+import (
+	"context"
+	"errors"
+	"github.com/evorts/kevlars/auth"
+	"github.com/evorts/kevlars/contracts"
+	"github.com/evorts/kevlars/logger"
+	"github.com/evorts/kevlars/requests"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// oauthError response as defined by RFC 6749 section 5.2
+type oauthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// EchoClientCredentialsTokenHandler token endpoint of client credentials grant (RFC 6749 section 4.4).
+// client authenticate through http basic auth or client_id and client_secret form parameters
+func EchoClientCredentialsTokenHandler(ac auth.ClientManager, log logger.Manager) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+		if c.FormValue("grant_type") != auth.GrantTypeClientCredentials {
+			return c.JSON(http.StatusBadRequest, oauthError{Error: "unsupported_grant_type"})
+		}
+		clientId, secret := clientCredentialsFromRequest(c)
+		if len(clientId) < 1 {
+			return c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_request", Description: "client authentication required"})
+		}
+		token, err := ac.IssueToken(c.Request().Context(), clientId, secret, c.FormValue("scope"))
+		if err != nil {
+			log.ErrorWithProps(map[string]interface{}{
+				"cid":  clientId,
+				"path": c.Request().URL.Path,
+			}, err.Error())
+		}
+		switch {
+		case err == nil:
+			return c.JSON(http.StatusOK, token)
+		case errors.Is(err, auth.ErrInvalidClient):
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="token"`)
+			return c.JSON(http.StatusUnauthorized, oauthError{Error: "invalid_client"})
+		case errors.Is(err, auth.ErrInvalidScope):
+			return c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_scope"})
+		default:
+			return c.JSON(http.StatusInternalServerError, oauthError{Error: "server_error"})
+		}
+	}
+}
+
+// EchoTokenIntrospectionHandler introspection endpoint (RFC 7662), caller should authenticate as client
+func EchoTokenIntrospectionHandler(ac auth.ClientManager) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if clientId, secret := clientCredentialsFromRequest(c); !ac.Authenticate(c.Request().Context(), clientId, secret) {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="introspect"`)
+			return c.JSON(http.StatusUnauthorized, oauthError{Error: "invalid_client"})
+		}
+		token := c.FormValue("token")
+		if len(token) < 1 {
+			return c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_request", Description: "token required"})
+		}
+		// reason of inactive token should not be exposed to the caller
+		rs, _ := ac.IntrospectToken(c.Request().Context(), token)
+		return c.JSON(http.StatusOK, rs)
+	}
+}
+
+// EchoWithClientTokenAuthorization authorize request bearing token issued by client credentials grant
+func EchoWithClientTokenAuthorization(ac auth.ClientManager, log logger.Manager) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			resource := req.URL.Path
+			scope := auth.Scope("").FromHttpMethod(req.Method)
+			cm, allowed := ac.IsTokenAllowed(req.Context(), tokenFromHeader(req), resource, scope)
+			if !allowed {
+				log.ErrorWithProps(map[string]interface{}{
+					"cid":    cm,
+					"method": req.Method,
+					"path":   resource,
+				}, "request not allowed")
+				return c.JSON(contracts.NewResponseFail(http.StatusUnauthorized, "Not authorized to access this resource", contracts.ErrorDetail{
+					Code: "ERR:NOK:AUTH",
+					Errors: map[string]string{
+						"err": "token not acceptable",
+					},
+				}))
+			}
+			// set client id in both echo context and native context
+			c.Set(requests.ContextClientId.String(), cm)
+			c.SetRequest(req.WithContext(context.WithValue(req.Context(), requests.ContextClientId, cm)))
+			return next(c)
+		}
+	}
+}
+
+func clientCredentialsFromRequest(c echo.Context) (clientId, secret string) {
+	if id, pass, ok := c.Request().BasicAuth(); ok {
+		return id, pass
+	}
+	return c.FormValue("client_id"), c.FormValue("client_secret")
+}
@@ -0,0 +1,88 @@
+package midware
+
+import (
+	"github.com/evorts/kevlars/contracts"
+	"github.com/evorts/kevlars/jwe"
+	"github.com/evorts/kevlars/requests"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// TenantResolver resolve tenant id of request, empty means unresolved
+type TenantResolver func(c echo.Context) string
+
+// TenantFromHeader resolve tenant from X-TENANT-ID header
+func TenantFromHeader() TenantResolver {
+	return func(c echo.Context) string {
+		return strings.TrimSpace(c.Request().Header.Get(requests.HeaderTenantId.String()))
+	}
+}
+
+// TenantFromSubdomain resolve tenant from subdomain of the given base domain,
+// e.g. request to acme.example.com with base domain example.com resolved as acme
+func TenantFromSubdomain(baseDomain string) TenantResolver {
+	suffix := "." + strings.TrimPrefix(strings.ToLower(baseDomain), ".")
+	return func(c echo.Context) string {
+		host := strings.ToLower(c.Request().Host)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !strings.HasSuffix(host, suffix) {
+			return ""
+		}
+		sub := strings.TrimSuffix(host, suffix)
+		// only the closest label to base domain considered as tenant
+		if idx := strings.LastIndex(sub, "."); idx >= 0 {
+			sub = sub[idx+1:]
+		}
+		return sub
+	}
+}
+
+// TenantFromToken resolve tenant from claim of bearer token
+func TenantFromToken(j jwe.Manager) TenantResolver {
+	return func(c echo.Context) string {
+		token := tokenFromHeader(c.Request())
+		if len(token) < 1 {
+			return ""
+		}
+		claim, err := j.Decode(token)
+		if err != nil {
+			return ""
+		}
+		return claim.TenantID
+	}
+}
+
+// EchoWithTenant put tenant id resolved by the first resolver returning non-empty value
+// into both echo context and native context. when required, request without tenant will be rejected
+func EchoWithTenant(required bool, resolvers ...TenantResolver) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			tenantId := ""
+			for _, resolve := range resolvers {
+				if tenantId = resolve(c); len(tenantId) > 0 {
+					break
+				}
+			}
+			if len(tenantId) < 1 {
+				if required {
+					return c.JSON(contracts.NewResponseFail(http.StatusBadRequest, "Tenant could not be resolved", contracts.ErrorDetail{
+						Code: "ERR:NOK:TENANT",
+						Errors: map[string]string{
+							"err": "tenant required",
+						},
+					}))
+				}
+				return next(c)
+			}
+			req := c.Request()
+			c.Set(requests.ContextTenantId.String(), tenantId)
+			c.SetRequest(req.WithContext(requests.WithTenantId(req.Context(), tenantId)))
+			return next(c)
+		}
+	}
+}
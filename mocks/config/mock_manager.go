@@ -3,6 +3,8 @@
 package config
 
 import (
+	context "context"
+
 	config "github.com/evorts/kevlars/config"
 	mock "github.com/stretchr/testify/mock"
 
@@ -69,6 +71,62 @@ func (_c *Manager_AllSettings_Call) RunAndReturn(run func() map[string]interface
 	return _c
 }
 
+// Explain provides a mock function with given fields: key
+func (_m *Manager) Explain(key string) (config.Provenance, bool) {
+	ret := _m.Called(key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Explain")
+	}
+
+	var r0 config.Provenance
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(string) (config.Provenance, bool)); ok {
+		return rf(key)
+	}
+	if rf, ok := ret.Get(0).(func(string) config.Provenance); ok {
+		r0 = rf(key)
+	} else {
+		r0 = ret.Get(0).(config.Provenance)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = rf(key)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// Manager_Explain_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Explain'
+type Manager_Explain_Call struct {
+	*mock.Call
+}
+
+// Explain is a helper method to define mock.On call
+//   - key string
+func (_e *Manager_Expecter) Explain(key interface{}) *Manager_Explain_Call {
+	return &Manager_Explain_Call{Call: _e.mock.On("Explain", key)}
+}
+
+func (_c *Manager_Explain_Call) Run(run func(key string)) *Manager_Explain_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string))
+	})
+	return _c
+}
+
+func (_c *Manager_Explain_Call) Return(_a0 config.Provenance, _a1 bool) *Manager_Explain_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Manager_Explain_Call) RunAndReturn(run func(string) (config.Provenance, bool)) *Manager_Explain_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Get provides a mock function with given fields: key
 func (_m *Manager) Get(key string) interface{} {
 	ret := _m.Called(key)
@@ -634,6 +692,55 @@ func (_c *Manager_GetMapArray_Call) RunAndReturn(run func(string) []map[string]i
 	return _c
 }
 
+// GetMapArrayOrElse provides a mock function with given fields: key, orElse
+func (_m *Manager) GetMapArrayOrElse(key string, orElse []map[string]interface{}) []map[string]interface{} {
+	ret := _m.Called(key, orElse)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMapArrayOrElse")
+	}
+
+	var r0 []map[string]interface{}
+	if rf, ok := ret.Get(0).(func(string, []map[string]interface{}) []map[string]interface{}); ok {
+		r0 = rf(key, orElse)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]map[string]interface{})
+		}
+	}
+
+	return r0
+}
+
+// Manager_GetMapArrayOrElse_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetMapArrayOrElse'
+type Manager_GetMapArrayOrElse_Call struct {
+	*mock.Call
+}
+
+// GetMapArrayOrElse is a helper method to define mock.On call
+//   - key string
+//   - orElse []map[string]interface{}
+func (_e *Manager_Expecter) GetMapArrayOrElse(key interface{}, orElse interface{}) *Manager_GetMapArrayOrElse_Call {
+	return &Manager_GetMapArrayOrElse_Call{Call: _e.mock.On("GetMapArrayOrElse", key, orElse)}
+}
+
+func (_c *Manager_GetMapArrayOrElse_Call) Run(run func(key string, orElse []map[string]interface{})) *Manager_GetMapArrayOrElse_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].([]map[string]interface{}))
+	})
+	return _c
+}
+
+func (_c *Manager_GetMapArrayOrElse_Call) Return(_a0 []map[string]interface{}) *Manager_GetMapArrayOrElse_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Manager_GetMapArrayOrElse_Call) RunAndReturn(run func(string, []map[string]interface{}) []map[string]interface{}) *Manager_GetMapArrayOrElse_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetString provides a mock function with given fields: key
 func (_m *Manager) GetString(key string) string {
 	ret := _m.Called(key)
@@ -1202,6 +1309,40 @@ func (_c *Manager_MustInit_Call) RunAndReturn(run func() config.Manager) *Manage
 	return _c
 }
 
+// OnChange provides a mock function with given fields: key, fn
+func (_m *Manager) OnChange(key string, fn config.ChangeFunc) {
+	_m.Called(key, fn)
+}
+
+// Manager_OnChange_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'OnChange'
+type Manager_OnChange_Call struct {
+	*mock.Call
+}
+
+// OnChange is a helper method to define mock.On call
+//   - key string
+//   - fn config.ChangeFunc
+func (_e *Manager_Expecter) OnChange(key interface{}, fn interface{}) *Manager_OnChange_Call {
+	return &Manager_OnChange_Call{Call: _e.mock.On("OnChange", key, fn)}
+}
+
+func (_c *Manager_OnChange_Call) Run(run func(key string, fn config.ChangeFunc)) *Manager_OnChange_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(string), args[1].(config.ChangeFunc))
+	})
+	return _c
+}
+
+func (_c *Manager_OnChange_Call) Return() *Manager_OnChange_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Manager_OnChange_Call) RunAndReturn(run func(string, config.ChangeFunc)) *Manager_OnChange_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UnmarshalTo provides a mock function with given fields: key, to
 func (_m *Manager) UnmarshalTo(key string, to interface{}) error {
 	ret := _m.Called(key, to)
@@ -1249,6 +1390,52 @@ func (_c *Manager_UnmarshalTo_Call) RunAndReturn(run func(string, interface{}) e
 	return _c
 }
 
+// Watch provides a mock function with given fields: ctx
+func (_m *Manager) Watch(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Watch")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Manager_Watch_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Watch'
+type Manager_Watch_Call struct {
+	*mock.Call
+}
+
+// Watch is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Manager_Expecter) Watch(ctx interface{}) *Manager_Watch_Call {
+	return &Manager_Watch_Call{Call: _e.mock.On("Watch", ctx)}
+}
+
+func (_c *Manager_Watch_Call) Run(run func(ctx context.Context)) *Manager_Watch_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Manager_Watch_Call) Return(_a0 error) *Manager_Watch_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Manager_Watch_Call) RunAndReturn(run func(context.Context) error) *Manager_Watch_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewManager creates a new instance of Manager. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewManager(t interface {
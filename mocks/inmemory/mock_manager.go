@@ -24,6 +24,66 @@ func (_m *Manager) EXPECT() *Manager_Expecter {
 	return &Manager_Expecter{mock: &_m.Mock}
 }
 
+// CompareAndSwap provides a mock function with given fields: ctx, key, old, new, expire
+func (_m *Manager) CompareAndSwap(ctx context.Context, key string, old string, new string, expire time.Duration) (bool, error) {
+	ret := _m.Called(ctx, key, old, new, expire)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CompareAndSwap")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, time.Duration) (bool, error)); ok {
+		return rf(ctx, key, old, new, expire)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, time.Duration) bool); ok {
+		r0 = rf(ctx, key, old, new, expire)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, time.Duration) error); ok {
+		r1 = rf(ctx, key, old, new, expire)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Manager_CompareAndSwap_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CompareAndSwap'
+type Manager_CompareAndSwap_Call struct {
+	*mock.Call
+}
+
+// CompareAndSwap is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+//   - old string
+//   - new string
+//   - expire time.Duration
+func (_e *Manager_Expecter) CompareAndSwap(ctx interface{}, key interface{}, old interface{}, new interface{}, expire interface{}) *Manager_CompareAndSwap_Call {
+	return &Manager_CompareAndSwap_Call{Call: _e.mock.On("CompareAndSwap", ctx, key, old, new, expire)}
+}
+
+func (_c *Manager_CompareAndSwap_Call) Run(run func(ctx context.Context, key string, old string, new string, expire time.Duration)) *Manager_CompareAndSwap_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string), args[4].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *Manager_CompareAndSwap_Call) Return(_a0 bool, _a1 error) *Manager_CompareAndSwap_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Manager_CompareAndSwap_Call) RunAndReturn(run func(context.Context, string, string, string, time.Duration) (bool, error)) *Manager_CompareAndSwap_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Connect provides a mock function with given fields: ctx
 func (_m *Manager) Connect(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -385,6 +445,65 @@ func (_c *Manager_HGetAll_Call) RunAndReturn(run func(context.Context, string, i
 	return _c
 }
 
+// HKeys provides a mock function with given fields: ctx, key
+func (_m *Manager) HKeys(ctx context.Context, key string) ([]string, error) {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for HKeys")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]string, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []string); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Manager_HKeys_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'HKeys'
+type Manager_HKeys_Call struct {
+	*mock.Call
+}
+
+// HKeys is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+func (_e *Manager_Expecter) HKeys(ctx interface{}, key interface{}) *Manager_HKeys_Call {
+	return &Manager_HKeys_Call{Call: _e.mock.On("HKeys", ctx, key)}
+}
+
+func (_c *Manager_HKeys_Call) Run(run func(ctx context.Context, key string)) *Manager_HKeys_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *Manager_HKeys_Call) Return(_a0 []string, _a1 error) *Manager_HKeys_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Manager_HKeys_Call) RunAndReturn(run func(context.Context, string) ([]string, error)) *Manager_HKeys_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // HMGet provides a mock function with given fields: ctx, key, field, bindTo
 func (_m *Manager) HMGet(ctx context.Context, key string, field string, bindTo interface{}) error {
 	ret := _m.Called(ctx, key, field, bindTo)
@@ -599,6 +718,64 @@ func (_c *Manager_HSetWhenNotExist_Call) RunAndReturn(run func(context.Context,
 	return _c
 }
 
+// Incr provides a mock function with given fields: ctx, key, expire
+func (_m *Manager) Incr(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	ret := _m.Called(ctx, key, expire)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Incr")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Duration) (int64, error)); ok {
+		return rf(ctx, key, expire)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Duration) int64); ok {
+		r0 = rf(ctx, key, expire)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Duration) error); ok {
+		r1 = rf(ctx, key, expire)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Manager_Incr_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Incr'
+type Manager_Incr_Call struct {
+	*mock.Call
+}
+
+// Incr is a helper method to define mock.On call
+//   - ctx context.Context
+//   - key string
+//   - expire time.Duration
+func (_e *Manager_Expecter) Incr(ctx interface{}, key interface{}, expire interface{}) *Manager_Incr_Call {
+	return &Manager_Incr_Call{Call: _e.mock.On("Incr", ctx, key, expire)}
+}
+
+func (_c *Manager_Incr_Call) Run(run func(ctx context.Context, key string, expire time.Duration)) *Manager_Incr_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *Manager_Incr_Call) Return(_a0 int64, _a1 error) *Manager_Incr_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Manager_Incr_Call) RunAndReturn(run func(context.Context, string, time.Duration) (int64, error)) *Manager_Incr_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // MustConnect provides a mock function with given fields: ctx
 func (_m *Manager) MustConnect(ctx context.Context) inmemory.Manager {
 	ret := _m.Called(ctx)
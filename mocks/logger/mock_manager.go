@@ -2,7 +2,10 @@
 
 package logger
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	logger "github.com/evorts/kevlars/logger"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // Manager is an autogenerated mock type for the Manager type
 type Manager struct {
@@ -629,6 +632,39 @@ func (_c *Manager_PanicWithProps_Call) RunAndReturn(run func(map[string]interfac
 	return _c
 }
 
+// SetLevel provides a mock function with given fields: level
+func (_m *Manager) SetLevel(level logger.LogLevel) {
+	_m.Called(level)
+}
+
+// Manager_SetLevel_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetLevel'
+type Manager_SetLevel_Call struct {
+	*mock.Call
+}
+
+// SetLevel is a helper method to define mock.On call
+//   - level logger.LogLevel
+func (_e *Manager_Expecter) SetLevel(level interface{}) *Manager_SetLevel_Call {
+	return &Manager_SetLevel_Call{Call: _e.mock.On("SetLevel", level)}
+}
+
+func (_c *Manager_SetLevel_Call) Run(run func(level logger.LogLevel)) *Manager_SetLevel_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(logger.LogLevel))
+	})
+	return _c
+}
+
+func (_c *Manager_SetLevel_Call) Return() *Manager_SetLevel_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *Manager_SetLevel_Call) RunAndReturn(run func(logger.LogLevel)) *Manager_SetLevel_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Trace provides a mock function with given fields: messages
 func (_m *Manager) Trace(messages ...interface{}) {
 	var _ca []interface{}
@@ -17,6 +17,7 @@ const (
 	HeaderClientId       Header = "X-CLIENT-ID"
 	HeaderSignature      Header = "X-SIGNATURE"
 	HeaderIdempotencyKey Header = "X-IDEMPOTENCY-KEY"
+	HeaderTenantId       Header = "X-TENANT-ID"
 )
 
 func (h Header) String() string { return string(h) }
@@ -121,6 +121,37 @@ func ClientId(ctx context.Context) string {
 	})
 }
 
+// TenantIdEcho get tenant id from echo context
+func TenantIdEcho(ec echo.Context) string {
+	return rules.WhenTrueRE1(eval.IsNil(ec.Get(ContextTenantId.String())), func() string {
+		return ""
+	}, func() string {
+		return ec.Get(ContextTenantId.String()).(string)
+	})
+}
+
+// TenantId get tenant id from context, empty means no tenant
+func TenantId(ctx context.Context) string {
+	tenantId, _ := ctx.Value(ContextTenantId).(string)
+	return tenantId
+}
+
+// WithTenantId put tenant id into context
+func WithTenantId(ctx context.Context, tenantId string) context.Context {
+	return context.WithValue(ctx, ContextTenantId, tenantId)
+}
+
+// RemoteIp get ip address of the requester from context
+func RemoteIp(ctx context.Context) string {
+	ip, _ := ctx.Value(ContextRemoteIp).(string)
+	return ip
+}
+
+// WithRemoteIp put ip address of the requester into context
+func WithRemoteIp(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, ContextRemoteIp, ip)
+}
+
 // GenerateUUIDV4 produce UUIDv4 string value
 func GenerateUUIDV4() string {
 	return uuid.Must(uuid.NewV4()).String()
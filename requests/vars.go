@@ -18,6 +18,8 @@ const (
 	ContextClientId  RequestContext = "client_id"
 	ContextRequestId RequestContext = "request_id"
 	ContextSignature RequestContext = "signature"
+	ContextTenantId  RequestContext = "tenant_id"
+	ContextRemoteIp  RequestContext = "remote_ip"
 )
 
 func (t RequestContext) String() string {
@@ -157,11 +157,15 @@ func NewApp(opts ...Option) IApplication {
 	app.portGrpc = app.Config().GetIntOrElse(AppPortGrpc.String(), 9090)
 	app.startContext = context.Background()
 	app.gracefulTimeout = app.Config().GetDurationOrElse(AppGracefulTimeout.String(), 10*time.Second)
+	app.maskedFields = app.Config().GetStringSlice(AppMaskedFields.String())
+	app.maskedHeaders = app.Config().GetStringSlice(AppMaskedHeaders.String())
 
 	for _, opt := range opts {
 		opt.apply(app)
 	}
 	app.withLogger()
+	app.withConfigWatch()
 	app.withHealthcheck()
+	app.withConfigDump()
 	return app
 }
@@ -25,17 +25,17 @@ func (app *Application) WithClientAuthorization() IApplication {
 		auth.ClientWithDatabaseRead(app.DefaultDBR()),
 		auth.ClientWithLogger(app.Log()),
 	)
-	if enabled := app.Config().GetBool("auth.client.migrations.enabled"); enabled {
+	if enabled := app.Config().GetBool(AuthClientMigrationsEnabled.String()); enabled {
 		app.authClient.AddOptions(
 			auth.ClientWithExecuteMigration(
 				enabled,
-				app.Config().GetStringSliceOrElse("auth.client.migrations.dir", []string{})...,
+				app.Config().GetStringSliceOrElse(AuthClientMigrationsDir.String(), []string{})...,
 			),
 		)
 	}
-	if v := app.Config().GetBool("auth.client.use_in_memory"); v && app.HasInMemories() {
+	if v := app.Config().GetBool(AuthClientUseInMemory.String()); v && app.HasInMemories() {
 		// when use in memory are true, use default when the defined key not found
-		if im := app.Config().GetString("auth.client.in_memory_instance"); len(im) > 0 && app.HasInMemory(im) {
+		if im := app.Config().GetString(AuthClientInMemoryInstance.String()); len(im) > 0 && app.HasInMemory(im) {
 			app.authClient.AddOptions(auth.ClientWithInMemory(app.InMemory(im)))
 		} else {
 			app.authClient.AddOptions(auth.ClientWithInMemory(app.DefaultInMemory()))
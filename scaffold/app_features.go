@@ -25,15 +25,15 @@ func (app *Application) WithFeatureFlag() IApplication {
 		fflag.WithDatabaseRead(app.DefaultDBR()),
 		fflag.WithLogger(app.Log()),
 	)
-	if enabled := app.Config().GetBool("feature_flag.migrations.enabled"); enabled {
+	if enabled := app.Config().GetBool(FeatureFlagMigrationsEnabled.String()); enabled {
 		app.featureFlag.AddOptions(
 			fflag.WithExecuteMigration(
 				enabled,
-				app.Config().GetStringSliceOrElse("feature_flag.migrations.dir", []string{})...,
+				app.Config().GetStringSliceOrElse(FeatureFlagMigrationsDir.String(), []string{})...,
 			),
 		)
 	}
-	if v := app.Config().GetBool("feature_flag.lazy_load_data"); v {
+	if v := app.Config().GetBool(FeatureFlagLazyLoadData.String()); v {
 		app.featureFlag.AddOptions(fflag.WithLazyLoadData(v))
 	}
 	app.featureFlag.MustInit()
@@ -8,9 +8,12 @@
 package scaffold
 
 import (
+	"github.com/evorts/kevlars/config"
+	"github.com/evorts/kevlars/contracts"
 	"github.com/evorts/kevlars/ctime"
 	"github.com/evorts/kevlars/health"
 	"github.com/evorts/kevlars/logger"
+	"github.com/evorts/kevlars/midware"
 	"github.com/evorts/kevlars/rules"
 	"github.com/evorts/kevlars/telemetry"
 	"github.com/evorts/kevlars/utils"
@@ -22,7 +25,9 @@ import (
 
 type IMonitoring interface {
 	withLogger() IApplication
+	withConfigWatch() IApplication
 	withHealthcheck() IApplication
+	withConfigDump() IApplication
 
 	Log() logger.Manager
 	Telemetry() telemetry.Manager
@@ -74,6 +79,25 @@ func (app *Application) withLogger() IApplication {
 	return app
 }
 
+// withConfigWatch reload configuration on changes of its source, log level follow the changes right away,
+// other components could subscribe through Config().OnChange
+func (app *Application) withConfigWatch() IApplication {
+	if !app.Config().GetBool(AppConfigWatch.String()) {
+		return app
+	}
+	app.Config().OnChange(AppLogLevel.String(), func(old, new interface{}) {
+		logLevel := app.Config().GetInt(AppLogLevel.String())
+		if !utils.NumberInRange(logLevel, logger.LogLevelPanic.Id(), logger.LogLevelOff.Id()) {
+			logLevel = logger.LogLevelError.Id()
+		}
+		app.log.SetLevel(logger.LogLevel(logLevel))
+	})
+	if err := app.Config().Watch(app.startContext); err != nil {
+		app.log.WarnWithProps(map[string]interface{}{"error": err.Error()}, "failed to watch configuration changes")
+	}
+	return app
+}
+
 func (app *Application) withHealthcheck() IApplication {
 	if app.Config().GetBool(AppHealth.String()) {
 		app.routes = append(app.routes,
@@ -111,6 +135,26 @@ func (app *Application) withHealthcheck() IApplication {
 	return app
 }
 
+// withConfigDump expose the effective configuration with secrets redacted, only registered
+// when api keys defined since the configuration reveal the internal of the app
+func (app *Application) withConfigDump() IApplication {
+	if !app.Config().GetBool(AppConfigDump.String()) {
+		return app
+	}
+	var apiKeys []midware.ApiKeySecretMap
+	if err := app.Config().UnmarshalTo(AppConfigDumpApiKeys.String(), &apiKeys); err != nil || len(apiKeys) < 1 {
+		app.log.Warn("config dump not registered, api keys are not defined")
+		return app
+	}
+	app.routes = append(app.routes, route{
+		method:      http.MethodGet,
+		path:        app.Config().GetStringOrElse(AppConfigDumpPath.String(), "/internal/config"),
+		handlerEcho: app.configDumpEchoHandler,
+		middlewares: []echo.MiddlewareFunc{midware.EchoWithAuthApiKeySecrets(apiKeys)},
+	})
+	return app
+}
+
 // healthInfoEchoHandler godoc
 // @Summary      System healthcheck
 // @Description  Check the health status of the system where the app reside
@@ -159,6 +203,33 @@ func (app *Application) healthDependenciesEchoHandler(c echo.Context) error {
 	return c.JSON(http.StatusOK, result)
 }
 
+// configDumpEchoHandler godoc
+// @Summary      Effective configuration
+// @Description  Dump the effective configuration with secrets redacted, or provenance of the key when defined
+// @Tags         monitoring
+// @Accept       json
+// @Produce      json
+// @Param        key  query  string  false  "config key to explain, e.g. app.log.level"
+// @Success      200  {object}  contracts.ResponseSuccess[map[string]interface{}]
+// @Failure      404  {object}  contracts.ResponseFail
+// @Router       /internal/config [get]
+func (app *Application) configDumpEchoHandler(c echo.Context) error {
+	key := c.QueryParam("key")
+	if len(key) < 1 {
+		return c.JSON(contracts.NewResponseOK("effective configuration", config.Redact(app.Config(), append(append([]string{}, configSecretKeys...), app.MaskedFields()...)...)))
+	}
+	p, ok := app.Config().Explain(key)
+	if !ok {
+		return c.JSON(contracts.NewResponseFail(http.StatusNotFound, "config key not found", contracts.ErrorDetail{
+			Code: "ERR:NOK:CFG",
+			Errors: map[string]string{
+				"key": key,
+			},
+		}))
+	}
+	return c.JSON(contracts.NewResponseOK("config provenance", p))
+}
+
 // healthEchoHandler godoc
 // @Summary      System ping
 // @Description  Check reachable of the app by ping
@@ -95,14 +95,14 @@ func (app *Application) GRPC(context string) rpc.ClientManager {
 
 func (app *Application) WithRestClients() IApplication {
 	var restServices []service
-	err := app.Config().UnmarshalTo("rest.services", &restServices)
+	err := app.Config().UnmarshalTo(RestServices.String(), &restServices)
 	if err != nil || len(restServices) < 1 {
 		panic("asking rest clients but no configuration defined")
 	}
 	var (
-		telemetryEnabled = app.Config().GetBool("rest.telemetry_enabled")
-		metricEnabled    = app.Config().GetBool("rest.metric_enabled")
-		name             = app.Config().GetStringOrElse("rest.name", "rest.client")
+		telemetryEnabled = app.Config().GetBool(RestTelemetryEnabled.String())
+		metricEnabled    = app.Config().GetBool(RestMetricEnabled.String())
+		name             = app.Config().GetStringOrElse(RestName.String(), "rest.client")
 	)
 	app.restClients = make(map[string]rest.Manager)
 	for _, svc := range restServices {
@@ -110,8 +110,8 @@ func (app *Application) WithRestClients() IApplication {
 		opts := []rest.Option{
 			rest.WithName(contextName),
 			rest.WithBaseUrl(svc.ServiceUrl),
-			rest.WithDebugging(app.Config().GetBool("rest.debug_enabled")),
-			rest.WithTracing(app.Config().GetBool("rest.trace_enabled")),
+			rest.WithDebugging(app.Config().GetBool(RestDebugEnabled.String())),
+			rest.WithTracing(app.Config().GetBool(RestTraceEnabled.String())),
 			rest.WithLogger(app.Log()),
 		}
 		rules.WhenTrue(telemetryEnabled, func() {
@@ -144,7 +144,7 @@ func (app *Application) WithRestClients() IApplication {
 		})
 		// check whether there's a need for authentication
 		var svcAuth map[string]interface{}
-		svcAuthCfgKey := fmt.Sprintf("rest.auth.%s", svc.Name)
+		svcAuthCfgKey := fmt.Sprintf("%s.%s", RestAuth, svc.Name)
 		err = app.Config().UnmarshalTo(svcAuthCfgKey, &svcAuth)
 		rules.WhenTrue(
 			eval.AND(
@@ -178,15 +178,15 @@ func (app *Application) WithRestClients() IApplication {
 
 func (app *Application) WithSoapClients() IApplication {
 	var soapServices []service
-	err := app.Config().UnmarshalTo("soap.services", &soapServices)
+	err := app.Config().UnmarshalTo(SoapServices.String(), &soapServices)
 	if err != nil || len(soapServices) < 1 {
 		panic("asking soap managers but no configuration defined")
 	}
 	var (
-		telemetryEnabled = app.Config().GetBool("soap.telemetry_enabled")
-		metricEnabled    = app.Config().GetBool("soap.metric_enabled")
-		name             = app.Config().GetStringOrElse("soap.name", "soap.client")
-		largeFileTimeout = app.Config().GetIntOrElse("soap.large_file_timeout", 300) // default 5 minute
+		telemetryEnabled = app.Config().GetBool(SoapTelemetryEnabled.String())
+		metricEnabled    = app.Config().GetBool(SoapMetricEnabled.String())
+		name             = app.Config().GetStringOrElse(SoapName.String(), "soap.client")
+		largeFileTimeout = app.Config().GetIntOrElse(SoapLargeFileTimeout.String(), 300) // default 5 minute
 	)
 	app.soapClients = make(map[string]soap.Manager)
 	for _, svc := range soapServices {
@@ -210,12 +210,12 @@ func (app *Application) WithSoapClients() IApplication {
 		rules.WhenTrue(metricEnabled, func() {
 			opts = append(opts, soap.WithMetrics(metricEnabled, app.Metrics()))
 		})
-		authType := app.Config().GetString(fmt.Sprintf("soap.auth.%s.use", svc.Name))
+		authType := app.Config().GetString(fmt.Sprintf("%s.%s.use", SoapAuth, svc.Name))
 		if authType == authTypeBasic.String() {
 			opts = append(opts,
 				soap.WithBasicAuth(
-					app.Config().GetString(fmt.Sprintf("soap.auth.%s.%s.username", svc.Name, authType)),
-					app.Config().GetString(fmt.Sprintf("soap.auth.%s.%s.password", svc.Name, authType)),
+					app.Config().GetString(fmt.Sprintf("%s.%s.%s.username", SoapAuth, svc.Name, authType)),
+					app.Config().GetString(fmt.Sprintf("%s.%s.%s.password", SoapAuth, svc.Name, authType)),
 				),
 			)
 		}
@@ -233,14 +233,14 @@ func (app *Application) WithSoapClients() IApplication {
 
 func (app *Application) WithGrpcClients() IApplication {
 	var grpcServices []service
-	err := app.Config().UnmarshalTo("grpc.services", &grpcServices)
+	err := app.Config().UnmarshalTo(GrpcServices.String(), &grpcServices)
 	if err != nil || len(grpcServices) < 1 {
 		panic("asking grpc managers but no configuration defined")
 	}
 	var (
-		telemetryEnabled = app.Config().GetBool("grpc.telemetry_enabled")
-		metricEnabled    = app.Config().GetBool("grpc.metric_enabled")
-		name             = app.Config().GetStringOrElse("grpc.name", "grpc.client")
+		telemetryEnabled = app.Config().GetBool(GrpcTelemetryEnabled.String())
+		metricEnabled    = app.Config().GetBool(GrpcMetricEnabled.String())
+		name             = app.Config().GetStringOrElse(GrpcName.String(), "grpc.client")
 	)
 	app.grpcClients = make(map[string]rpc.ClientManager)
 	for _, svc := range grpcServices {
@@ -261,7 +261,7 @@ func (app *Application) WithGrpcClients() IApplication {
 		rules.WhenTrue(svc.LogRequestPayload, func() {
 			opts = append(opts, rpc.WithLoggingRequestPayload(svc.LogRequestPayload, svc.LogRequestPayloadInJson))
 		})
-		authCfgKey := "grpc.auth." + svc.Name
+		authCfgKey := GrpcAuth.String() + "." + svc.Name
 		rules.WhenTrue(app.Config().GetBool(authCfgKey+".enabled"), func() {
 			authType := app.Config().GetString(authCfgKey + ".use")
 			switch authType {
@@ -50,12 +50,12 @@ func (app *Application) Run(run func(a *Application)) {
 func (app *Application) RunUseEcho(run func(a *Application, e *echo.Echo)) {
 	e := echo.New()
 	e.HideBanner = true
-	e.Debug = app.Config().GetInt("app.log.level") == logger.LogLevelDebug.Id()
-	e.Logger.SetLevel(log.Lvl(logger.LogLevel(app.Config().GetInt("app.log.level")).EchoLogLevel()))
+	e.Debug = app.Config().GetInt(AppLogLevel.String()) == logger.LogLevelDebug.Id()
+	e.Logger.SetLevel(log.Lvl(logger.LogLevel(app.Config().GetInt(AppLogLevel.String())).EchoLogLevel()))
 	// register predefined routes
 	if len(app.routes) > 0 {
 		for _, r := range app.routes {
-			e.Add(r.method, r.path, r.handlerEcho)
+			e.Add(r.method, r.path, r.handlerEcho, r.middlewares...)
 		}
 	}
 	run(app, e)
@@ -70,9 +70,9 @@ func (app *Application) RunUseEcho(run func(a *Application, e *echo.Echo)) {
 func (app *Application) RunRestApiUseEcho(run func(a *Application, e *echo.Echo)) {
 	e := echo.New()
 	e.HideBanner = true
-	e.Debug = app.Config().GetInt("app.log.level") == logger.LogLevelDebug.Id()
+	e.Debug = app.Config().GetInt(AppLogLevel.String()) == logger.LogLevelDebug.Id()
 	e.Validator = validation.NewValidator().MustInit()
-	e.Logger.SetLevel(log.Lvl(logger.LogLevel(app.Config().GetInt("app.log.level")).EchoLogLevel()))
+	e.Logger.SetLevel(log.Lvl(logger.LogLevel(app.Config().GetInt(AppLogLevel.String())).EchoLogLevel()))
 	e.Pre(middleware.RequestIDWithConfig(middleware.RequestIDConfig{
 		Generator: func() string {
 			reqId := ""
@@ -102,26 +102,26 @@ func (app *Application) RunRestApiUseEcho(run func(a *Application, e *echo.Echo)
 		},
 		TargetHeader: echo.HeaderXRequestID,
 	}))
-	skipEndpoints := app.Config().GetStringSlice("telemetry.tracing.skip_endpoints")
+	skipEndpoints := app.Config().GetStringSlice(TelemetryTracingSkipEndpoints.String())
 	e.Use(
 		midware.EchoLogger(app.Log(), skipEndpoints...),
 		middleware.Recover(),
 	)
-	if app.Config().GetBool("app.timeout.enabled") {
+	if app.Config().GetBool(AppTimeoutEnabled.String()) {
 		e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
-			Timeout: time.Duration(app.Config().GetIntOrElse("app.timeout.ms", 5000)) * time.Millisecond,
+			Timeout: time.Duration(app.Config().GetIntOrElse(AppTimeoutMs.String(), 5000)) * time.Millisecond,
 		}))
 	}
 	e.HTTPErrorHandler = midware.NewEchoHttpError(app.Telemetry(), app.Log()).Handler
 	if app.Telemetry().Enabled() {
 		echoMwOTel := midware.NewEchoTelemetryMiddleware(
 			app.Telemetry(),
-			midware.EchoWithTracerName(app.Config().GetString("telemetry.name")),
-			midware.EchoWithBodyTrace(app.Config().GetBool("telemetry.body_tracing_enabled")),
+			midware.EchoWithTracerName(app.Config().GetString(TelemetryName.String())),
+			midware.EchoWithBodyTrace(app.Config().GetBool(TelemetryBodyTracingEnabled.String())),
 			midware.EchoWithMaskedBodyFields(app.MaskedFields()...),
 			midware.EchoWithMaskedHeaders(app.MaskedHeaders()...),
 			midware.EchoWithSkipper(func(c echo.Context) bool {
-				return utils.InArray(app.Config().GetStringSlice("telemetry.tracing.skip_endpoints"), c.Request().URL.Path)
+				return utils.InArray(app.Config().GetStringSlice(TelemetryTracingSkipEndpoints.String()), c.Request().URL.Path)
 			}),
 		)
 		e.Pre(echoMwOTel.EchoOTelPre())
@@ -137,7 +137,7 @@ func (app *Application) RunRestApiUseEcho(run func(a *Application, e *echo.Echo)
 	// register predefined routes
 	if len(app.routes) > 0 {
 		for _, r := range app.routes {
-			e.Add(r.method, r.path, r.handlerEcho)
+			e.Add(r.method, r.path, r.handlerEcho, r.middlewares...)
 		}
 	}
 	run(app, e)
@@ -150,7 +150,7 @@ func (app *Application) RunRestApiUseEcho(run func(a *Application, e *echo.Echo)
 }
 
 func (app *Application) RunGrpcServer(run func(app *Application, rpcServer *grpc.Server)) {
-	netListen, err := net.Listen("tcp", ":"+utils.IntToString(app.Config().GetIntOrElse("app.grpc_port", 8899)))
+	netListen, err := net.Listen("tcp", ":"+utils.IntToString(app.Config().GetIntOrElse(AppGrpcPort.String(), 8899)))
 	if err != nil {
 		log.Fatalf("failed connection: %v", err)
 	}
@@ -8,8 +8,10 @@
 package scaffold
 
 import (
+	"fmt"
 	"github.com/evorts/kevlars/common"
 	"github.com/evorts/kevlars/crypt"
+	"strconv"
 )
 
 type ISecurity interface {
@@ -28,7 +30,7 @@ func (app *Application) WithCrypts() IApplication {
 	if app.HasCrypts() {
 		return app
 	}
-	crypts := app.Config().GetStringMap("crypt")
+	crypts := app.Config().GetStringMap(CryptSection.String())
 	if len(crypts) == 0 {
 		panic("No crypt configured")
 	}
@@ -37,7 +39,7 @@ func (app *Application) WithCrypts() IApplication {
 		if !ok {
 			continue
 		}
-		cipher, key, iv, pk := "", "", "", ""
+		cipher, key, iv, pk, pub := "", "", "", "", ""
 		if v, exist := item["cipher"]; exist {
 			cipher = v.(string)
 		}
@@ -50,8 +52,17 @@ func (app *Application) WithCrypts() IApplication {
 		if v, exist := item["pk"]; exist {
 			pk = v.(string)
 		}
+		if v, exist := item["public_key"]; exist {
+			pub = v.(string)
+		}
 		app.cryptMap[k] = crypt.New()
 		if len(cipher) > 0 {
+			if crypt.Cipher(cipher).Deprecated() {
+				app.Log().WarnWithProps(map[string]interface{}{
+					"crypt":  k,
+					"cipher": cipher,
+				}, "cipher is deprecated, only use it to decrypt existing data and migrate to a modern cipher")
+			}
 			app.cryptMap[k].AddOptions(crypt.WithCipher(crypt.Cipher(cipher)))
 		}
 		if len(key) > 0 {
@@ -63,6 +74,27 @@ func (app *Application) WithCrypts() IApplication {
 		if len(pk) > 0 {
 			app.cryptMap[k].AddOptions(crypt.WithPrivateKey(pk))
 		}
+		// public key only crypt could encrypt but not decrypt
+		if len(pub) > 0 {
+			app.cryptMap[k].AddOptions(crypt.WithPublicKey(pub))
+		}
+		// versioned key encryption keys of envelope cipher, e.g. keys: {"1": "...", "2": "..."}
+		if keys, exist := item["keys"].(map[string]interface{}); exist {
+			for version, kek := range keys {
+				ver, err := strconv.ParseUint(version, 10, 32)
+				if err != nil {
+					panic(fmt.Sprintf("invalid key version %s of crypt %s", version, k))
+				}
+				app.cryptMap[k].AddOptions(crypt.WithKeyVersion(uint32(ver), common.Bytes(fmt.Sprint(kek))))
+			}
+		}
+		if v, exist := item["key_version"]; exist {
+			ver, err := strconv.ParseUint(fmt.Sprint(v), 10, 32)
+			if err != nil {
+				panic(fmt.Sprintf("invalid key version of crypt %s", k))
+			}
+			app.cryptMap[k].AddOptions(crypt.WithCurrentKeyVersion(uint32(ver)))
+		}
 		app.cryptMap[k].MustInit()
 	}
 	return app
@@ -43,7 +43,7 @@ func (app *Application) WithDatabases() IApplication {
 	//	 "postgres":{"driver":"","dsn":"","telemetry_enabled":bool}
 	//	 "mysql":{"driver":"","dsn":"","telemetry_enabled":bool}
 	//	}
-	dbs := app.config.GetStringMap("dbs")
+	dbs := app.config.GetStringMap(DbsSection.String())
 	if len(dbs) < 1 {
 		panic("there's no dbs configuration found")
 	}
@@ -132,7 +132,7 @@ func (app *Application) WithInMemories() IApplication {
 	//	 "default":{"provider":"valkey","enabled":"[value]","address":"","creds":"","db":[bool]},
 	//	 "other":{"provider":"redis","enabled":"[value]","address":"","creds":"","db":[bool]}
 	//	}
-	inMemories := app.config.GetStringMap("in_memory")
+	inMemories := app.config.GetStringMap(InMemorySection.String())
 	if len(inMemories) < 1 {
 		panic("there's no in memory configuration found")
 	}
@@ -0,0 +1,149 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: config_schema
+ * @Date: 23/08/24 10.20
+ */
+
+package scaffold
+
+import (
+	"github.com/evorts/kevlars/config"
+	"github.com/evorts/kevlars/midware"
+	"time"
+)
+
+// schemaConfig is the shape of configuration keys consumed by the scaffold, merged from config and secret file.
+// keep it along with the keys read by the app, thus the exported schema stay accurate
+type schemaConfig struct {
+	App         schemaApp                         `mapstructure:"app" validate:"required"`
+	Dbs         map[string]schemaDB               `mapstructure:"dbs" desc:"databases by instance name, default instance required by WithDatabases"`
+	InMemory    map[string]schemaInMemory         `mapstructure:"in_memory" desc:"in memory stores by instance name"`
+	Crypt       map[string]schemaCrypt            `mapstructure:"crypt" desc:"crypts by instance name" secret:"true"`
+	Rest        schemaRemote                      `mapstructure:"rest"`
+	Soap        schemaSoap                        `mapstructure:"soap"`
+	Grpc        schemaRemote                      `mapstructure:"grpc"`
+	FeatureFlag schemaFeatureFlag                 `mapstructure:"feature_flag"`
+	Auth        schemaAuth                        `mapstructure:"auth"`
+	Telemetry   schemaTelemetry                   `mapstructure:"telemetry"`
+	Queues      map[string]map[string]interface{} `mapstructure:"queues"`
+}
+
+type schemaApp struct {
+	Name            string        `mapstructure:"name" validate:"required"`
+	Version         string        `mapstructure:"version"`
+	Env             string        `mapstructure:"env" desc:"overridden by APP_ENV"`
+	GracefulTimeout time.Duration `mapstructure:"graceful_timeout" default:"10s"`
+	GrpcPort        int           `mapstructure:"grpc_port" default:"8899" validate:"min=1,max=65535"`
+	MaskedFields    []string      `mapstructure:"masked_fields" desc:"request body fields masked on traces and config dump"`
+	MaskedHeaders   []string      `mapstructure:"masked_headers"`
+	Port            struct {
+		Rest int `mapstructure:"rest" default:"8080" validate:"min=1,max=65535"`
+		Grpc int `mapstructure:"grpc" default:"9090" validate:"min=1,max=65535"`
+	} `mapstructure:"port"`
+	Config struct {
+		Watch bool `mapstructure:"watch" desc:"reload on changes of the config source"`
+		Dump  struct {
+			Enabled bool                      `mapstructure:"enabled"`
+			Path    string                    `mapstructure:"path" default:"/internal/config"`
+			ApiKeys []midware.ApiKeySecretMap `mapstructure:"api_keys" secret:"true"`
+		} `mapstructure:"dump"`
+	} `mapstructure:"config"`
+	Log struct {
+		Level             int    `mapstructure:"level" desc:"0 = PANIC, 1 = FATAL, 2 = ERROR, 3 = WARN, 4 = INFO, 5 = DEBUG, 6 = TRACE" validate:"min=0,max=7"`
+		Timezone          string `mapstructure:"tz"`
+		UseCustomTimezone bool   `mapstructure:"use_custom_timezone"`
+	} `mapstructure:"log"`
+	Healthcheck struct {
+		Health       bool `mapstructure:"health" desc:"/health"`
+		Metrics      bool `mapstructure:"metrics" desc:"/health/metrics"`
+		Dependencies bool `mapstructure:"dependencies" desc:"/health/dependencies"`
+	} `mapstructure:"healthcheck"`
+	Timeout struct {
+		Enabled bool `mapstructure:"enabled"`
+		Ms      int  `mapstructure:"ms" default:"5000" validate:"min=1"`
+	} `mapstructure:"timeout"`
+}
+
+type schemaDB struct {
+	Driver            string `mapstructure:"driver" desc:"instance without driver and dsn skipped" validate:"oneof=postgres mysql mssql sqlmock"`
+	Dsn               string `mapstructure:"dsn" secret:"true"`
+	TelemetryEnabled  bool   `mapstructure:"telemetry_enabled" default:"true"`
+	MaxOpenConnection int    `mapstructure:"max_open_connection" validate:"min=0"`
+	MaxIdleConnection int    `mapstructure:"max_idle_connection" validate:"min=0"`
+}
+
+type schemaInMemory struct {
+	Provider         string                 `mapstructure:"provider" default:"valkey" validate:"oneof=valkey redis"`
+	Address          string                 `mapstructure:"address"`
+	Pass             string                 `mapstructure:"pass" secret:"true"`
+	Enabled          bool                   `mapstructure:"enabled" default:"true"`
+	TelemetryEnabled bool                   `mapstructure:"telemetry_enabled"`
+	TLS              map[string]interface{} `mapstructure:"tls"`
+}
+
+type schemaCrypt struct {
+	Cipher     string            `mapstructure:"cipher" desc:"empty use the default cipher" validate:"omitempty,oneof=3DES AES-CBC AES-GCM RSA RSA-OAEP XCHACHA20-POLY1305 AES-GCM-SIV X25519-CHACHA20-POLY1305 AES-SIV ENVELOPE"`
+	Key        string            `mapstructure:"key"`
+	IV         string            `mapstructure:"iv"`
+	PK         string            `mapstructure:"pk" desc:"base64 PEM private key"`
+	PublicKey  string            `mapstructure:"public_key" desc:"base64 PEM public key, encrypt only"`
+	KeyVersion int               `mapstructure:"key_version" desc:"current key version of envelope cipher" validate:"min=1"`
+	Keys       map[string]string `mapstructure:"keys" desc:"key encryption keys of envelope cipher by version"`
+}
+
+type schemaRemoteAuth struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Use     string `mapstructure:"use" validate:"oneof=basic headers oauth insecure ssl_tls token"`
+}
+
+type schemaRemote struct {
+	Name             string                      `mapstructure:"name"`
+	TelemetryEnabled bool                        `mapstructure:"telemetry_enabled"`
+	MetricEnabled    bool                        `mapstructure:"metric_enabled"`
+	DebugEnabled     bool                        `mapstructure:"debug_enabled"`
+	TraceEnabled     bool                        `mapstructure:"trace_enabled"`
+	Services         []service                   `mapstructure:"services"`
+	Auth             map[string]schemaRemoteAuth `mapstructure:"auth" desc:"authentication by service name"`
+}
+
+type schemaSoap struct {
+	Remote           schemaRemote `mapstructure:",squash"`
+	LargeFileTimeout int          `mapstructure:"large_file_timeout" default:"300" validate:"min=1"`
+}
+
+type schemaMigrations struct {
+	Enabled bool     `mapstructure:"enabled"`
+	Dir     []string `mapstructure:"dir"`
+}
+
+type schemaFeatureFlag struct {
+	Migrations   schemaMigrations `mapstructure:"migrations"`
+	LazyLoadData bool             `mapstructure:"lazy_load_data"`
+}
+
+type schemaAuth struct {
+	Client struct {
+		Migrations       schemaMigrations `mapstructure:"migrations"`
+		UseInMemory      bool             `mapstructure:"use_in_memory"`
+		InMemoryInstance string           `mapstructure:"in_memory_instance"`
+	} `mapstructure:"client"`
+}
+
+type schemaTelemetry struct {
+	Name               string `mapstructure:"name"`
+	BodyTracingEnabled bool   `mapstructure:"body_tracing_enabled"`
+	Tracing            struct {
+		SkipEndpoints []string `mapstructure:"skip_endpoints"`
+	} `mapstructure:"tracing"`
+}
+
+// configSecretKeys of the scaffold schema, always redacted on config dump along with the masked fields
+var configSecretKeys = ConfigSchema().SecretKeys()
+
+// ConfigSchema of the keys consumed by the scaffold as json schema, keys of the app itself are not restricted
+func ConfigSchema() *config.Schema {
+	s := config.SchemaOf(schemaConfig{})
+	s.Title = "kevlars scaffold configuration"
+	return s
+}
@@ -0,0 +1,82 @@
+/**
+ * @Author: steven
+ * @Description:
+ * @File: config_schema_test
+ * @Date: 23/08/24 11.40
+ */
+
+package scaffold
+
+import (
+	"encoding/json"
+	"github.com/evorts/kevlars/config"
+	mockConfig "github.com/evorts/kevlars/mocks/config"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestConfigSchemaCoverKeys(t *testing.T) {
+	s := ConfigSchema()
+	for _, key := range []ConfigKey{
+		AppName, AppVersion, AppEnv, AppPortRest, AppPortGrpc, AppGracefulTimeout, AppConfigWatch,
+		AppMaskedFields, AppMaskedHeaders, AppConfigDump, AppConfigDumpPath, AppConfigDumpApiKeys,
+		AppLogLevel, AppLogTimezone, AppLogUseCustomTimezone, AppHealth, AppMetric, AppDependencies,
+		AppTimeoutEnabled, AppTimeoutMs, AppGrpcPort,
+		DbsSection, InMemorySection, CryptSection,
+		AuthClientMigrationsEnabled, AuthClientMigrationsDir, AuthClientUseInMemory, AuthClientInMemoryInstance,
+		FeatureFlagMigrationsEnabled, FeatureFlagMigrationsDir, FeatureFlagLazyLoadData,
+		TelemetryName, TelemetryBodyTracingEnabled, TelemetryTracingSkipEndpoints,
+		RestName, RestServices, RestTelemetryEnabled, RestMetricEnabled, RestDebugEnabled, RestTraceEnabled, RestAuth,
+		SoapName, SoapServices, SoapTelemetryEnabled, SoapMetricEnabled, SoapLargeFileTimeout, SoapAuth,
+		GrpcName, GrpcServices, GrpcTelemetryEnabled, GrpcMetricEnabled, GrpcAuth,
+	} {
+		current := s
+		for _, segment := range strings.Split(key.String(), ".") {
+			current = current.Properties[segment]
+			if !assert.NotNil(t, current, "%s not found on the schema", key) {
+				break
+			}
+		}
+	}
+}
+
+func TestConfigDumpSampleRedacted(t *testing.T) {
+	v := viper.New()
+	v.SetConfigFile("sample_config.yaml")
+	require.NoError(t, v.ReadInConfig())
+	v.SetConfigFile("sample_secrets.yaml")
+	require.NoError(t, v.MergeInConfig())
+	// fill the blank secrets of the sample
+	secrets := map[string]string{
+		"crypt.default.key":      "crypt-default-key",
+		"crypt.default.iv":       "crypt-default-iv",
+		"crypt.chacha.key":       "crypt-chacha-key",
+		"crypt.audit_reader.pk":  "crypt-audit-reader-pk",
+		"crypt.envelope.keys.1":  "crypt-envelope-kek-1",
+		"crypt.envelope.keys.2":  "crypt-envelope-kek-2",
+		"in_memory.default.pass": "in-memory-pass",
+	}
+	for key, value := range secrets {
+		v.Set(key, value)
+	}
+	v.Set(AppConfigDumpApiKeys.String(), []map[string]interface{}{{"client_id": "ops", "key": "dump-api-key"}})
+	secrets[AppConfigDumpApiKeys.String()] = "dump-api-key"
+
+	cm := mockConfig.NewManager(t)
+	cm.EXPECT().AllSettings().Return(v.AllSettings())
+	cm.EXPECT().Explain(mock.Anything).Return(config.Provenance{}, false)
+	b, err := json.Marshal(config.Redact(cm, configSecretKeys...))
+	require.NoError(t, err)
+	dump := string(b)
+	assert.Contains(t, dump, "sample_app")
+	for key, value := range secrets {
+		assert.NotContains(t, dump, value, "%s not redacted", key)
+	}
+	for _, password := range []string{"b4Zd3x6aLRM6mKs2S3", "SRue3Ye3McxDjePWKk", "Levku5jrqVU2Tzr4P4", "xSnh6rXmRR4nk4Zdxn"} {
+		assert.NotContains(t, dump, password)
+	}
+}
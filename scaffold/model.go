@@ -17,4 +17,5 @@ type route struct {
 	method      string
 	path        string
 	handlerEcho echo.HandlerFunc
+	middlewares []echo.MiddlewareFunc
 }
@@ -18,6 +18,13 @@ const (
 	AppPortRest        = AppSection + ".port.rest"
 	AppPortGrpc        = AppSection + ".port.grpc"
 	AppGracefulTimeout = AppSection + ".graceful_timeout"
+	AppConfigWatch     = AppSection + ".config.watch"
+	AppMaskedFields    = AppSection + ".masked_fields"
+	AppMaskedHeaders   = AppSection + ".masked_headers"
+
+	AppConfigDump        = AppSection + ".config.dump.enabled"
+	AppConfigDumpPath    = AppSection + ".config.dump.path"
+	AppConfigDumpApiKeys = AppSection + ".config.dump.api_keys"
 
 	AppLogLevel             = AppSection + ".log.level"
 	AppLogTimezone          = AppSection + ".log.tz"
@@ -26,6 +33,64 @@ const (
 	AppHealth       = AppSection + ".healthcheck.health"
 	AppMetric       = AppSection + ".healthcheck.metrics"
 	AppDependencies = AppSection + ".healthcheck.dependencies"
+
+	AppTimeoutEnabled = AppSection + ".timeout.enabled"
+	AppTimeoutMs      = AppSection + ".timeout.ms"
+	AppGrpcPort       = AppSection + ".grpc_port"
+)
+
+const (
+	DbsSection      ConfigKey = "dbs"
+	InMemorySection ConfigKey = "in_memory"
+	CryptSection    ConfigKey = "crypt"
+
+	AuthSection ConfigKey = "auth"
+
+	AuthClientMigrationsEnabled = AuthSection + ".client.migrations.enabled"
+	AuthClientMigrationsDir     = AuthSection + ".client.migrations.dir"
+	AuthClientUseInMemory       = AuthSection + ".client.use_in_memory"
+	AuthClientInMemoryInstance  = AuthSection + ".client.in_memory_instance"
+
+	FeatureFlagSection ConfigKey = "feature_flag"
+
+	FeatureFlagMigrationsEnabled = FeatureFlagSection + ".migrations.enabled"
+	FeatureFlagMigrationsDir     = FeatureFlagSection + ".migrations.dir"
+	FeatureFlagLazyLoadData      = FeatureFlagSection + ".lazy_load_data"
+
+	TelemetrySection ConfigKey = "telemetry"
+
+	TelemetryName                 = TelemetrySection + ".name"
+	TelemetryBodyTracingEnabled   = TelemetrySection + ".body_tracing_enabled"
+	TelemetryTracingSkipEndpoints = TelemetrySection + ".tracing.skip_endpoints"
+)
+
+const (
+	RestSection ConfigKey = "rest"
+
+	RestName             = RestSection + ".name"
+	RestServices         = RestSection + ".services"
+	RestTelemetryEnabled = RestSection + ".telemetry_enabled"
+	RestMetricEnabled    = RestSection + ".metric_enabled"
+	RestDebugEnabled     = RestSection + ".debug_enabled"
+	RestTraceEnabled     = RestSection + ".trace_enabled"
+	RestAuth             = RestSection + ".auth"
+
+	SoapSection ConfigKey = "soap"
+
+	SoapName             = SoapSection + ".name"
+	SoapServices         = SoapSection + ".services"
+	SoapTelemetryEnabled = SoapSection + ".telemetry_enabled"
+	SoapMetricEnabled    = SoapSection + ".metric_enabled"
+	SoapLargeFileTimeout = SoapSection + ".large_file_timeout"
+	SoapAuth             = SoapSection + ".auth"
+
+	GrpcSection ConfigKey = "grpc"
+
+	GrpcName             = GrpcSection + ".name"
+	GrpcServices         = GrpcSection + ".services"
+	GrpcTelemetryEnabled = GrpcSection + ".telemetry_enabled"
+	GrpcMetricEnabled    = GrpcSection + ".metric_enabled"
+	GrpcAuth             = GrpcSection + ".auth"
 )
 
 func (c ConfigKey) String() string {